@@ -1,6 +1,7 @@
 package ImgMeta
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 )
@@ -9,7 +10,10 @@ var idJFIF = []byte{'J', 'F', 'I', 'F', 0}
 var idJFXX = []byte{'J', 'F', 'X', 'X', 0}
 var idEXIF = []byte{'E', 'x', 'i', 'f', 0, 0}
 var idXMP = []byte{'h', 't', 't', 'p', ':', '/', '/', 'n', 's', '.', 'a', 'd', 'o', 'b', 'e', '.', 'c', 'o', 'm', '/', 'x', 'a', 'p', '/', '1', '.', '0', '/', 0}
+var idXMPExtension = []byte{'h', 't', 't', 'p', ':', '/', '/', 'n', 's', '.', 'a', 'd', 'o', 'b', 'e', '.', 'c', 'o', 'm', '/', 'x', 'm', 'p', '/', 'e', 'x', 't', 'e', 'n', 's', 'i', 'o', 'n', '/', 0}
 var idAPP2 = []byte{'I', 'C', 'C', '_', 'P', 'R', 'O', 'F', 'I', 'L', 'E', 0}
+var idMPF = []byte{'M', 'P', 'F', 0}
+var idAdobe = []byte{'A', 'd', 'o', 'b', 'e'}
 var idIPTC = []byte{'P', 'h', 'o', 't', 'o', 's', 'h', 'o', 'p', ' ', '3', '.', '0', 0}
 
 // TIFF Header - Byte Order
@@ -23,11 +27,21 @@ const (
 	cIFDEXIF    uint16 = 0x8769
 	cIFDGPS     uint16 = 0x8825
 	cIFDINTEROP uint16 = 0xa005
+	cIFDONE     uint16 = 0x0001 // thumbnail IFD, linked by IFD0
 )
 
 func fAPPReadBlock(marker uint16, reader *JpegReader, extra uint32) (appblock []byte, err error) {
 	appLength := uint16(0)
-	binary.Read(reader, binary.BigEndian, &appLength)
+	if err = binary.Read(reader, binary.BigEndian, &appLength); err != nil {
+		return nil, &exifError{fmt.Sprintf("Segment 0x%X is truncated", marker)}
+	}
+	// The length counts its own 2 bytes, the segment must fit in the remaining data
+	if appLength < 2 {
+		return nil, &exifError{fmt.Sprintf("Segment 0x%X has an invalid length %d", marker, appLength)}
+	}
+	if uint64(appLength)-2+uint64(extra) > uint64(len(reader.data))-reader.pos() {
+		return nil, &exifError{fmt.Sprintf("Segment 0x%X with length %d runs past the end of the data", marker, appLength)}
+	}
 
 	size := uint32(appLength) + 2 + extra
 	appblock = make([]byte, size)
@@ -44,31 +58,35 @@ func fAPPReadBlock(marker uint16, reader *JpegReader, extra uint32) (appblock []
 }
 
 func fAPPReadComment(marker uint16, reader *JpegReader) (a APP, err error) {
-	//fmt.Print("APP:COMMENT = ")
-	app := &tAPP{offset: 0, endian: binary.BigEndian}
+	app := &tCommentAPP{offset: reader.pos() - 2, endian: binary.BigEndian}
 	app.block, err = fAPPReadBlock(marker, reader, 0)
-	comment := string(app.block[4:])
-	fmt.Println(comment)
-	return app, nil
+	return app, err
 }
 
-func fAPPReadJFIF(app *tAPP) (err error) {
-	//fmt.Printf("APP:JFIF (length: %d)\n", len(app.block))
+func fAPPReadJFIF(app *tJFIFAPP) (err error) {
+	if len(app.block) < JFIFYThumbnail+1 {
+		return &exifError{"APP0 JFIF segment is too small"}
+	}
 	return nil
 }
 
-func fAPPReadJFXX(app *tAPP) (err error) {
-	//fmt.Printf("APP:JFXX (length: %d)\n", len(app.block))
+func fAPPReadJFXX(app *tJFIFAPP) (err error) {
+	if len(app.block) < JFXXExtensionCode+1 {
+		return &exifError{"APP0 JFXX segment is too small"}
+	}
 	return nil
 }
 
 func fAPPReadJF(marker uint16, reader *JpegReader) (a APP, err error) {
-	app := &tAPP{offset: 10, endian: binary.BigEndian}
+	app := &tJFIFAPP{offset: reader.pos() - 2, endian: binary.BigEndian}
 	app.block, err = fAPPReadBlock(marker, reader, 0)
+	if err != nil {
+		return app, err
+	}
 	if app.HasID(idJFIF) {
 		return app, fAPPReadJFIF(app)
 	} else if app.HasID(idJFXX) {
-		return app, fAPPReadJFIF(app)
+		return app, fAPPReadJFXX(app)
 	}
 	return app, &exifError{"APP0 has wrong identifier, should be 'JFIF' or 'JFXX'"}
 }
@@ -81,7 +99,14 @@ func fAPPReadAPP1(marker uint16, reader *JpegReader) (a APP, err error) {
 		exif := &tEXIFAPP{block: app.block, offset: app.offset, endian: binary.BigEndian}
 		return exif, err
 	} else if app.HasID(idXMP) {
-		return app, nil
+		xmp := &tXMPAPP{block: app.block, offset: app.offset, endian: binary.BigEndian}
+		return xmp, err
+	} else if app.HasID(idXMPExtension) {
+		extension := &tXMPExtensionAPP{block: app.block, offset: app.offset, endian: binary.BigEndian}
+		if len(app.block) < 4+len(idXMPExtension)+40 {
+			return extension, &exifError{"APP1 Extended XMP segment is too small"}
+		}
+		return extension, err
 	}
 	return app, &exifError{"APP1 has wrong identifier, should be 'EXIF' or 'XMP'"}
 }
@@ -92,12 +117,15 @@ func fAPPReadICCPROFILE(app *tAPP) (err error) {
 }
 
 func fAPPReadAPP2(marker uint16, reader *JpegReader) (a APP, err error) {
-	app := &tAPP{offset: 10, endian: binary.BigEndian}
+	app := &tAPP{offset: reader.pos() - 2, endian: binary.BigEndian}
 	app.block, err = fAPPReadBlock(marker, reader, 0)
 	if app.HasID(idAPP2) {
 		return app, fAPPReadICCPROFILE(app)
+	} else if app.HasID(idMPF) {
+		mpf := &tMPFAPP{block: app.block, offset: app.offset, endian: binary.BigEndian}
+		return mpf, err
 	}
-	return app, &exifError{"APP2 has wrong identifier, should be 'ICC_PROFILE'"}
+	return app, &exifError{"APP2 has wrong identifier, should be 'ICC_PROFILE' or 'MPF'"}
 }
 
 func fAPPReadIPTC(marker uint16, reader *JpegReader) (a APP, err error) {
@@ -110,7 +138,16 @@ func fAPPReadIPTC(marker uint16, reader *JpegReader) (a APP, err error) {
 	return app, &exifError{"APP13 has wrong identifier, should be 'Photoshop 3.0\000'"}
 }
 
-func fAPPReadSOF0(marker uint16, reader *JpegReader) (a APP, err error) {
+func fAPPReadAdobe(marker uint16, reader *JpegReader) (a APP, err error) {
+	app := &tAdobeAPP{offset: reader.pos() - 2, endian: binary.BigEndian}
+	app.block, err = fAPPReadBlock(marker, reader, 0)
+	if app.HasID(idAdobe) && len(app.block) >= AdobeTransform+1 {
+		return app, nil
+	}
+	return app, &exifError{"APP14 has wrong identifier, should be 'Adobe'"}
+}
+
+func fAPPReadSOFn(marker uint16, reader *JpegReader) (a APP, err error) {
 	app := &tSOFnAPP{marker: marker, endian: binary.BigEndian}
 	app.block, err = fAPPReadBlock(marker, reader, 0)
 	return app, nil
@@ -127,6 +164,95 @@ func fAPPEnd(marker uint16, reader *JpegReader) (a APP, err error) {
 	return nil, nil
 }
 
+// fAPPReadOpaque reads any APPn segment that has no (working) parser, the
+// segment is kept as-is together with its identifier.
+func fAPPReadOpaque(marker uint16, reader *JpegReader) (a APP, err error) {
+	app := &tOpaqueAPP{tAPP: tAPP{offset: reader.pos() - 2, endian: binary.BigEndian}}
+	app.block, err = fAPPReadBlock(marker, reader, 0)
+	if err != nil {
+		return nil, err
+	}
+	app.identifier = readIdentifier(app.block[4:])
+	return app, nil
+}
+
+// readIdentifier returns the printable string at the start of an APPn payload,
+// e.g. "Ducky", "FPXR" or "Adobe".
+func readIdentifier(payload []byte) string {
+	n := 0
+	for n < len(payload) && n < 64 && payload[n] >= 0x20 && payload[n] < 0x7F {
+		n++
+	}
+	return string(payload[:n])
+}
+
+// fAPPReadSegment reads the segment following the marker. APPn segments are first offered to the registered
+// parsers, then to the built-in reader; if neither can handle it the segment is kept as an opaque segment.
+func fAPPReadSegment(marker uint16, reader *JpegReader) (a APP, err error) {
+	start := reader.cursor
+	if marker >= cAPP0 && marker <= cAPP15 {
+		if a, found, err := fAPPReadRegistered(marker, reader); found {
+			return a, err
+		}
+		reader.cursor = start
+		if segment, ok := aSegments[marker]; ok {
+			if a, err = segment.reader(marker, reader); err == nil {
+				return a, nil
+			}
+			reader.cursor = start
+		}
+		return fAPPReadOpaque(marker, reader)
+	}
+
+	segment, ok := aSegments[marker]
+	if !ok {
+		// Unknown or reserved marker, all of these carry a length
+		return fAPPReadIgnore(marker, reader)
+	}
+	if segment.reader == nil {
+		return fAPPEnd(marker, reader)
+	}
+	return segment.reader(marker, reader)
+}
+
+// SegmentParser decodes an APPn segment, the block holds the full segment
+// (marker, length and payload).
+type SegmentParser func(marker uint16, block []byte) (APP, error)
+
+type tSegmentParser struct {
+	marker uint16
+	prefix []byte
+	parser SegmentParser
+}
+
+var aSegmentParsers = []tSegmentParser{}
+
+// RegisterSegmentParser registers a parser for APPn segments with the given marker whose payload starts
+// with identifierPrefix, e.g. RegisterSegmentParser(0xFFEC, "Ducky", parseDucky). Parsers registered later
+// take precedence, and all registered parsers take precedence over the built-in ones. Registration is not
+// safe for concurrent use and is meant to be done at initialization.
+func RegisterSegmentParser(marker uint16, identifierPrefix string, parser SegmentParser) {
+	aSegmentParsers = append(aSegmentParsers, tSegmentParser{marker: marker, prefix: []byte(identifierPrefix), parser: parser})
+}
+
+func fAPPReadRegistered(marker uint16, reader *JpegReader) (a APP, found bool, err error) {
+	if len(aSegmentParsers) == 0 {
+		return nil, false, nil
+	}
+	block, err := fAPPReadBlock(marker, reader, 0)
+	if err != nil {
+		return nil, false, nil
+	}
+	for i := len(aSegmentParsers) - 1; i >= 0; i-- {
+		p := aSegmentParsers[i]
+		if p.marker == marker && bytes.HasPrefix(block[4:], p.prefix) {
+			a, err = p.parser(marker, block)
+			return a, true, err
+		}
+	}
+	return nil, false, nil
+}
+
 type tAPPReader func(uint16, *JpegReader) (APP, error)
 
 type tAPPSegment struct {
@@ -137,24 +263,28 @@ type tAPPSegment struct {
 
 var aSegments = map[uint16]tAPPSegment{
 
-	cSOI:  {name: "SOI", marker: cSOI, reader: nil},
-	cEOI:  {name: "EOI", marker: cEOI, reader: nil},
-	cJFIF: {name: "JFIF", marker: cJFIF, reader: fAPPReadJF},
-	cEXIF: {name: "EXIF", marker: cEXIF, reader: fAPPReadAPP1},
-	cICC:  {name: "ICC", marker: cICC, reader: fAPPReadAPP2},
-	cMETA: {name: "META", marker: cMETA, reader: fAPPReadIgnore},
-	cIPTC: {name: "IPTC", marker: cIPTC, reader: fAPPReadIPTC},
-
-	cSOF0:     {name: "SOF0", marker: cSOF0, reader: fAPPReadSOF0},
-	cSOF1:     {name: "SOF1", marker: cSOF1, reader: fAPPReadIgnore},
-	cSOF1 + 1: {name: "SOF2", marker: cSOF1 + 1, reader: fAPPReadIgnore},
-	cSOF1 + 2: {name: "SOF3", marker: cSOF1 + 2, reader: fAPPReadIgnore},
-	cSOF1 + 4: {name: "SOF5", marker: cSOF1 + 4, reader: fAPPReadIgnore},
-	cSOF1 + 5: {name: "SOF6", marker: cSOF1 + 5, reader: fAPPReadIgnore},
-	cSOF1 + 6: {name: "SOF7", marker: cSOF1 + 6, reader: fAPPReadIgnore},
-	cSOF1 + 8: {name: "SOF9", marker: cSOF1 + 8, reader: fAPPReadIgnore},
-	cSOF1 + 9: {name: "SOF10", marker: cSOF1 + 9, reader: fAPPReadIgnore},
-	cSOF11:    {name: "SOF11", marker: cSOF11, reader: fAPPReadIgnore},
+	cSOI:   {name: "SOI", marker: cSOI, reader: nil},
+	cEOI:   {name: "EOI", marker: cEOI, reader: nil},
+	cJFIF:  {name: "JFIF", marker: cJFIF, reader: fAPPReadJF},
+	cEXIF:  {name: "EXIF", marker: cEXIF, reader: fAPPReadAPP1},
+	cICC:   {name: "ICC", marker: cICC, reader: fAPPReadAPP2},
+	cMETA:  {name: "META", marker: cMETA, reader: fAPPReadIgnore},
+	cIPTC:  {name: "IPTC", marker: cIPTC, reader: fAPPReadIPTC},
+	cADOBE: {name: "Adobe", marker: cADOBE, reader: fAPPReadAdobe},
+
+	cSOF0:      {name: "SOF0", marker: cSOF0, reader: fAPPReadSOFn},
+	cSOF1:      {name: "SOF1", marker: cSOF1, reader: fAPPReadSOFn},
+	cSOF1 + 1:  {name: "SOF2", marker: cSOF1 + 1, reader: fAPPReadSOFn},
+	cSOF1 + 2:  {name: "SOF3", marker: cSOF1 + 2, reader: fAPPReadSOFn},
+	cSOF1 + 4:  {name: "SOF5", marker: cSOF1 + 4, reader: fAPPReadSOFn},
+	cSOF1 + 5:  {name: "SOF6", marker: cSOF1 + 5, reader: fAPPReadSOFn},
+	cSOF1 + 6:  {name: "SOF7", marker: cSOF1 + 6, reader: fAPPReadSOFn},
+	cSOF1 + 8:  {name: "SOF9", marker: cSOF1 + 8, reader: fAPPReadSOFn},
+	cSOF1 + 9:  {name: "SOF10", marker: cSOF1 + 9, reader: fAPPReadSOFn},
+	cSOF11:     {name: "SOF11", marker: cSOF11, reader: fAPPReadSOFn},
+	cSOF11 + 2: {name: "SOF13", marker: cSOF11 + 2, reader: fAPPReadSOFn},
+	cSOF11 + 3: {name: "SOF14", marker: cSOF11 + 3, reader: fAPPReadSOFn},
+	cSOF11 + 4: {name: "SOF15", marker: cSOF11 + 4, reader: fAPPReadSOFn},
 
 	cDHT: {name: "cDHT", marker: cDHT, reader: fAPPReadIgnore},
 	cDAC: {name: "cDAC", marker: cDAC, reader: fAPPReadIgnore},
@@ -216,6 +346,9 @@ func (t tAPP) ID(cid []byte) (id []byte) {
 	return
 }
 func (t tAPP) HasID(cid []byte) bool {
+	if len(t.block) < 4+len(cid) {
+		return false
+	}
 	id := t.block[4 : 4+len(cid)]
 	for i, b := range id {
 		if b != cid[i] {
@@ -229,3 +362,27 @@ func (t tAPP) ReadValue(tagID2Find uint16) (interface{}, error) {
 	fmt.Printf("Read value of tag:0x%X in APP:BASIC\n", tagID2Find)
 	return int(0), nil
 }
+
+// tOpaqueAPP is an APPn segment that is not decoded, it is kept as-is
+type tOpaqueAPP struct {
+	tAPP
+	identifier string
+}
+
+func (t tOpaqueAPP) Name() string {
+	return fmt.Sprintf("APP%d:%s", t.Marker()&0x0F, t.identifier)
+}
+
+// Identifier returns the identifier string at the start of the segment
+func (t tOpaqueAPP) Identifier() string {
+	return t.identifier
+}
+
+// Payload returns the data of the segment, including the identifier
+func (t tOpaqueAPP) Payload() []byte {
+	return t.block[4:]
+}
+
+func (t tOpaqueAPP) ReadValue(tagID2Find uint16) (interface{}, error) {
+	return nil, &exifError{fmt.Sprintf("Segment '%s' is not decoded, tag 0x%X cannot be read", t.Name(), tagID2Find)}
+}
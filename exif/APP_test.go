@@ -0,0 +1,50 @@
+package ImgMeta
+
+import (
+	"testing"
+)
+
+// tRenamedAPP is a segment of a registered parser that uses the name of a built-in segment
+type tRenamedAPP struct {
+	tAPP
+	name string
+}
+
+func (t tRenamedAPP) Name() string {
+	return t.name
+}
+
+func TestRegisteredParserWithBuiltinName(t *testing.T) {
+	saved := aSegmentParsers
+	defer func() { aSegmentParsers = saved }()
+	for _, p := range []struct{ name, prefix string }{{"EXIF", "Exif"}, {"XMP", "http://ns.adobe.com/xap"}, {"MPF", "MPF"}, {"JFIF", "JFIF"}} {
+		name := p.name
+		RegisterSegmentParser(0xFFE1, p.prefix, func(marker uint16, block []byte) (APP, error) {
+			return &tRenamedAPP{tAPP: tAPP{block: block}, name: name}, nil
+		})
+	}
+	image := readTestImage(t)
+	if _, exists := image.apps["EXIF"].(*tRenamedAPP); !exists {
+		t.Fatal("EXIF was not read by the registered parser")
+	}
+	if _, err := NewExifWriterFrom(image); err == nil {
+		t.Errorf("NewExifWriterFrom: no error")
+	}
+	if _, err := NewXMPWriterFrom(image); err == nil {
+		t.Errorf("NewXMPWriterFrom: no error")
+	}
+	if _, err := image.ReadXMPValue(XmpNsXMP, "Rating"); err == nil {
+		t.Errorf("ReadXMPValue: no error")
+	}
+	image.apps["MPF"] = &tRenamedAPP{name: "MPF"}
+	image.apps["JFIF"] = &tRenamedAPP{name: "JFIF"}
+	if _, _, err := image.MPImages(); err == nil {
+		t.Errorf("MPImages: no error")
+	}
+	if _, err := image.JFIF(); err == nil {
+		t.Errorf("JFIF: no error")
+	}
+	image.GainMap()
+	image.Summary()
+	GetBasicInfo(image)
+}
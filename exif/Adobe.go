@@ -0,0 +1,154 @@
+package ImgMeta
+
+import (
+	"encoding/binary"
+)
+
+/*
+Structure of an Adobe APP14 segment
+
+Adobe applications (Photoshop, Illustrator, Acrobat) write an APP14 segment that tells the decoder which colour
+transform was applied to the components before compression. Without it a decoder has to guess if a 3 component
+image is RGB or YCbCr and if a 4 component image is CMYK or YCCK.
+
+    [Record name]    [size]   [description]
+    ---------------------------------------
+    Identifier       5 bytes  ("Adobe" = 0x41646F6265), not NUL terminated
+    Version          2 bytes  DCTEncode/DCTDecode version (usually 100 or 101)
+    Flags0           2 bytes  0x8000 bit: encoder used blend=1 downsampling
+    Flags1           2 bytes  0
+    Transform        1 byte   colour transform (0: unknown, RGB or CMYK
+                                                1: YCbCr
+                                                2: YCCK)
+
+Note that Adobe writes CMYK and YCCK images with inverted values (0 is full ink), any JPEG with an APP14 Adobe
+segment and 4 components should be treated as inverted.
+
+*/
+
+// Adobe APP14 fields, given as offset in the segment
+const (
+	AdobeVersion   = 0x0009
+	AdobeFlags0    = 0x000B
+	AdobeFlags1    = 0x000D
+	AdobeTransform = 0x000F
+)
+
+// Adobe colour transform values
+const (
+	AdobeTransformUnknown uint8 = 0
+	AdobeTransformYCbCr   uint8 = 1
+	AdobeTransformYCCK    uint8 = 2
+)
+
+type tAdobeAPP struct {
+	offset uint64           // Offset of this APP in the file
+	endian binary.ByteOrder // Byte-Order
+	block  []byte           // full APP block
+}
+
+func (t tAdobeAPP) Name() string {
+	return "Adobe"
+}
+func (t tAdobeAPP) Marker() uint16 {
+	return t.endian.Uint16(t.block)
+}
+func (t tAdobeAPP) Length() uint16 {
+	return t.endian.Uint16(t.block[2:])
+}
+func (t tAdobeAPP) ID(cid []byte) (id []byte) {
+	id = t.block[4 : 4+len(cid)]
+	return
+}
+func (t tAdobeAPP) HasID(cid []byte) bool {
+	if len(t.block) < 4+len(cid) {
+		return false
+	}
+	id := t.block[4 : 4+len(cid)]
+	for i, b := range id {
+		if b != cid[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (t tAdobeAPP) ReadValue(tagID2Find uint16) (interface{}, error) {
+	switch tagID2Find {
+	case AdobeVersion, AdobeFlags0, AdobeFlags1:
+		return t.endian.Uint16(t.block[tagID2Find:]), nil
+	case AdobeTransform:
+		return t.Transform(), nil
+	}
+	return int(0), &exifError{"Reading Adobe tag value failed"}
+}
+
+// Transform returns the colour transform byte
+func (t tAdobeAPP) Transform() uint8 {
+	return t.block[AdobeTransform]
+}
+
+// ColorModel is the colour model of the compressed image components
+type ColorModel int
+
+const (
+	ColorModelUnknown   ColorModel = iota
+	ColorModelGrayscale ColorModel = iota
+	ColorModelRGB       ColorModel = iota
+	ColorModelYCbCr     ColorModel = iota
+	ColorModelCMYK      ColorModel = iota
+	ColorModelYCCK      ColorModel = iota
+)
+
+var aColorModelNames = map[ColorModel]string{
+	ColorModelUnknown:   "Unknown",
+	ColorModelGrayscale: "Grayscale",
+	ColorModelRGB:       "RGB",
+	ColorModelYCbCr:     "YCbCr",
+	ColorModelCMYK:      "CMYK",
+	ColorModelYCCK:      "YCCK",
+}
+
+func (c ColorModel) String() string {
+	return aColorModelNames[c]
+}
+
+// ColorModel determines the colour model of the image from the component count of the frame
+// and the Adobe APP14 transform flag, following the same rules as libjpeg. Inverted is true
+// for CMYK and YCCK images written by Adobe, which store 0 as full ink.
+func (i Image) ColorModel() (model ColorModel, inverted bool, err error) {
+	var sof *tSOFnAPP
+	for _, app := range i.apps {
+		if s, ok := app.(*tSOFnAPP); ok {
+			sof = s
+			break
+		}
+	}
+	if sof == nil {
+		return ColorModelUnknown, false, &exifError{"Image does not have a 'SOFn' section"}
+	}
+
+	adobe, hasAdobe := i.apps["Adobe"].(*tAdobeAPP)
+	ids := sof.ComponentIDs()
+	switch len(ids) {
+	case 1:
+		return ColorModelGrayscale, false, nil
+	case 3:
+		if hasAdobe {
+			if adobe.Transform() == AdobeTransformUnknown {
+				return ColorModelRGB, false, nil
+			}
+			return ColorModelYCbCr, false, nil
+		}
+		if !i.hasMarker(cJFIF) && ids[0] == 'R' && ids[1] == 'G' && ids[2] == 'B' {
+			return ColorModelRGB, false, nil
+		}
+		return ColorModelYCbCr, false, nil
+	case 4:
+		if hasAdobe && adobe.Transform() == AdobeTransformYCCK {
+			return ColorModelYCCK, true, nil
+		}
+		return ColorModelCMYK, hasAdobe, nil
+	}
+	return ColorModelUnknown, false, nil
+}
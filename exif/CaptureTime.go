@@ -0,0 +1,231 @@
+package ImgMeta
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+/*
+Capture time
+
+EXIF DateTimeOriginal is the wall clock of the camera without a time zone, sorting photos of cameras set to different
+zones (or of a trip through several zones) by it puts them out of order. CaptureTime combines the sources to get the
+moment the photo was taken, and tells how sure it is:
+
+    Source              Time                                         Offset
+    DateTimeOriginal    EXIF 0x9003 + SubsecTimeOriginal (0x9291)    OffsetTimeOriginal (0x9011, EXIF 2.31), or
+                                                                     derived from the GPS time, or taken from an
+                                                                     XMP/IPTC date with the same wall clock
+    DateTimeDigitized   EXIF 0x9004 + SubsecTimeDigitized (0x9292)   OffsetTimeDigitized (0x9012)
+    XMP                 exif:DateTimeOriginal, photoshop:DateCreated when given
+    IPTC                2:55 DateCreated + 2:60 TimeCreated          when given
+    GPS                 GPSDateStamp + GPSTimestamp                  UTC
+
+    Confidence      Meaning
+    Exact           the offset was recorded, Time is the moment of capture
+    DerivedOffset   the offset was derived from the GPS time, rounded to 15 minutes
+    Local           the wall clock only; Time is returned in UTC as if the wall clock were UTC
+    DateOnly        the day only, at 00:00 (UTC)
+    None            no capture time, Time is zero
+
+Malformed values (blank, "0000:00:00 00:00:00", unknown layouts, offsets outside ±14 hours) are skipped. The GPS
+time is often the time of the last fix, it is only used for the offset when it is within 5 minutes of a whole
+15 minute offset.
+
+*/
+
+// CaptureSource is where the capture time was read from
+type CaptureSource int
+
+// Capture time sources
+const (
+	CaptureSourceNone CaptureSource = iota
+	CaptureSourceDateTimeOriginal
+	CaptureSourceDateTimeDigitized
+	CaptureSourceXMP
+	CaptureSourceIPTC
+	CaptureSourceGPS
+)
+
+func (s CaptureSource) String() string {
+	return [...]string{"none", "EXIF DateTimeOriginal", "EXIF DateTimeDigitized", "XMP", "IPTC", "GPS"}[s]
+}
+
+// CaptureConfidence tells how precise the capture time is, a higher value is more precise
+type CaptureConfidence int
+
+// Capture time confidences
+const (
+	CaptureConfidenceNone CaptureConfidence = iota
+	CaptureConfidenceDateOnly
+	CaptureConfidenceLocal
+	CaptureConfidenceDerivedOffset
+	CaptureConfidenceExact
+)
+
+func (c CaptureConfidence) String() string {
+	return [...]string{"none", "date only", "local", "derived offset", "exact"}[c]
+}
+
+// CaptureTimestamp is the resolved capture time of an image
+type CaptureTimestamp struct {
+	Time       time.Time
+	Source     CaptureSource
+	Confidence CaptureConfidence
+}
+
+const (
+	cMaxUTCOffset       = 14 * time.Hour
+	cUTCOffsetStep      = 15 * time.Minute
+	cGPSOffsetTolerance = 5 * time.Minute
+)
+
+// CaptureTime returns the time the photo was taken, from the most precise source.
+// Examples:
+//
+//	capture := image.CaptureTime()
+//	if capture.Confidence >= ImgMeta.CaptureConfidenceDerivedOffset {
+//		fmt.Println(capture.Time.UTC())
+//	}
+func (i Image) CaptureTime() CaptureTimestamp {
+	s := i.metadataSources()
+	exifTimes := []struct {
+		source         CaptureSource
+		tag, sub, zone uint16
+	}{
+		{CaptureSourceDateTimeOriginal, ExifTagDateTimeOriginal, ExifTagSubsecTimeOriginal, ExifTagOffsetTimeOriginal},
+		{CaptureSourceDateTimeDigitized, ExifTagDateTimeDigitized, ExifTagSubsecTimeDigitized, ExifTagOffsetTimeDigitized},
+	}
+	for _, e := range exifTimes {
+		wall, err := parseExifDateTime(s.exifString(IFDExif, e.tag), s.exifString(IFDExif, e.sub))
+		if err != nil {
+			continue
+		}
+		if offset, err := parseUTCOffset(s.exifString(IFDExif, e.zone)); err == nil {
+			return CaptureTimestamp{withOffset(wall, offset), e.source, CaptureConfidenceExact}
+		}
+		if e.source != CaptureSourceDateTimeOriginal {
+			return CaptureTimestamp{wall, e.source, CaptureConfidenceLocal}
+		}
+		if utc, ok := s.gpsTime(); ok {
+			if offset, ok := gpsOffset(wall, utc); ok {
+				return CaptureTimestamp{withOffset(wall, offset), e.source, CaptureConfidenceDerivedOffset}
+			}
+		}
+		for _, other := range []CaptureTimestamp{s.xmpCaptureTime(), s.iptcCaptureTime()} {
+			_, offset := other.Time.Zone()
+			sameWallClock := other.Time.Format("2006-01-02T15:04:05") == wall.Format("2006-01-02T15:04:05")
+			if other.Confidence == CaptureConfidenceExact && sameWallClock {
+				return CaptureTimestamp{withOffset(wall, time.Duration(offset)*time.Second), e.source, CaptureConfidenceExact}
+			}
+		}
+		return CaptureTimestamp{wall, e.source, CaptureConfidenceLocal}
+	}
+
+	best := CaptureTimestamp{}
+	for _, candidate := range []CaptureTimestamp{s.xmpCaptureTime(), s.iptcCaptureTime()} {
+		if candidate.Confidence > best.Confidence {
+			best = candidate
+		}
+	}
+	if best.Confidence < CaptureConfidenceExact {
+		if utc, ok := s.gpsTime(); ok {
+			return CaptureTimestamp{utc, CaptureSourceGPS, CaptureConfidenceExact}
+		}
+	}
+	return best
+}
+
+// xmpCaptureTime reads exif:DateTimeOriginal, or else photoshop:DateCreated
+func (s tMetadataSources) xmpCaptureTime() CaptureTimestamp {
+	for _, name := range [][2]string{{XmpNsEXIF, "DateTimeOriginal"}, {XmpNsPhotoshop, "DateCreated"}} {
+		value := strings.TrimSpace(firstString(s.xmpStrings(name[0], name[1])))
+		t, err := parseXMPDate(value)
+		if err != nil || t.Year() == 0 {
+			continue
+		}
+		clock := ""
+		if n := strings.IndexByte(value, 'T'); n >= 0 {
+			clock = value[n+1:]
+		}
+		switch {
+		case clock == "":
+			return CaptureTimestamp{t, CaptureSourceXMP, CaptureConfidenceDateOnly}
+		case strings.HasSuffix(clock, "Z") || strings.ContainsAny(clock, "+-"):
+			return CaptureTimestamp{t, CaptureSourceXMP, CaptureConfidenceExact}
+		}
+		return CaptureTimestamp{t, CaptureSourceXMP, CaptureConfidenceLocal}
+	}
+	return CaptureTimestamp{}
+}
+
+// iptcCaptureTime reads DateCreated and TimeCreated
+func (s tMetadataSources) iptcCaptureTime() CaptureTimestamp {
+	date := firstString(s.iptcStrings(IptcTagApplication2DateCreated))
+	clock := firstString(s.iptcStrings(IptcTagApplication2TimeCreated))
+	t, err := parseIPTCDateTime(date, clock)
+	if err != nil || t.Year() == 0 {
+		return CaptureTimestamp{}
+	}
+	if _, err := time.Parse("150405-0700", clock); err == nil {
+		return CaptureTimestamp{t, CaptureSourceIPTC, CaptureConfidenceExact}
+	}
+	if _, err := time.Parse("150405", clock); err != nil {
+		return CaptureTimestamp{t, CaptureSourceIPTC, CaptureConfidenceDateOnly}
+	}
+	return CaptureTimestamp{t, CaptureSourceIPTC, CaptureConfidenceLocal}
+}
+
+// gpsTime reads the UTC time of the GPS date and time stamps
+func (s tMetadataSources) gpsTime() (time.Time, bool) {
+	date, err := time.Parse("2006:01:02", s.exifString(IFDGPS, ExifGpsTagGPSDateStamp))
+	if err != nil || date.Year() == 0 {
+		return time.Time{}, false
+	}
+	value, found := s.exifValue(IFDGPS, ExifGpsTagGPSTimestamp)
+	hms := exifFloats(value)
+	if !found || len(hms) != 3 {
+		return time.Time{}, false
+	}
+	seconds := hms[0]*3600 + hms[1]*60 + hms[2]
+	if math.IsNaN(seconds) || seconds < 0 || seconds >= 24*3600 {
+		return time.Time{}, false
+	}
+	return date.Add(time.Duration(seconds * float64(time.Second))), true
+}
+
+// gpsOffset derives the UTC offset of a wall clock time from the GPS time
+func gpsOffset(wall time.Time, utc time.Time) (time.Duration, bool) {
+	difference := wall.Sub(utc)
+	offset := difference.Round(cUTCOffsetStep)
+	if offset < -cMaxUTCOffset || offset > cMaxUTCOffset {
+		return 0, false
+	}
+	if deviation := difference - offset; deviation > cGPSOffsetTolerance || deviation < -cGPSOffsetTolerance {
+		return 0, false
+	}
+	return offset, true
+}
+
+// parseUTCOffset parses an EXIF offset "+HH:MM" or "-HH:MM"
+func parseUTCOffset(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	t, err := time.Parse("-07:00", value)
+	if err != nil {
+		return 0, &exifError{fmt.Sprintf("EXIF offset '%s' is malformed", value)}
+	}
+	_, seconds := t.Zone()
+	offset := time.Duration(seconds) * time.Second
+	if offset < -cMaxUTCOffset || offset > cMaxUTCOffset {
+		return 0, &exifError{fmt.Sprintf("EXIF offset '%s' is out of range", value)}
+	}
+	return offset, nil
+}
+
+// withOffset returns the wall clock time (read as UTC) in the zone with the given offset
+func withOffset(wall time.Time, offset time.Duration) time.Time {
+	zone := time.FixedZone("", int(offset/time.Second))
+	return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(), zone)
+}
@@ -0,0 +1,355 @@
+package ImgMeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+//go:generate python3 gen_jis.py
+
+/*
+Character codes
+
+UserComment (0x9286), GPSProcessingMethod (0x1B) and GPSAreaInformation (0x1C) are UNDEFINED values that start with
+an 8 byte character code telling how the text after it is encoded:
+
+    Code                        Encoding
+    "ASCII\0\0\0"               ITU-T T.50 IA5 (ASCII)
+    "UNICODE\0"                 UCS-2 (UTF-16) in the byte order of the TIFF header, or of a byte order mark
+    "JIS\0\0\0\0\0"             JIS X 0208, as Shift-JIS or as ISO-2022-JP (with escape sequences)
+    "\0\0\0\0\0\0\0\0"          undefined, read as UTF-8 (or Latin-1 when it is not valid UTF-8)
+
+Some writers put UNICODE text in the wrong byte order, when the other order gives clearly more ASCII characters
+that order is used. JIS text is decoded with the Windows code page 932 table (JISTable.go, written by gen_jis.py), so
+the NEC extensions and the IBM extensions (both the NEC selected rows 89-92 and the IBM block 0xFA40-0xFC4B of
+Shift-JIS) read too. The text may be padded with NULs or spaces, these are removed.
+
+When writing, SetText uses ASCII when it can and UNICODE (in the byte order of the writer) otherwise;
+EncodeCharacterCode writes the given code, JIS as Shift-JIS.
+
+*/
+
+// Character code names
+const (
+	CharacterCodeASCII     = "ASCII"
+	CharacterCodeUnicode   = "UNICODE"
+	CharacterCodeJIS       = "JIS"
+	CharacterCodeUndefined = ""
+)
+
+// aCharacterCodes are the 8 byte prefixes of the character codes
+var aCharacterCodes = map[string][]byte{
+	CharacterCodeASCII:     {'A', 'S', 'C', 'I', 'I', 0, 0, 0},
+	CharacterCodeUnicode:   {'U', 'N', 'I', 'C', 'O', 'D', 'E', 0},
+	CharacterCodeJIS:       {'J', 'I', 'S', 0, 0, 0, 0, 0},
+	CharacterCodeUndefined: {0, 0, 0, 0, 0, 0, 0, 0},
+}
+
+// DecodeCharacterCode decodes a value that starts with a character code, order is the byte order of the TIFF
+// header. It returns the text and the name of the character code.
+func DecodeCharacterCode(data []byte, order binary.ByteOrder) (text string, code string, err error) {
+	if len(data) < 8 {
+		return "", "", &exifError{"Value is too short for a character code"}
+	}
+	prefix, data := data[:8], data[8:]
+	code = "unknown"
+	for name, p := range aCharacterCodes {
+		if bytes.Equal(prefix, p) {
+			code = name
+			break
+		}
+	}
+	switch code {
+	case CharacterCodeASCII:
+		text = string(data)
+	case CharacterCodeUnicode:
+		text = decodeUTF16(data, order)
+	case CharacterCodeJIS:
+		text = decodeJIS(data)
+	case CharacterCodeUndefined:
+		if len(data) > 0 && !utf8.Valid(data) {
+			text = latin1(string(data))
+		} else {
+			text = string(data)
+		}
+	default:
+		return "", "", &exifError{fmt.Sprintf("Unknown character code '%s'", strings.TrimRight(string(prefix), "\x00"))}
+	}
+	return trimCharacterCode(text), code, nil
+}
+
+// EncodeCharacterCode encodes text with the given character code, order is the byte order of the TIFF header
+func EncodeCharacterCode(text string, code string, order binary.ByteOrder) ([]byte, error) {
+	prefix, known := aCharacterCodes[code]
+	if !known {
+		return nil, &exifError{fmt.Sprintf("Unknown character code '%s'", code)}
+	}
+	data := append([]byte{}, prefix...)
+	switch code {
+	case CharacterCodeASCII:
+		for _, r := range text {
+			if r >= 0x80 {
+				return nil, &exifError{fmt.Sprintf("'%c' is not ASCII", r)}
+			}
+		}
+		data = append(data, text...)
+	case CharacterCodeUnicode:
+		for _, unit := range utf16.Encode([]rune(text)) {
+			data = append(data, 0, 0)
+			order.PutUint16(data[len(data)-2:], unit)
+		}
+	case CharacterCodeJIS:
+		encoded, err := encodeShiftJIS(text)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, encoded...)
+	default:
+		data = append(data, text...)
+	}
+	return data, nil
+}
+
+// decodeUTF16 decodes UTF-16, a byte order mark overrides the given byte order
+func decodeUTF16(data []byte, order binary.ByteOrder) string {
+	if len(data) >= 2 {
+		switch {
+		case data[0] == 0xFE && data[1] == 0xFF:
+			order, data = binary.BigEndian, data[2:]
+		case data[0] == 0xFF && data[1] == 0xFE:
+			order, data = binary.LittleEndian, data[2:]
+		default:
+			order = guessUTF16Order(data, order)
+		}
+	}
+	units := make([]uint16, len(data)/2)
+	for n := range units {
+		units[n] = order.Uint16(data[2*n:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// guessUTF16Order returns the other byte order when that gives clearly more ASCII characters
+func guessUTF16Order(data []byte, order binary.ByteOrder) binary.ByteOrder {
+	other := binary.ByteOrder(binary.LittleEndian)
+	if order == binary.LittleEndian {
+		other = binary.BigEndian
+	}
+	ascii, otherASCII := 0, 0
+	for n := 0; n+1 < len(data); n += 2 {
+		if unit := order.Uint16(data[n:]); unit > 0 && unit < 0x80 {
+			ascii++
+		}
+		if unit := other.Uint16(data[n:]); unit > 0 && unit < 0x80 {
+			otherASCII++
+		}
+	}
+	if otherASCII > 2*ascii {
+		return other
+	}
+	return order
+}
+
+// jisRune returns the character of a JIS X 0208 row and cell (1 to 94), rows 115 to 120 are the IBM extension of
+// Shift-JIS
+func jisRune(row int, cell int) rune {
+	code := uint16(0)
+	switch {
+	case cell < 1 || cell > 94:
+	case row >= 1 && row <= 94:
+		code = aJISX0208[(row-1)*94+cell-1]
+	case row >= 115 && row <= 120:
+		code = aIBMExtension[(row-115)*94+cell-1]
+	}
+	if code == 0 {
+		return utf8.RuneError
+	}
+	return rune(code)
+}
+
+// decodeJIS decodes ISO-2022-JP when the text has escape sequences, and Shift-JIS otherwise
+func decodeJIS(data []byte) string {
+	if bytes.IndexByte(data, 0x1B) >= 0 {
+		return decodeISO2022JP(data)
+	}
+	return decodeShiftJIS(data)
+}
+
+func decodeShiftJIS(data []byte) string {
+	var text strings.Builder
+	for n := 0; n < len(data); n++ {
+		b := data[n]
+		switch {
+		case b < 0x80:
+			text.WriteByte(b)
+		case b >= 0xA1 && b <= 0xDF:
+			text.WriteRune(rune(0xFF61 + int(b) - 0xA1))
+		case (b >= 0x81 && b <= 0x9F || b >= 0xE0 && b <= 0xFC) && n+1 < len(data) && data[n+1] >= 0x40 && data[n+1] <= 0xFC && data[n+1] != 0x7F:
+			trail := int(data[n+1])
+			n++
+			row := int(b) - 0x81
+			if b >= 0xE0 {
+				row = int(b) - 0xC1
+			}
+			row = 2*row + 1
+			cell := trail - 0x3F
+			if trail >= 0x80 {
+				cell--
+			}
+			if trail >= 0x9F {
+				row, cell = row+1, trail-0x9E
+			}
+			text.WriteRune(jisRune(row, cell))
+		default:
+			text.WriteRune(utf8.RuneError)
+		}
+	}
+	return text.String()
+}
+
+func decodeISO2022JP(data []byte) string {
+	const (
+		ascii = iota
+		jisX0208
+		katakana
+	)
+	var text strings.Builder
+	mode := ascii
+	for n := 0; n < len(data); n++ {
+		if data[n] == 0x1B && n+2 < len(data) {
+			switch string(data[n+1 : n+3]) {
+			case "(B", "(J":
+				mode = ascii
+			case "$@", "$B":
+				mode = jisX0208
+			case "(I":
+				mode = katakana
+			default:
+				text.WriteRune(utf8.RuneError)
+				continue
+			}
+			n += 2
+			continue
+		}
+		b := data[n]
+		switch {
+		case b >= 0x80:
+			text.WriteRune(utf8.RuneError)
+		case b < 0x21 || b > 0x7E || mode == ascii:
+			text.WriteByte(b)
+		case mode == katakana && b <= 0x5F:
+			text.WriteRune(rune(0xFF61 + int(b) - 0x21))
+		case mode == jisX0208 && n+1 < len(data):
+			text.WriteRune(jisRune(int(b)-0x20, int(data[n+1])-0x20))
+			n++
+		default:
+			text.WriteRune(utf8.RuneError)
+		}
+	}
+	return text.String()
+}
+
+var (
+	jisCodesOnce sync.Once
+	jisCodes     map[rune]int // character to (row-1)*94 + cell-1, the NEC selected rows before the IBM extension
+)
+
+func encodeShiftJIS(text string) ([]byte, error) {
+	jisCodesOnce.Do(func() {
+		jisCodes = map[rune]int{}
+		for index, r := range aJISX0208 {
+			if _, exists := jisCodes[rune(r)]; r != 0 && !exists {
+				jisCodes[rune(r)] = index
+			}
+		}
+		for index, r := range aIBMExtension {
+			if _, exists := jisCodes[rune(r)]; r != 0 && !exists {
+				jisCodes[rune(r)] = 114*94 + index
+			}
+		}
+	})
+	data := []byte{}
+	for _, r := range text {
+		switch index, exists := jisCodes[r]; {
+		case r < 0x80:
+			data = append(data, byte(r))
+		case r >= 0xFF61 && r <= 0xFF9F:
+			data = append(data, byte(r-0xFF61+0xA1))
+		case exists:
+			row, cell := index/94+1, index%94+1
+			lead := (row-1)/2 + 0x81
+			if lead > 0x9F {
+				lead += 0x40
+			}
+			trail := cell + 0x9E
+			if row%2 == 1 {
+				trail = cell + 0x3F
+				if trail >= 0x7F {
+					trail++
+				}
+			}
+			data = append(data, byte(lead), byte(trail))
+		default:
+			return nil, &exifError{fmt.Sprintf("'%c' can not be encoded as JIS", r)}
+		}
+	}
+	return data, nil
+}
+
+func trimCharacterCode(text string) string {
+	return strings.TrimRight(text, "\x00 ")
+}
+
+// SetText sets a tag that holds text with a character code (e.g. UserComment), as ASCII when the text is ASCII
+// and as UNICODE otherwise
+func (w *ExifWriter) SetText(ifdType uint16, id uint16, text string) error {
+	code := CharacterCodeASCII
+	for _, r := range text {
+		if r >= 0x80 {
+			code = CharacterCodeUnicode
+			break
+		}
+	}
+	data, err := EncodeCharacterCode(text, code, w.endian)
+	if err != nil {
+		return err
+	}
+	return w.SetTag(ifdType, id, data)
+}
+
+// Text returns the text of a tag that holds text with a character code
+func (w *ExifWriter) Text(ifdType uint16, id uint16) (string, error) {
+	value, exists := w.Tag(ifdType, id)
+	if !exists {
+		return "", &exifError{fmt.Sprintf("Tag 0x%04X is not set", id)}
+	}
+	data, ok := value.([]byte)
+	if !ok {
+		return "", &exifError{fmt.Sprintf("Tag 0x%04X does not hold text with a character code", id)}
+	}
+	text, _, err := DecodeCharacterCode(data, w.endian)
+	return text, err
+}
+
+// UserComment returns the EXIF UserComment decoded to UTF-8
+func (i Image) UserComment() (string, error) {
+	s := i.metadataSources()
+	if s.exif == nil {
+		return "", &exifError{"Image does not have 'EXIF' meta section"}
+	}
+	value, err := s.exif.ReadIFDValue(IFDExif, ExifTagUserComment)
+	if err != nil {
+		return "", err
+	}
+	data, ok := value.([]byte)
+	if !ok {
+		return "", &exifError{"UserComment does not hold text with a character code"}
+	}
+	text, _, err := DecodeCharacterCode(data, s.exif.endian)
+	return text, err
+}
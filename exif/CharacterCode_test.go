@@ -0,0 +1,126 @@
+package ImgMeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDecodeShiftJIS(t *testing.T) {
+	for _, test := range []struct {
+		data []byte
+		want string
+	}{
+		{[]byte{0x82, 0xA0}, "あ"},              // row 4, first cell of an even row
+		{[]byte{0x88, 0x9F}, "亜"},              // row 16, first kanji
+		{[]byte{0x93, 0xFA, 0x96, 0x7B}, "日本"}, // trail below and above 0x7F
+		{[]byte{0x81, 0x40}, "　"},              // row 1, cell 1
+		{[]byte{0x81, 0x5C, 0x81, 0x60}, "―～"}, // cp932 mappings
+		{[]byte{0x83, 0x41}, "ア"},
+		{[]byte{0x87, 0x40}, "①"}, // NEC row 13
+		{[]byte{0xED, 0x40}, "纊"}, // NEC selected IBM extension, row 89
+		{[]byte{0xEA, 0xA4}, "熙"}, // last cell of row 84
+		{[]byte{0xFA, 0x40}, "ⅰ"}, // IBM extension
+		{[]byte{0xFA, 0x55}, "￤"}, // IBM extension
+		{[]byte{0xFC, 0x4B}, "黑"}, // last character of the IBM extension
+		{[]byte{0xB1}, "ｱ"},       // half-width katakana
+		{[]byte{'a', 0x82, 0xA0, 'b'}, "aあb"},
+		{[]byte{0x82}, string(utf8.RuneError)},                // truncated
+		{[]byte{0x82, 0x7F}, string(utf8.RuneError) + "\x7F"}, // invalid trail byte
+		{[]byte{0xFC, 0xFC}, string(utf8.RuneError)},          // unassigned
+	} {
+		if got := decodeShiftJIS(test.data); got != test.want {
+			t.Errorf("% X: %q, want %q", test.data, got, test.want)
+		}
+	}
+}
+
+func TestDecodeISO2022JP(t *testing.T) {
+	for _, test := range []struct {
+		data []byte
+		want string
+	}{
+		{[]byte("\x1b$BF|K\\8l\x1b(B"), "日本語"},
+		{[]byte("a\x1b$B$\"\x1b(Bb"), "aあb"},
+		{[]byte("\x1b$@F|\x1b(J!"), "日!"},
+		{[]byte("\x1b(I1\x1b(B"), "ｱ"},
+		{[]byte("\x1b$BF"), string(utf8.RuneError)}, // truncated
+	} {
+		if got := decodeISO2022JP(test.data); got != test.want {
+			t.Errorf("%q: %q, want %q", test.data, got, test.want)
+		}
+	}
+}
+
+func TestCharacterCodeRoundTrip(t *testing.T) {
+	for _, test := range []struct {
+		code string
+		text string
+	}{
+		{CharacterCodeASCII, "Hello, world"},
+		{CharacterCodeJIS, "日本語のテキスト"},
+		{CharacterCodeJIS, "ｱｲｳ ①② ⅰⅱ 纊 黑 ～"},
+		{CharacterCodeJIS, "mixed ASCII と漢字"},
+		{CharacterCodeUnicode, "Grüße, 日本 😀"},
+		{CharacterCodeUnicode, "plain ASCII"},
+		{CharacterCodeUndefined, "undefined ü"},
+	} {
+		for _, order := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+			data, err := EncodeCharacterCode(test.text, test.code, order)
+			if err != nil {
+				t.Errorf("%s %q: %v", test.code, test.text, err)
+				continue
+			}
+			text, code, err := DecodeCharacterCode(data, order)
+			if err != nil || text != test.text || code != test.code {
+				t.Errorf("%s %q %v: %q %s %v", test.code, test.text, order, text, code, err)
+			}
+		}
+	}
+	if _, err := EncodeCharacterCode("€", CharacterCodeJIS, binary.BigEndian); err == nil {
+		t.Errorf("€ encoded as JIS")
+	}
+}
+
+func TestEncodeShiftJIS(t *testing.T) {
+	for _, test := range []struct {
+		text string
+		want []byte
+	}{
+		{"あ", []byte{0x82, 0xA0}},
+		{"日本", []byte{0x93, 0xFA, 0x96, 0x7B}},
+		{"ア", []byte{0x83, 0x41}},
+		{"ｱ", []byte{0xB1}},
+		{"黑", []byte{0xEE, 0xEC}}, // the NEC selected row is used before the IBM extension
+		{"ⅰ", []byte{0xEE, 0xEF}}, // not 0xFA40
+	} {
+		if got, err := encodeShiftJIS(test.text); err != nil || !bytes.Equal(got, test.want) {
+			t.Errorf("%q: % X %v, want % X", test.text, got, err, test.want)
+		}
+	}
+}
+
+func TestGuessUTF16Order(t *testing.T) {
+	text := []byte{0, 'H', 0, 'i', 0, '!'}
+	for _, test := range []struct {
+		data  []byte
+		order binary.ByteOrder
+		want  binary.ByteOrder
+	}{
+		{text, binary.BigEndian, binary.BigEndian},
+		{text, binary.LittleEndian, binary.BigEndian},
+		{[]byte{'H', 0, 'i', 0, '!', 0}, binary.BigEndian, binary.LittleEndian},
+		{[]byte{0x65, 0xE5, 0x67, 0x2C}, binary.LittleEndian, binary.LittleEndian}, // no ASCII either way
+		{[]byte{0, 'H', 'i', 0}, binary.LittleEndian, binary.LittleEndian},         // not clearly more
+	} {
+		if got := guessUTF16Order(test.data, test.order); got != test.want {
+			t.Errorf("% X %v: %v, want %v", test.data, test.order, got, test.want)
+		}
+	}
+	// a UNICODE value written in the wrong byte order
+	data := append(append([]byte{}, aCharacterCodes[CharacterCodeUnicode]...), 'H', 0, 'i', 0)
+	if text, _, err := DecodeCharacterCode(data, binary.BigEndian); err != nil || text != "Hi" {
+		t.Errorf("swapped UNICODE: %q %v", text, err)
+	}
+}
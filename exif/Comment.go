@@ -0,0 +1,153 @@
+package ImgMeta
+
+import (
+	"encoding/binary"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+/*
+Structure of a COM segment
+
+The COM segment holds a free-form comment, there can be any number of them anywhere before the start of scan.
+
+    [Record name]    [size]   [description]
+    ---------------------------------------
+    Comment            ...    the comment text, no identifier
+
+The JPEG standard does not say anything about the character set of a comment. Most tools write ASCII or UTF-8,
+older Windows and Mac tools write ISO-8859-1 (or a code page close to it) and some write UTF-16 with a byte order
+mark. Comments are often NUL terminated.
+
+*/
+
+// cMaxCommentLength is the maximum size of a comment that fits in one COM segment
+const cMaxCommentLength = 0xFFFF - 2
+
+type tCommentAPP struct {
+	offset uint64           // Offset of this APP in the file
+	endian binary.ByteOrder // Byte-Order
+	block  []byte           // full APP block
+}
+
+func newCommentAPP(comment string) *tCommentAPP {
+	block := make([]byte, 4, 4+len(comment))
+	binary.BigEndian.PutUint16(block, cCOMMENT)
+	binary.BigEndian.PutUint16(block[2:], uint16(2+len(comment)))
+	block = append(block, comment...)
+	return &tCommentAPP{offset: 0, endian: binary.BigEndian, block: block}
+}
+
+func (t tCommentAPP) Name() string {
+	return "Comment"
+}
+func (t tCommentAPP) Marker() uint16 {
+	return t.endian.Uint16(t.block)
+}
+func (t tCommentAPP) Length() uint16 {
+	return t.endian.Uint16(t.block[2:])
+}
+func (t tCommentAPP) ID(cid []byte) []byte {
+	return []byte{}
+}
+func (t tCommentAPP) HasID(cid []byte) bool {
+	return true
+}
+
+func (t tCommentAPP) ReadValue(tagID2Find uint16) (interface{}, error) {
+	return t.Comment(), nil
+}
+
+// Comment returns the comment converted to UTF-8
+func (t tCommentAPP) Comment() string {
+	return decodeText(t.block[4:])
+}
+
+// decodeText converts text of an unknown character set to UTF-8. UTF-16 is recognized by its
+// byte order mark, valid UTF-8 (and thus ASCII) is kept as-is and anything else is taken to be
+// ISO-8859-1. Trailing NUL characters are removed.
+func decodeText(data []byte) string {
+	if len(data) >= 2 && ((data[0] == 0xFE && data[1] == 0xFF) || (data[0] == 0xFF && data[1] == 0xFE)) {
+		var endian binary.ByteOrder = binary.BigEndian
+		if data[0] == 0xFF {
+			endian = binary.LittleEndian
+		}
+		units := make([]uint16, 0, len(data)/2)
+		for i := 2; i+1 < len(data); i += 2 {
+			units = append(units, endian.Uint16(data[i:]))
+		}
+		for len(units) > 0 && units[len(units)-1] == 0 {
+			units = units[:len(units)-1]
+		}
+		return string(utf16.Decode(units))
+	}
+
+	for len(data) > 0 && data[len(data)-1] == 0 {
+		data = data[:len(data)-1]
+	}
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// Comments returns the text of all COM segments, in file order
+func (i Image) Comments() (comments []string) {
+	for _, segment := range i.segments {
+		if comment, ok := segment.app.(*tCommentAPP); ok {
+			comments = append(comments, comment.Comment())
+		}
+	}
+	return comments
+}
+
+// AddComment adds a COM segment (UTF-8) after the existing comments, or after the last APPn
+// segment if there are none. The segment is written by WriteJpeg.
+func (i *Image) AddComment(comment string) error {
+	if len(comment) > cMaxCommentLength {
+		return &exifError{"Comment does not fit in a COM segment"}
+	}
+	at := 0
+	for n, segment := range i.segments {
+		marker := segment.app.Marker()
+		if marker == cCOMMENT || (marker >= cAPP0 && marker <= cAPP15) {
+			at = n + 1
+		}
+	}
+	app := newCommentAPP(comment)
+	segments := append([]tSegment{}, i.segments[:at]...)
+	segments = append(segments, tSegment{app: app, block: app.block})
+	i.segments = append(segments, i.segments[at:]...)
+	i.apps[app.Name()] = app
+	return nil
+}
+
+// RemoveComments removes all COM segments
+func (i *Image) RemoveComments() {
+	segments := make([]tSegment, 0, len(i.segments))
+	for _, segment := range i.segments {
+		if segment.app.Marker() != cCOMMENT {
+			segments = append(segments, segment)
+		}
+	}
+	i.segments = segments
+	delete(i.apps, "Comment")
+}
+
+// SetComments replaces all COM segments by the given comments
+func (i *Image) SetComments(comments []string) error {
+	for _, comment := range comments {
+		if len(comment) > cMaxCommentLength {
+			return &exifError{"Comment does not fit in a COM segment"}
+		}
+	}
+	i.RemoveComments()
+	for _, comment := range comments {
+		i.AddComment(comment)
+	}
+	return nil
+}
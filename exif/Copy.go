@@ -0,0 +1,273 @@
+package ImgMeta
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+)
+
+/*
+Copying metadata
+
+CopyMetadata transplants the EXIF, XMP, IPTC and ICC segments of one image into another, e.g. into a resized
+rendition that was encoded with image/jpeg. The copied metadata is made to describe the new image:
+
+    EXIF    PixelXDimension, PixelYDimension (and ImageWidth/ImageHeight when present) are set to the frame size
+    XMP     exif:PixelXDimension, exif:PixelYDimension, tiff:ImageWidth, tiff:ImageLength when present
+    EXIF    the thumbnail, when the source has one, is regenerated from the new image (160x120 at most)
+
+When the pixels of the new image already have the orientation applied (CopyFilter.Oriented) the EXIF Orientation
+and XMP tiff:Orientation are reset to 1 (top-left).
+
+The Strip options of the filter are applied to the copy, metadata that is stripped entirely (e.g. Strip.ICC) is
+not copied at all. For example, to publish renditions without location:
+
+    filter := CopyFilter{EXIF: true, XMP: true, IPTC: true, ICC: true}
+    filter.Strip, _ = StripPreset(StripWebPublish)
+
+*/
+
+// CopyFilter selects the metadata that CopyMetadata copies
+type CopyFilter struct {
+	EXIF     bool
+	XMP      bool
+	IPTC     bool
+	ICC      bool
+	Oriented bool                       // the pixels of the destination are already rotated upright
+	ExifTag  func(ifd, tag uint16) bool // when set, only the EXIF tags for which it returns true are copied
+	Strip    StripOptions               // metadata that is removed from the copy
+}
+
+const (
+	cThumbnailWidth   = 160
+	cThumbnailHeight  = 120
+	cThumbnailQuality = 80
+)
+
+// CopyMetadata returns a writer that writes dst with the metadata of src, the segments of dst that are not
+// copied over are kept.
+// Examples:
+//
+//	writer, err := CopyMetadata(original, rendition, CopyFilter{EXIF: true, XMP: true, IPTC: true, ICC: true})
+//	...
+//	err = writer.Write(out)
+func CopyMetadata(src Image, dst Image, filter CopyFilter) (*JpegWriter, error) {
+	width, height, err := dst.frameSize()
+	if err != nil {
+		return nil, err
+	}
+	m := NewMetadata(src)
+	if err := m.Strip(filter.Strip); err != nil {
+		return nil, err
+	}
+	w := NewJpegWriter(dst)
+
+	if _, exists := src.apps["EXIF"]; filter.EXIF && exists && (m.exif != nil || !filter.Strip.EXIF) {
+		exif, err := m.Exif()
+		if err != nil {
+			return nil, err
+		}
+		if err := copyExif(exif, dst, width, height, filter); err != nil {
+			return nil, err
+		}
+		block, _, err := exif.Encode()
+		if err != nil {
+			return nil, err
+		}
+		if err := w.Replace(block); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, exists := src.apps["XMP"]; filter.XMP && exists {
+		xmp, err := m.XMP()
+		if err != nil {
+			return nil, err
+		}
+		copyXMP(xmp, width, height, filter)
+		if len(xmp.properties) > 0 {
+			segment, extension, err := xmp.Encode()
+			if err != nil {
+				return nil, err
+			}
+			if err := w.Replace(segment); err != nil {
+				return nil, err
+			}
+			if err := w.ReplaceAll("XMPExtension", extension); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if _, exists := src.apps["IPTC"]; filter.IPTC && exists && (m.iptc != nil || !filter.Strip.IPTC) {
+		blocks := [][]byte{}
+		if m.iptc != nil {
+			if blocks, err = m.iptc.Encode(); err != nil {
+				return nil, err
+			}
+		} else {
+			for _, s := range src.segments {
+				if s.app.Name() == "IPTC" {
+					blocks = append(blocks, s.block)
+				}
+			}
+		}
+		if err := w.ReplaceAll("IPTC", blocks); err != nil {
+			return nil, err
+		}
+	}
+
+	if filter.ICC && !filter.Strip.ICC {
+		profile := [][]byte{}
+		for _, s := range src.segments {
+			if segmentRank(s.app) == 5 {
+				profile = append(profile, s.block)
+			}
+		}
+		if len(profile) > 0 {
+			w.removeIf(func(app APP, rank int) bool { return rank == 5 })
+			for _, block := range profile {
+				if err := w.Insert(block); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return w, nil
+}
+
+// copyExif filters the tags and makes the dimensions, orientation and thumbnail match the destination
+func copyExif(exif *ExifWriter, dst Image, width uint32, height uint32, filter CopyFilter) error {
+	if filter.ExifTag != nil {
+		for ifdType, tags := range exif.ifds {
+			for id := range tags {
+				if !filter.ExifTag(ifdType, id) {
+					delete(tags, id)
+				}
+			}
+		}
+		if exif.makerNote != nil && !filter.ExifTag(IFDExif, ExifTagMakerNote) {
+			exif.makerNote = nil
+		}
+	}
+	dimensions := []struct {
+		ifd   uint16
+		tag   uint16
+		value uint32
+	}{
+		{IFDExif, ExifTagPixelXDimension, width},
+		{IFDExif, ExifTagPixelYDimension, height},
+		{IFD0, ExifTagImageWidth, width},
+		{IFD0, ExifTagImageHeight, height},
+	}
+	for _, d := range dimensions {
+		_, exists := exif.ifds[d.ifd][d.tag]
+		if exists || (d.ifd == IFDExif && len(exif.ifds[IFDExif]) > 0) {
+			if err := exif.SetTag(d.ifd, d.tag, int(d.value)); err != nil {
+				return err
+			}
+		}
+	}
+	if filter.Oriented {
+		for _, ifdType := range []uint16{IFD0, IFD1} {
+			if _, exists := exif.ifds[ifdType][ExifTagOrientation]; exists {
+				if err := exif.SetTag(ifdType, ExifTagOrientation, 1); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if exif.thumbnail != nil {
+		thumbnail, err := dst.makeThumbnail()
+		if err != nil {
+			return err
+		}
+		exif.thumbnail = thumbnail
+	}
+	return nil
+}
+
+// copyXMP makes the dimensions and orientation in the XMP match the destination
+func copyXMP(xmp *XMPWriter, width uint32, height uint32, filter CopyFilter) {
+	properties := []struct {
+		namespace string
+		name      string
+		value     uint32
+	}{
+		{XmpNsEXIF, "PixelXDimension", width},
+		{XmpNsEXIF, "PixelYDimension", height},
+		{XmpNsTIFF, "ImageWidth", width},
+		{XmpNsTIFF, "ImageLength", height},
+	}
+	for _, p := range properties {
+		if _, exists := xmp.Property(p.namespace, p.name); exists {
+			xmp.Set(p.namespace, p.name, int(p.value))
+		}
+	}
+	if _, exists := xmp.Property(XmpNsTIFF, "Orientation"); exists && filter.Oriented {
+		xmp.Set(XmpNsTIFF, "Orientation", 1)
+	}
+}
+
+// makeThumbnail decodes the image and encodes a thumbnail of at most 160x120 (or 120x160) pixels
+func (i Image) makeThumbnail() ([]byte, error) {
+	decoded, err := jpeg.Decode(bytes.NewReader(i.data))
+	if err != nil {
+		return nil, err
+	}
+	bounds := decoded.Bounds()
+	maxWidth, maxHeight := cThumbnailWidth, cThumbnailHeight
+	if bounds.Dy() > bounds.Dx() {
+		maxWidth, maxHeight = maxHeight, maxWidth
+	}
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > maxWidth {
+		width, height = maxWidth, height*maxWidth/width
+	}
+	if height > maxHeight {
+		width, height = width*maxHeight/height, maxHeight
+	}
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, scaleImage(decoded, width, height), &jpeg.Options{Quality: cThumbnailQuality}); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// scaleImage scales an image down by averaging (a grid of at most 8x8 of) the pixels under each new pixel
+func scaleImage(src image.Image, width int, height int) *image.RGBA {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := bounds.Min.Y + (y+1)*bounds.Dy()/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		stepY := (y1 - y0 + 7) / 8
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := bounds.Min.X + (x+1)*bounds.Dx()/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			stepX := (x1 - x0 + 7) / 8
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy += stepY {
+				for sx := x0; sx < x1; sx += stepX {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a, n = r+cr, g+cg, b+cb, a+ca, n+1
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n >> 8), uint8(g / n >> 8), uint8(b / n >> 8), uint8(a / n >> 8)})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,42 @@
+package ImgMeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+func TestCopyMetadataStripICC(t *testing.T) {
+	icc := append([]byte{0xFF, 0xE2, 0, 0}, idAPP2...)
+	icc = append(icc, 1, 1, 'p', 'r', 'o', 'f', 'i', 'l', 'e')
+	binary.BigEndian.PutUint16(icc[2:], uint16(len(icc)-2))
+	src, err := readJpegData(writeTestJpeg(t, readTestImage(t), icc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rendition bytes.Buffer
+	if err := jpeg.Encode(&rendition, image.NewGray(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	dst, err := readJpegData(rendition.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, strip := range []bool{false, true} {
+		filter := CopyFilter{ICC: true, Strip: StripOptions{ICC: strip}}
+		w, err := CopyMetadata(src, dst, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		copied := false
+		for _, app := range w.Segments() {
+			copied = copied || segmentRank(app) == 5
+		}
+		if copied == strip {
+			t.Errorf("Strip.ICC %v: ICC profile copied %v", strip, copied)
+		}
+	}
+}
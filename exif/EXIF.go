@@ -1,6 +1,7 @@
 package ImgMeta
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"math"
@@ -114,10 +115,17 @@ type ifdOffsetItem struct {
 	ifdType uint16
 }
 
-func (t tEXIFAPP) ReadValue(tagID2Find uint16) (interface{}, error) {
-	fmt.Printf("Read value of tag:0x%X in APP:EXIF\n", tagID2Find)
+// cEXIFTIFFOffset is the offset of the TIFF header in the EXIF APP block (marker, length and "Exif\0\0")
+const cEXIFTIFFOffset = uint32(10)
+
+// IFDs walks the IFD tree and returns the IFDs keyed by type, the thumbnail IFD (IFD1) is keyed by cIFDONE
+func (t tEXIFAPP) IFDs() map[uint16]tExifIFD {
+	ifds := map[uint16]tExifIFD{}
+	if len(t.block) < int(cEXIFTIFFOffset)+8 {
+		return ifds
+	}
 
-	tiffOffset := uint32(10)
+	tiffOffset := cEXIFTIFFOffset
 	ifd0Offset := tiffOffset + t.TIFFOffsetToIFD0()
 	endian := t.TIFFByteOrder()
 
@@ -126,52 +134,91 @@ func (t tEXIFAPP) ReadValue(tagID2Find uint16) (interface{}, error) {
 
 	for len(ifdQueue) > 0 {
 		// Pop the next offset to process
-		ifdItem := ifdQueue[len(ifdQueue)-1]
-		ifdQueue = ifdQueue[:len(ifdQueue)-1]
+		ifdItem := ifdQueue[0]
+		ifdQueue = ifdQueue[1:]
+		if _, exists := ifds[ifdItem.ifdType]; exists {
+			continue
+		}
 
-		ifd := tExifIFD{offset: ifdItem.offset, appblock: t.block, endian: endian}
-		// How many fields does this IFD have ?
-		numberOfTags := ifd.NumberOfTags()
-		//fmt.Printf("Checking ifd:0x%X at offset:%d with %d fields\n", ifdItem.ifdType, ifdItem.offset, numberOfTags)
+		ifd := tExifIFD{offset: ifdItem.offset, appblock: t.block, endian: endian, base: tiffOffset}
+		ifds[ifdItem.ifdType] = ifd
 
+		numberOfTags := ifd.NumberOfTags()
 		for i := uint32(0); i < numberOfTags; i++ {
 			tag := ifd.GetTag(i)
 			tagID := tag.TagID()
 
-			//fmt.Printf("Checking tag:0x%X at index:%d\n", tagID, i)
-			if tagID == tagID2Find {
-				//fmt.Printf("Found tag:0x%X in APP:EXIF at index %d\n", tagID, i)
-				return ifd.ReadValue(tag)
-			}
-
-			// IFD0, reading the offsets to the other IFD segments
-			if ifdItem.ifdType == cIFDZERO && tagID == cIFDEXIF {
-				//fmt.Printf("Found ifd:0x%X in APP:EXIF at index %d\n", tagID, i)
-				anotherIfdOffset := tiffOffset + tag.valueOrOffset()
-				ifdQueue = append(ifdQueue, ifdOffsetItem{offset: anotherIfdOffset, ifdType: cIFDEXIF})
-			} else if ifdItem.ifdType == cIFDZERO && tagID == cIFDGPS {
-				//fmt.Printf("Found gps:0x%X in APP:EXIF at index %d\n", tagID, i)
-				anotherIfdOffset := tiffOffset + tag.valueOrOffset()
-				ifdQueue = append(ifdQueue, ifdOffsetItem{offset: anotherIfdOffset, ifdType: cIFDGPS})
+			// Reading the offsets to the other IFD segments
+			if ifdItem.ifdType == cIFDZERO && (tagID == cIFDEXIF || tagID == cIFDGPS) {
+				ifdQueue = append(ifdQueue, ifdOffsetItem{offset: tiffOffset + tag.valueOrOffset(), ifdType: tagID})
 			} else if ifdItem.ifdType == cIFDEXIF && tagID == cIFDINTEROP {
-				//fmt.Printf("Found iop:0x%X in APP:EXIF at index %d\n", tag, i)
-				anotherIfdOffset := tiffOffset + tag.valueOrOffset()
-				ifdQueue = append(ifdQueue, ifdOffsetItem{offset: anotherIfdOffset, ifdType: cIFDINTEROP})
+				ifdQueue = append(ifdQueue, ifdOffsetItem{offset: tiffOffset + tag.valueOrOffset(), ifdType: cIFDINTEROP})
+			}
+		}
+		if ifdItem.ifdType == cIFDZERO {
+			if next := ifd.NextIFD(); next != 0 {
+				ifdQueue = append(ifdQueue, ifdOffsetItem{offset: tiffOffset + next, ifdType: cIFDONE})
 			}
 		}
 	}
+	return ifds
+}
 
-	return int(1), nil
+func (t tEXIFAPP) ReadValue(tagID2Find uint16) (interface{}, error) {
+	//fmt.Printf("Read value of tag:0x%X in APP:EXIF\n", tagID2Find)
+
+	ifds := t.IFDs()
+	for _, ifdType := range []uint16{cIFDZERO, cIFDEXIF, cIFDGPS, cIFDINTEROP} {
+		ifd, exists := ifds[ifdType]
+		if !exists {
+			continue
+		}
+		if tag, found := ifd.FindTag(tagID2Find); found {
+			return ifd.ReadValue(tag)
+		}
+	}
+	return nil, &exifError{fmt.Sprintf("EXIF tag 0x%X not found", tagID2Find)}
+}
+
+// ReadIFDValue reads the value of a tag in a specific IFD (cIFDZERO, cIFDEXIF, cIFDGPS, cIFDINTEROP or cIFDONE),
+// tags of the GPS and Interoperability IFD share their numbers with other IFDs.
+func (t tEXIFAPP) ReadIFDValue(ifdType uint16, tagID2Find uint16) (interface{}, error) {
+	ifd, exists := t.IFDs()[ifdType]
+	if !exists {
+		return nil, &exifError{fmt.Sprintf("EXIF IFD 0x%X not found", ifdType)}
+	}
+	tag, found := ifd.FindTag(tagID2Find)
+	if !found {
+		return nil, &exifError{fmt.Sprintf("EXIF tag 0x%X not found", tagID2Find)}
+	}
+	return ifd.ReadValue(tag)
 }
 
 type tExifIFD struct {
 	offset   uint32           // IFD-Offset
 	endian   binary.ByteOrder // Endian
 	appblock []byte
+	base     uint32 // Offset of the TIFF header, value offsets are relative to it
 }
 
 func (ifd tExifIFD) NumberOfTags() uint32 {
-	return uint32(ifd.endian.Uint16(ifd.appblock[ifd.offset:]))
+	if uint64(ifd.offset)+2 > uint64(len(ifd.appblock)) {
+		return 0
+	}
+	n := uint32(ifd.endian.Uint16(ifd.appblock[ifd.offset:]))
+	if max := (uint32(len(ifd.appblock)) - ifd.offset - 2) / 12; n > max {
+		n = max
+	}
+	return n
+}
+
+// NextIFD returns the link to the next IFD, relative to the TIFF header
+func (ifd tExifIFD) NextIFD() uint32 {
+	o := uint64(ifd.offset) + 2 + uint64(ifd.NumberOfTags())*12
+	if o+4 > uint64(len(ifd.appblock)) {
+		return 0
+	}
+	return ifd.endian.Uint32(ifd.appblock[o:])
 }
 
 func (ifd tExifIFD) GetTag(index uint32) tExifTag {
@@ -211,20 +258,6 @@ func (tag tExifTag) countOrComponents() uint32 {
 func (tag tExifTag) valueOrOffset() uint32 {
 	return tag.endian.Uint32(tag.appblock[tag.offset+8:])
 }
-func (tag tExifTag) valueAsU8() uint8 {
-	return tag.appblock[tag.offset+8+3]
-}
-func (tag tExifTag) valueAsU16() uint16 {
-	return binary.LittleEndian.Uint16(tag.appblock[tag.offset+8+2:])
-}
-func (tag tExifTag) valueAsU32() uint32 {
-	return binary.LittleEndian.Uint32(tag.appblock[tag.offset+8:])
-}
-func (tag tExifTag) valueAsFloat32() float32 {
-	bits := binary.LittleEndian.Uint32(tag.appblock[tag.offset+8:])
-	float := math.Float32frombits(bits)
-	return float
-}
 
 type tExifTagFieldType uint16
 
@@ -248,105 +281,133 @@ const (
 	cSRATIONAL = 0x000A
 	cFLOAT32   = 0x000B
 	cFLOAT64   = 0x000C
+	cIFD       = 0x000D // offset of a sub-IFD, not read as a value
+	cIFD8      = 0x0012 // 64-bit offset of a sub-IFD (BigTIFF), not read as a value
+
+	cSHORTLONG = 0x0200 // not a TIFF type, tags that can be either SHORT or LONG
 )
 
-func (ifd tExifIFD) readValueFromOffset(offset uint32, typeID uint16, count uint32) (interface{}, error) {
+// valueData returns the bytes holding the value of a tag, values of up to 4 bytes are stored in the tag
+// itself, larger values are stored at an offset relative to the TIFF header.
+func (ifd tExifIFD) valueData(tag tExifTag) ([]byte, error) {
+	fieldType := tag.TypeID() &^ cARRAY
+	if fieldType == 0 || int(fieldType) >= len(aExifTagFieldSize) {
+		return nil, &exifError{fmt.Sprintf("EXIF tag 0x%X has unknown type %d", tag.TagID(), fieldType)}
+	}
+	size := uint64(getExifTagFieldSize(tExifTagFieldType(fieldType))) * uint64(tag.countOrComponents())
+	if size <= 4 {
+		return tag.appblock[tag.offset+8 : tag.offset+8+uint32(size)], nil
+	}
+	offset := uint64(ifd.base) + uint64(tag.valueOrOffset())
+	if offset+size > uint64(len(ifd.appblock)) {
+		return nil, &exifError{fmt.Sprintf("EXIF tag 0x%X value is out of bounds", tag.TagID())}
+	}
+	return ifd.appblock[offset : offset+size], nil
+}
+
+func (ifd tExifIFD) ReadValue(tag tExifTag) (interface{}, error) {
+	data, err := ifd.valueData(tag)
+	if err != nil {
+		return int(0), err
+	}
+	return decodeExifValue(ifd.endian, tag.TypeID(), tag.countOrComponents(), data)
+}
+
+// decodeExifValue decodes the value of a tag, single values are returned as their Go type (rationals as
+// float64), multiple values as a slice, ASCII as a string and UNDEFINED as a []byte.
+func decodeExifValue(endian binary.ByteOrder, typeID uint16, count uint32, data []byte) (interface{}, error) {
+	fieldType := typeID &^ cARRAY
+	if fieldType == cASCII {
+		if n := bytes.IndexByte(data, 0); n >= 0 {
+			data = data[:n]
+		}
+		return string(data), nil
+	}
+	if fieldType == cUNDEFINED {
+		return append([]byte{}, data...), nil
+	}
+	size := uint32(getExifTagFieldSize(tExifTagFieldType(fieldType)))
+	if count == 0 || uint32(len(data)) < count*size {
+		return int(0), &exifError{"Reading EXIF tag value failed"}
+	}
+
 	switch typeID {
+	case cUBYTE:
+		return data[0], nil
+	case cUSHORT:
+		return endian.Uint16(data), nil
+	case cULONG:
+		return endian.Uint32(data), nil
+	case cSBYTE:
+		return int8(data[0]), nil
+	case cSSHORT:
+		return int16(endian.Uint16(data)), nil
+	case cSLONG:
+		return int32(endian.Uint32(data)), nil
+	case cURATIONAL:
+		return float64(endian.Uint32(data)) / float64(endian.Uint32(data[4:])), nil
+	case cSRATIONAL:
+		return float64(int32(endian.Uint32(data))) / float64(int32(endian.Uint32(data[4:]))), nil
+	case cFLOAT32:
+		return math.Float32frombits(endian.Uint32(data)), nil
+	case cFLOAT64:
+		return math.Float64frombits(endian.Uint64(data)), nil
 	case cARRAY | cUBYTE:
-		offset = ifd.offset + offset
-		array := append([]uint8{}, ifd.appblock[offset:offset+count]...)
-		return array, nil
+		return append([]uint8{}, data[:count]...), nil
 	case cARRAY | cUSHORT:
-		offset = ifd.offset + offset
-		block := ifd.appblock[offset : offset+count*2]
-		array := make([]uint16, count, count)
+		array := make([]uint16, count)
 		for i := uint32(0); i < count; i++ {
-			array[i] = ifd.endian.Uint16(block[i*2:])
+			array[i] = endian.Uint16(data[i*2:])
 		}
 		return array, nil
 	case cARRAY | cULONG:
-		offset = ifd.offset + offset
-		block := ifd.appblock[offset : offset+count*4]
-		array := make([]uint32, count, count)
+		array := make([]uint32, count)
 		for i := uint32(0); i < count; i++ {
-			array[i] = ifd.endian.Uint32(block[i*4:])
+			array[i] = endian.Uint32(data[i*4:])
 		}
 		return array, nil
 	case cARRAY | cSBYTE:
-		offset = ifd.offset + offset
-		block := ifd.appblock[offset : offset+count]
-		array := make([]int8, count, count)
+		array := make([]int8, count)
 		for i := uint32(0); i < count; i++ {
-			array[i] = int8(block[i])
+			array[i] = int8(data[i])
 		}
 		return array, nil
 	case cARRAY | cSSHORT:
-		offset = ifd.offset + offset
-		block := ifd.appblock[offset : offset+count*2]
-		array := make([]int16, count, count)
+		array := make([]int16, count)
 		for i := uint32(0); i < count; i++ {
-			array[i] = int16(ifd.endian.Uint16(block[i*2:]))
+			array[i] = int16(endian.Uint16(data[i*2:]))
 		}
 		return array, nil
 	case cARRAY | cSLONG:
-		offset = ifd.offset + offset
-		block := ifd.appblock[offset : offset+count*4]
-		array := make([]int32, count, count)
+		array := make([]int32, count)
 		for i := uint32(0); i < count; i++ {
-			array[i] = int32(ifd.endian.Uint32(block[i*4:]))
+			array[i] = int32(endian.Uint32(data[i*4:]))
+		}
+		return array, nil
+	case cARRAY | cURATIONAL:
+		array := make([]float64, count)
+		for i := uint32(0); i < count; i++ {
+			array[i] = float64(endian.Uint32(data[i*8:])) / float64(endian.Uint32(data[i*8+4:]))
+		}
+		return array, nil
+	case cARRAY | cSRATIONAL:
+		array := make([]float64, count)
+		for i := uint32(0); i < count; i++ {
+			array[i] = float64(int32(endian.Uint32(data[i*8:]))) / float64(int32(endian.Uint32(data[i*8+4:])))
+		}
+		return array, nil
+	case cARRAY | cFLOAT32:
+		array := make([]float32, count)
+		for i := uint32(0); i < count; i++ {
+			array[i] = math.Float32frombits(endian.Uint32(data[i*4:]))
+		}
+		return array, nil
+	case cARRAY | cFLOAT64:
+		array := make([]float64, count)
+		for i := uint32(0); i < count; i++ {
+			array[i] = math.Float64frombits(endian.Uint64(data[i*8:]))
 		}
 		return array, nil
-	case cFLOAT64:
-		bits := ifd.endian.Uint64(ifd.appblock[ifd.offset+offset:])
-		float := math.Float64frombits(bits)
-		return float, nil
-	case cURATIONAL:
-		numerator := ifd.endian.Uint32(ifd.appblock[ifd.offset+offset:])
-		denominator := ifd.endian.Uint32(ifd.appblock[ifd.offset+offset+4:])
-		return float64(numerator) / float64(denominator), nil
-	case cSRATIONAL:
-		numerator := int32(ifd.endian.Uint32(ifd.appblock[ifd.offset+offset:]))
-		denominator := int32(ifd.endian.Uint32(ifd.appblock[ifd.offset+offset+4:]))
-		return float64(numerator) / float64(denominator), nil
-	}
-	return int(0), &exifError{"Reading EXIF tag value from offset failed"}
-}
-
-func (ifd tExifIFD) ReadValue(tag tExifTag) (interface{}, error) {
-
-	switch tag.TypeID() {
-	case cUBYTE:
-		return uint8(tag.valueOrOffset()), nil
-	case cUSHORT:
-		return uint16(tag.valueOrOffset()), nil
-	case cULONG:
-		return uint32(tag.valueOrOffset()), nil
-	case cSBYTE:
-		return int8(tag.valueOrOffset()), nil
-	case cSSHORT:
-		return int16(tag.valueOrOffset()), nil
-	case cSLONG:
-		return int32(tag.valueOrOffset()), nil
-	case cFLOAT32:
-		return tag.valueAsFloat32(), nil
-	case cFLOAT64:
-		return ifd.readValueFromOffset(tag.valueOrOffset(), tag.TypeID(), tag.countOrComponents())
-	case cURATIONAL:
-		return ifd.readValueFromOffset(tag.valueOrOffset(), tag.TypeID(), tag.countOrComponents())
-	case cSRATIONAL:
-		return ifd.readValueFromOffset(tag.valueOrOffset(), tag.TypeID(), tag.countOrComponents())
-	case cARRAY | cUBYTE:
-		return ifd.readValueFromOffset(tag.valueOrOffset(), tag.TypeID(), tag.countOrComponents())
-	case cARRAY | cUSHORT:
-		return ifd.readValueFromOffset(tag.valueOrOffset(), tag.TypeID(), tag.countOrComponents())
-	case cARRAY | cULONG:
-		return ifd.readValueFromOffset(tag.valueOrOffset(), tag.TypeID(), tag.countOrComponents())
-	case cARRAY | cSBYTE:
-		return ifd.readValueFromOffset(tag.valueOrOffset(), tag.TypeID(), tag.countOrComponents())
-	case cARRAY | cSSHORT:
-		return ifd.readValueFromOffset(tag.valueOrOffset(), tag.TypeID(), tag.countOrComponents())
-	case cARRAY | cSLONG:
-		return ifd.readValueFromOffset(tag.valueOrOffset(), tag.TypeID(), tag.countOrComponents())
 	}
 	return int(0), &exifError{"Reading EXIF tag value failed"}
 }
@@ -398,6 +459,9 @@ const (
 	ExifTagExifVersion               uint16 = 0x9000
 	ExifTagDateTimeOriginal          uint16 = 0x9003
 	ExifTagDateTimeDigitized         uint16 = 0x9004
+	ExifTagOffsetTime                uint16 = 0x9010
+	ExifTagOffsetTimeOriginal        uint16 = 0x9011
+	ExifTagOffsetTimeDigitized       uint16 = 0x9012
 	ExifTagComponentsConfiguration   uint16 = 0x9101
 	ExifTagCompressedBitsPerPixel    uint16 = 0x9102
 	ExifTagShutterSpeedValue         uint16 = 0x9201
@@ -451,6 +515,7 @@ const (
 	ExifTagLensMake                  uint16 = 0xA433
 	ExifTagLensModel                 uint16 = 0xA434
 	ExifTagLensSerialNumber          uint16 = 0xA435
+	ExifTagOffsetSchema              uint16 = 0xEA1D // Microsoft, offset a moved MakerNote has to be corrected with
 
 	ExifGpsTagGPSVersionID         uint16 = 0x0
 	ExifGpsTagGPSLatitudeRef       uint16 = 0x1
@@ -493,154 +558,160 @@ const (
 )
 
 type tExifTagDescr struct {
-	tag  uint16
-	id   uint16
-	name string
+	tag       uint16 // IFD the tag belongs in
+	id        uint16
+	name      string
+	fieldType uint16 // type of the value, cSHORTLONG when both SHORT and LONG are allowed
+	count     uint32 // number of values, 0 when the number is not fixed
 }
 
 var aExifTagDescr = map[uint16]tExifTagDescr{
 	// Primary tags
-	ExifTagImageWidth:                  {tag: cIFDZERO, name: "ImageWidth", id: ExifTagImageWidth},
-	ExifTagImageHeight:                 {tag: cIFDZERO, name: "ImageLength", id: ExifTagImageHeight},
-	ExifTagBitsPerSample:               {tag: cIFDZERO, name: "BitsPerSample", id: ExifTagBitsPerSample},
-	ExifTagCompression:                 {tag: cIFDZERO, name: "Compression", id: ExifTagCompression},
-	ExifTagPhotometricInterpretation:   {tag: cIFDZERO, name: "PhotometricInterpretation", id: ExifTagPhotometricInterpretation},
-	ExifTagImageDescription:            {tag: cIFDZERO, name: "ImageDescription", id: ExifTagImageDescription},
-	ExifTagMake:                        {tag: cIFDZERO, name: "Make", id: ExifTagMake},
-	ExifTagModel:                       {tag: cIFDZERO, name: "Model", id: ExifTagModel},
-	ExifTagStripOffsets:                {tag: cIFDZERO, name: "StripOffsets", id: ExifTagStripOffsets},
-	ExifTagOrientation:                 {tag: cIFDZERO, name: "Orientation", id: ExifTagOrientation},
-	ExifTagSamplesPerPixel:             {tag: cIFDZERO, name: "SamplesPerPixel", id: ExifTagSamplesPerPixel},
-	ExifTagRowsPerStrip:                {tag: cIFDZERO, name: "RowsPerStrip", id: ExifTagRowsPerStrip},
-	ExifTagStripByteCounts:             {tag: cIFDZERO, name: "StripByteCounts", id: ExifTagStripByteCounts},
-	ExifTagXResolution:                 {tag: cIFDZERO, name: "XResolution", id: ExifTagXResolution},
-	ExifTagYResolution:                 {tag: cIFDZERO, name: "YResolution", id: ExifTagYResolution},
-	ExifTagPlanarConfiguration:         {tag: cIFDZERO, name: "PlanarConfiguration", id: ExifTagPlanarConfiguration},
-	ExifTagResolutionUnit:              {tag: cIFDZERO, name: "ResolutionUnit", id: ExifTagResolutionUnit},
-	ExifTagTransferFunction:            {tag: cIFDZERO, name: "TransferFunction", id: ExifTagTransferFunction},
-	ExifTagSoftware:                    {tag: cIFDZERO, name: "Software", id: ExifTagSoftware},
-	ExifTagDateTime:                    {tag: cIFDZERO, name: "DateTime", id: ExifTagDateTime},
-	ExifTagArtist:                      {tag: cIFDZERO, name: "Artist", id: ExifTagArtist},
-	ExifTagWhitePoint:                  {tag: cIFDZERO, name: "WhitePoint", id: ExifTagWhitePoint},
-	ExifTagPrimaryChromaticities:       {tag: cIFDZERO, name: "PrimaryChromaticities", id: ExifTagPrimaryChromaticities},
-	ExifTagJPEGInterchangeFormat:       {tag: cIFDZERO, name: "JPEGInterchangeFormat", id: ExifTagJPEGInterchangeFormat},
-	ExifTagJPEGInterchangeFormatLength: {tag: cIFDZERO, name: "JPEGInterchangeFormatLength", id: ExifTagJPEGInterchangeFormatLength},
-	ExifTagYCbCrCoefficients:           {tag: cIFDZERO, name: "YCbCrCoefficients", id: ExifTagYCbCrCoefficients},
-	ExifTagYCbCrSubSampling:            {tag: cIFDZERO, name: "YCbCrSubSampling", id: ExifTagYCbCrSubSampling},
-	ExifTagYCbCrPositioning:            {tag: cIFDZERO, name: "YCbCrPositioning", id: ExifTagYCbCrPositioning},
-	ExifTagReferenceBlackWhite:         {tag: cIFDZERO, name: "ReferenceBlackWhite", id: ExifTagReferenceBlackWhite},
-	ExifTagCopyright:                   {tag: cIFDZERO, name: "Copyright", id: ExifTagCopyright},
+	ExifTagImageWidth:                  {tag: cIFDZERO, name: "ImageWidth", id: ExifTagImageWidth, fieldType: cSHORTLONG, count: 1},
+	ExifTagImageHeight:                 {tag: cIFDZERO, name: "ImageLength", id: ExifTagImageHeight, fieldType: cSHORTLONG, count: 1},
+	ExifTagBitsPerSample:               {tag: cIFDZERO, name: "BitsPerSample", id: ExifTagBitsPerSample, fieldType: cUSHORT, count: 3},
+	ExifTagCompression:                 {tag: cIFDZERO, name: "Compression", id: ExifTagCompression, fieldType: cUSHORT, count: 1},
+	ExifTagPhotometricInterpretation:   {tag: cIFDZERO, name: "PhotometricInterpretation", id: ExifTagPhotometricInterpretation, fieldType: cUSHORT, count: 1},
+	ExifTagImageDescription:            {tag: cIFDZERO, name: "ImageDescription", id: ExifTagImageDescription, fieldType: cASCII, count: 0},
+	ExifTagMake:                        {tag: cIFDZERO, name: "Make", id: ExifTagMake, fieldType: cASCII, count: 0},
+	ExifTagModel:                       {tag: cIFDZERO, name: "Model", id: ExifTagModel, fieldType: cASCII, count: 0},
+	ExifTagStripOffsets:                {tag: cIFDZERO, name: "StripOffsets", id: ExifTagStripOffsets, fieldType: cSHORTLONG, count: 0},
+	ExifTagOrientation:                 {tag: cIFDZERO, name: "Orientation", id: ExifTagOrientation, fieldType: cUSHORT, count: 1},
+	ExifTagSamplesPerPixel:             {tag: cIFDZERO, name: "SamplesPerPixel", id: ExifTagSamplesPerPixel, fieldType: cUSHORT, count: 1},
+	ExifTagRowsPerStrip:                {tag: cIFDZERO, name: "RowsPerStrip", id: ExifTagRowsPerStrip, fieldType: cSHORTLONG, count: 1},
+	ExifTagStripByteCounts:             {tag: cIFDZERO, name: "StripByteCounts", id: ExifTagStripByteCounts, fieldType: cSHORTLONG, count: 0},
+	ExifTagXResolution:                 {tag: cIFDZERO, name: "XResolution", id: ExifTagXResolution, fieldType: cURATIONAL, count: 1},
+	ExifTagYResolution:                 {tag: cIFDZERO, name: "YResolution", id: ExifTagYResolution, fieldType: cURATIONAL, count: 1},
+	ExifTagPlanarConfiguration:         {tag: cIFDZERO, name: "PlanarConfiguration", id: ExifTagPlanarConfiguration, fieldType: cUSHORT, count: 1},
+	ExifTagResolutionUnit:              {tag: cIFDZERO, name: "ResolutionUnit", id: ExifTagResolutionUnit, fieldType: cUSHORT, count: 1},
+	ExifTagTransferFunction:            {tag: cIFDZERO, name: "TransferFunction", id: ExifTagTransferFunction, fieldType: cUSHORT, count: 768},
+	ExifTagSoftware:                    {tag: cIFDZERO, name: "Software", id: ExifTagSoftware, fieldType: cASCII, count: 0},
+	ExifTagDateTime:                    {tag: cIFDZERO, name: "DateTime", id: ExifTagDateTime, fieldType: cASCII, count: 20},
+	ExifTagArtist:                      {tag: cIFDZERO, name: "Artist", id: ExifTagArtist, fieldType: cASCII, count: 0},
+	ExifTagWhitePoint:                  {tag: cIFDZERO, name: "WhitePoint", id: ExifTagWhitePoint, fieldType: cURATIONAL, count: 2},
+	ExifTagPrimaryChromaticities:       {tag: cIFDZERO, name: "PrimaryChromaticities", id: ExifTagPrimaryChromaticities, fieldType: cURATIONAL, count: 6},
+	ExifTagJPEGInterchangeFormat:       {tag: cIFDZERO, name: "JPEGInterchangeFormat", id: ExifTagJPEGInterchangeFormat, fieldType: cULONG, count: 1},
+	ExifTagJPEGInterchangeFormatLength: {tag: cIFDZERO, name: "JPEGInterchangeFormatLength", id: ExifTagJPEGInterchangeFormatLength, fieldType: cULONG, count: 1},
+	ExifTagYCbCrCoefficients:           {tag: cIFDZERO, name: "YCbCrCoefficients", id: ExifTagYCbCrCoefficients, fieldType: cURATIONAL, count: 3},
+	ExifTagYCbCrSubSampling:            {tag: cIFDZERO, name: "YCbCrSubSampling", id: ExifTagYCbCrSubSampling, fieldType: cUSHORT, count: 2},
+	ExifTagYCbCrPositioning:            {tag: cIFDZERO, name: "YCbCrPositioning", id: ExifTagYCbCrPositioning, fieldType: cUSHORT, count: 1},
+	ExifTagReferenceBlackWhite:         {tag: cIFDZERO, name: "ReferenceBlackWhite", id: ExifTagReferenceBlackWhite, fieldType: cURATIONAL, count: 6},
+	ExifTagCopyright:                   {tag: cIFDZERO, name: "Copyright", id: ExifTagCopyright, fieldType: cASCII, count: 0},
 
 	// EXIF tags
-	ExifTagExposureTime:              {tag: cIFDEXIF, name: "ExposureTime", id: ExifTagExposureTime},
-	ExifTagFNumber:                   {tag: cIFDEXIF, name: "FNumber", id: ExifTagFNumber},
-	ExifTagExposureProgram:           {tag: cIFDEXIF, name: "ExposureProgram", id: ExifTagExposureProgram},
-	ExifTagSpectralSensitivity:       {tag: cIFDEXIF, name: "SpectralSensitivity", id: ExifTagSpectralSensitivity},
-	ExifTagPhotographicSensitivity:   {tag: cIFDEXIF, name: "PhotographicSensitivity", id: ExifTagPhotographicSensitivity},
-	ExifTagOECF:                      {tag: cIFDEXIF, name: "OECF", id: ExifTagOECF},
-	ExifTagSensitivityType:           {tag: cIFDEXIF, name: "SensitivityType", id: ExifTagSensitivityType},
-	ExifTagStandardOutputSensitivity: {tag: cIFDEXIF, name: "StandardOutputSensitivity", id: ExifTagStandardOutputSensitivity},
-	ExifTagRecommendedExposureIndex:  {tag: cIFDEXIF, name: "RecommendedExposureIndex", id: ExifTagRecommendedExposureIndex},
-	ExifTagISOSpeed:                  {tag: cIFDEXIF, name: "ISOSpeed", id: ExifTagISOSpeed},
-	ExifTagISOSpeedLatitudeyyy:       {tag: cIFDEXIF, name: "ISOSpeedLatitudeyyy", id: ExifTagISOSpeedLatitudeyyy},
-	ExifTagISOSpeedLatitudezzz:       {tag: cIFDEXIF, name: "ISOSpeedLatitudezzz", id: ExifTagISOSpeedLatitudezzz},
-	ExifTagExifVersion:               {tag: cIFDEXIF, name: "ExifVersion", id: ExifTagExifVersion},
-	ExifTagDateTimeOriginal:          {tag: cIFDEXIF, name: "DateTimeOriginal", id: ExifTagDateTimeOriginal},
-	ExifTagDateTimeDigitized:         {tag: cIFDEXIF, name: "DateTimeDigitized", id: ExifTagDateTimeDigitized},
-	ExifTagComponentsConfiguration:   {tag: cIFDEXIF, name: "ComponentsConfiguration", id: ExifTagComponentsConfiguration},
-	ExifTagCompressedBitsPerPixel:    {tag: cIFDEXIF, name: "CompressedBitsPerPixel", id: ExifTagCompressedBitsPerPixel},
-	ExifTagShutterSpeedValue:         {tag: cIFDEXIF, name: "ShutterSpeedValue", id: ExifTagShutterSpeedValue},
-	ExifTagApertureValue:             {tag: cIFDEXIF, name: "ApertureValue", id: ExifTagApertureValue},
-	ExifTagBrightnessValue:           {tag: cIFDEXIF, name: "BrightnessValue", id: ExifTagBrightnessValue},
-	ExifTagExposureBiasValue:         {tag: cIFDEXIF, name: "ExposureBiasValue", id: ExifTagExposureBiasValue},
-	ExifTagMaxApertureValue:          {tag: cIFDEXIF, name: "MaxApertureValue", id: ExifTagMaxApertureValue},
-	ExifTagSubjectDistance:           {tag: cIFDEXIF, name: "SubjectDistance", id: ExifTagSubjectDistance},
-	ExifTagMeteringMode:              {tag: cIFDEXIF, name: "MeteringMode", id: ExifTagMeteringMode},
-	ExifTagLightSource:               {tag: cIFDEXIF, name: "LightSource", id: ExifTagLightSource},
-	ExifTagFlash:                     {tag: cIFDEXIF, name: "Flash", id: ExifTagFlash},
-	ExifTagFocalLength:               {tag: cIFDEXIF, name: "FocalLength", id: ExifTagFocalLength},
-	ExifTagSubjectArea:               {tag: cIFDEXIF, name: "SubjectArea", id: ExifTagSubjectArea},
-	ExifTagMakerNote:                 {tag: cIFDEXIF, name: "MakerNote", id: ExifTagMakerNote},
-	ExifTagUserComment:               {tag: cIFDEXIF, name: "UserComment", id: ExifTagUserComment},
-	ExifTagSubsecTime:                {tag: cIFDEXIF, name: "SubsecTime", id: ExifTagSubsecTime},
-	ExifTagSubsecTimeOriginal:        {tag: cIFDEXIF, name: "SubsecTimeOriginal", id: ExifTagSubsecTimeOriginal},
-	ExifTagSubsecTimeDigitized:       {tag: cIFDEXIF, name: "SubsecTimeDigitized", id: ExifTagSubsecTimeDigitized},
-	ExifTagFlashpixVersion:           {tag: cIFDEXIF, name: "FlashpixVersion", id: ExifTagFlashpixVersion},
-	ExifTagColorSpace:                {tag: cIFDEXIF, name: "ColorSpace", id: ExifTagColorSpace},
-	ExifTagPixelXDimension:           {tag: cIFDEXIF, name: "PixelXDimension", id: ExifTagPixelXDimension},
-	ExifTagPixelYDimension:           {tag: cIFDEXIF, name: "PixelYDimension", id: ExifTagPixelYDimension},
-	ExifTagRelatedSoundFile:          {tag: cIFDEXIF, name: "RelatedSoundFile", id: ExifTagRelatedSoundFile},
-	ExifTagFlashEnergy:               {tag: cIFDEXIF, name: "FlashEnergy", id: ExifTagFlashEnergy},
-	ExifTagSpatialFrequencyResponse:  {tag: cIFDEXIF, name: "SpatialFrequencyResponse", id: ExifTagSpatialFrequencyResponse},
-	ExifTagFocalPlaneXResolution:     {tag: cIFDEXIF, name: "FocalPlaneXResolution", id: ExifTagFocalPlaneXResolution},
-	ExifTagFocalPlaneYResolution:     {tag: cIFDEXIF, name: "FocalPlaneYResolution", id: ExifTagFocalPlaneYResolution},
-	ExifTagFocalPlaneResolutionUnit:  {tag: cIFDEXIF, name: "FocalPlaneResolutionUnit", id: ExifTagFocalPlaneResolutionUnit},
-	ExifTagSubjectLocation:           {tag: cIFDEXIF, name: "SubjectLocation", id: ExifTagSubjectLocation},
-	ExifTagExposureIndex:             {tag: cIFDEXIF, name: "ExposureIndex", id: ExifTagExposureIndex},
-	ExifTagSensingMethod:             {tag: cIFDEXIF, name: "SensingMethod", id: ExifTagSensingMethod},
-	ExifTagFileSource:                {tag: cIFDEXIF, name: "FileSource", id: ExifTagFileSource},
-	ExifTagSceneType:                 {tag: cIFDEXIF, name: "SceneType", id: ExifTagSceneType},
-	ExifTagCFAPattern:                {tag: cIFDEXIF, name: "CFAPattern", id: ExifTagCFAPattern},
-	ExifTagCustomRendered:            {tag: cIFDEXIF, name: "CustomRendered", id: ExifTagCustomRendered},
-	ExifTagExposureMode:              {tag: cIFDEXIF, name: "ExposureMode", id: ExifTagExposureMode},
-	ExifTagWhiteBalance:              {tag: cIFDEXIF, name: "WhiteBalance", id: ExifTagWhiteBalance},
-	ExifTagDigitalZoomRatio:          {tag: cIFDEXIF, name: "DigitalZoomRatio", id: ExifTagDigitalZoomRatio},
-	ExifTagFocalLengthIn35mmFilm:     {tag: cIFDEXIF, name: "FocalLengthIn35mmFilm", id: ExifTagFocalLengthIn35mmFilm},
-	ExifTagSceneCaptureType:          {tag: cIFDEXIF, name: "SceneCaptureType", id: ExifTagSceneCaptureType},
-	ExifTagGainControl:               {tag: cIFDEXIF, name: "GainControl", id: ExifTagGainControl},
-	ExifTagContrast:                  {tag: cIFDEXIF, name: "Contrast", id: ExifTagContrast},
-	ExifTagSaturation:                {tag: cIFDEXIF, name: "Saturation", id: ExifTagSaturation},
-	ExifTagSharpness:                 {tag: cIFDEXIF, name: "Sharpness", id: ExifTagSharpness},
-	ExifTagDeviceSettingDescription:  {tag: cIFDEXIF, name: "DeviceSettingDescription", id: ExifTagDeviceSettingDescription},
-	ExifTagSubjectDistanceRange:      {tag: cIFDEXIF, name: "SubjectDistanceRange", id: ExifTagSubjectDistanceRange},
-	ExifTagImageUniqueID:             {tag: cIFDEXIF, name: "ImageUniqueID", id: ExifTagImageUniqueID},
-	ExifTagCameraOwnerName:           {tag: cIFDEXIF, name: "CameraOwnerName", id: ExifTagCameraOwnerName},
-	ExifTagBodySerialNumber:          {tag: cIFDEXIF, name: "BodySerialNumber", id: ExifTagBodySerialNumber},
-	ExifTagLensSpecification:         {tag: cIFDEXIF, name: "LensSpecification", id: ExifTagLensSpecification},
-	ExifTagLensMake:                  {tag: cIFDEXIF, name: "LensMake", id: ExifTagLensMake},
-	ExifTagLensModel:                 {tag: cIFDEXIF, name: "LensModel", id: ExifTagLensModel},
-	ExifTagLensSerialNumber:          {tag: cIFDEXIF, name: "LensSerialNumber", id: ExifTagLensSerialNumber},
+	ExifTagExposureTime:              {tag: cIFDEXIF, name: "ExposureTime", id: ExifTagExposureTime, fieldType: cURATIONAL, count: 1},
+	ExifTagFNumber:                   {tag: cIFDEXIF, name: "FNumber", id: ExifTagFNumber, fieldType: cURATIONAL, count: 1},
+	ExifTagExposureProgram:           {tag: cIFDEXIF, name: "ExposureProgram", id: ExifTagExposureProgram, fieldType: cUSHORT, count: 1},
+	ExifTagSpectralSensitivity:       {tag: cIFDEXIF, name: "SpectralSensitivity", id: ExifTagSpectralSensitivity, fieldType: cASCII, count: 0},
+	ExifTagPhotographicSensitivity:   {tag: cIFDEXIF, name: "PhotographicSensitivity", id: ExifTagPhotographicSensitivity, fieldType: cUSHORT, count: 0},
+	ExifTagOECF:                      {tag: cIFDEXIF, name: "OECF", id: ExifTagOECF, fieldType: cUNDEFINED, count: 0},
+	ExifTagSensitivityType:           {tag: cIFDEXIF, name: "SensitivityType", id: ExifTagSensitivityType, fieldType: cUSHORT, count: 1},
+	ExifTagStandardOutputSensitivity: {tag: cIFDEXIF, name: "StandardOutputSensitivity", id: ExifTagStandardOutputSensitivity, fieldType: cULONG, count: 1},
+	ExifTagRecommendedExposureIndex:  {tag: cIFDEXIF, name: "RecommendedExposureIndex", id: ExifTagRecommendedExposureIndex, fieldType: cULONG, count: 1},
+	ExifTagISOSpeed:                  {tag: cIFDEXIF, name: "ISOSpeed", id: ExifTagISOSpeed, fieldType: cULONG, count: 1},
+	ExifTagISOSpeedLatitudeyyy:       {tag: cIFDEXIF, name: "ISOSpeedLatitudeyyy", id: ExifTagISOSpeedLatitudeyyy, fieldType: cULONG, count: 1},
+	ExifTagISOSpeedLatitudezzz:       {tag: cIFDEXIF, name: "ISOSpeedLatitudezzz", id: ExifTagISOSpeedLatitudezzz, fieldType: cULONG, count: 1},
+	ExifTagExifVersion:               {tag: cIFDEXIF, name: "ExifVersion", id: ExifTagExifVersion, fieldType: cUNDEFINED, count: 4},
+	ExifTagDateTimeOriginal:          {tag: cIFDEXIF, name: "DateTimeOriginal", id: ExifTagDateTimeOriginal, fieldType: cASCII, count: 20},
+	ExifTagDateTimeDigitized:         {tag: cIFDEXIF, name: "DateTimeDigitized", id: ExifTagDateTimeDigitized, fieldType: cASCII, count: 20},
+	ExifTagOffsetTime:                {tag: cIFDEXIF, name: "OffsetTime", id: ExifTagOffsetTime, fieldType: cASCII, count: 7},
+	ExifTagOffsetTimeOriginal:        {tag: cIFDEXIF, name: "OffsetTimeOriginal", id: ExifTagOffsetTimeOriginal, fieldType: cASCII, count: 7},
+	ExifTagOffsetTimeDigitized:       {tag: cIFDEXIF, name: "OffsetTimeDigitized", id: ExifTagOffsetTimeDigitized, fieldType: cASCII, count: 7},
+	ExifTagComponentsConfiguration:   {tag: cIFDEXIF, name: "ComponentsConfiguration", id: ExifTagComponentsConfiguration, fieldType: cUNDEFINED, count: 4},
+	ExifTagCompressedBitsPerPixel:    {tag: cIFDEXIF, name: "CompressedBitsPerPixel", id: ExifTagCompressedBitsPerPixel, fieldType: cURATIONAL, count: 1},
+	ExifTagShutterSpeedValue:         {tag: cIFDEXIF, name: "ShutterSpeedValue", id: ExifTagShutterSpeedValue, fieldType: cSRATIONAL, count: 1},
+	ExifTagApertureValue:             {tag: cIFDEXIF, name: "ApertureValue", id: ExifTagApertureValue, fieldType: cURATIONAL, count: 1},
+	ExifTagBrightnessValue:           {tag: cIFDEXIF, name: "BrightnessValue", id: ExifTagBrightnessValue, fieldType: cSRATIONAL, count: 1},
+	ExifTagExposureBiasValue:         {tag: cIFDEXIF, name: "ExposureBiasValue", id: ExifTagExposureBiasValue, fieldType: cSRATIONAL, count: 1},
+	ExifTagMaxApertureValue:          {tag: cIFDEXIF, name: "MaxApertureValue", id: ExifTagMaxApertureValue, fieldType: cURATIONAL, count: 1},
+	ExifTagSubjectDistance:           {tag: cIFDEXIF, name: "SubjectDistance", id: ExifTagSubjectDistance, fieldType: cURATIONAL, count: 1},
+	ExifTagMeteringMode:              {tag: cIFDEXIF, name: "MeteringMode", id: ExifTagMeteringMode, fieldType: cUSHORT, count: 1},
+	ExifTagLightSource:               {tag: cIFDEXIF, name: "LightSource", id: ExifTagLightSource, fieldType: cUSHORT, count: 1},
+	ExifTagFlash:                     {tag: cIFDEXIF, name: "Flash", id: ExifTagFlash, fieldType: cUSHORT, count: 1},
+	ExifTagFocalLength:               {tag: cIFDEXIF, name: "FocalLength", id: ExifTagFocalLength, fieldType: cURATIONAL, count: 1},
+	ExifTagSubjectArea:               {tag: cIFDEXIF, name: "SubjectArea", id: ExifTagSubjectArea, fieldType: cUSHORT, count: 0},
+	ExifTagMakerNote:                 {tag: cIFDEXIF, name: "MakerNote", id: ExifTagMakerNote, fieldType: cUNDEFINED, count: 0},
+	ExifTagUserComment:               {tag: cIFDEXIF, name: "UserComment", id: ExifTagUserComment, fieldType: cUNDEFINED, count: 0},
+	ExifTagSubsecTime:                {tag: cIFDEXIF, name: "SubsecTime", id: ExifTagSubsecTime, fieldType: cASCII, count: 0},
+	ExifTagSubsecTimeOriginal:        {tag: cIFDEXIF, name: "SubsecTimeOriginal", id: ExifTagSubsecTimeOriginal, fieldType: cASCII, count: 0},
+	ExifTagSubsecTimeDigitized:       {tag: cIFDEXIF, name: "SubsecTimeDigitized", id: ExifTagSubsecTimeDigitized, fieldType: cASCII, count: 0},
+	ExifTagFlashpixVersion:           {tag: cIFDEXIF, name: "FlashpixVersion", id: ExifTagFlashpixVersion, fieldType: cUNDEFINED, count: 4},
+	ExifTagColorSpace:                {tag: cIFDEXIF, name: "ColorSpace", id: ExifTagColorSpace, fieldType: cUSHORT, count: 1},
+	ExifTagPixelXDimension:           {tag: cIFDEXIF, name: "PixelXDimension", id: ExifTagPixelXDimension, fieldType: cSHORTLONG, count: 1},
+	ExifTagPixelYDimension:           {tag: cIFDEXIF, name: "PixelYDimension", id: ExifTagPixelYDimension, fieldType: cSHORTLONG, count: 1},
+	ExifTagRelatedSoundFile:          {tag: cIFDEXIF, name: "RelatedSoundFile", id: ExifTagRelatedSoundFile, fieldType: cASCII, count: 13},
+	ExifTagFlashEnergy:               {tag: cIFDEXIF, name: "FlashEnergy", id: ExifTagFlashEnergy, fieldType: cURATIONAL, count: 1},
+	ExifTagSpatialFrequencyResponse:  {tag: cIFDEXIF, name: "SpatialFrequencyResponse", id: ExifTagSpatialFrequencyResponse, fieldType: cUNDEFINED, count: 0},
+	ExifTagFocalPlaneXResolution:     {tag: cIFDEXIF, name: "FocalPlaneXResolution", id: ExifTagFocalPlaneXResolution, fieldType: cURATIONAL, count: 1},
+	ExifTagFocalPlaneYResolution:     {tag: cIFDEXIF, name: "FocalPlaneYResolution", id: ExifTagFocalPlaneYResolution, fieldType: cURATIONAL, count: 1},
+	ExifTagFocalPlaneResolutionUnit:  {tag: cIFDEXIF, name: "FocalPlaneResolutionUnit", id: ExifTagFocalPlaneResolutionUnit, fieldType: cUSHORT, count: 1},
+	ExifTagSubjectLocation:           {tag: cIFDEXIF, name: "SubjectLocation", id: ExifTagSubjectLocation, fieldType: cUSHORT, count: 2},
+	ExifTagExposureIndex:             {tag: cIFDEXIF, name: "ExposureIndex", id: ExifTagExposureIndex, fieldType: cURATIONAL, count: 1},
+	ExifTagSensingMethod:             {tag: cIFDEXIF, name: "SensingMethod", id: ExifTagSensingMethod, fieldType: cUSHORT, count: 1},
+	ExifTagFileSource:                {tag: cIFDEXIF, name: "FileSource", id: ExifTagFileSource, fieldType: cUNDEFINED, count: 1},
+	ExifTagSceneType:                 {tag: cIFDEXIF, name: "SceneType", id: ExifTagSceneType, fieldType: cUNDEFINED, count: 1},
+	ExifTagCFAPattern:                {tag: cIFDEXIF, name: "CFAPattern", id: ExifTagCFAPattern, fieldType: cUNDEFINED, count: 0},
+	ExifTagCustomRendered:            {tag: cIFDEXIF, name: "CustomRendered", id: ExifTagCustomRendered, fieldType: cUSHORT, count: 1},
+	ExifTagExposureMode:              {tag: cIFDEXIF, name: "ExposureMode", id: ExifTagExposureMode, fieldType: cUSHORT, count: 1},
+	ExifTagWhiteBalance:              {tag: cIFDEXIF, name: "WhiteBalance", id: ExifTagWhiteBalance, fieldType: cUSHORT, count: 1},
+	ExifTagDigitalZoomRatio:          {tag: cIFDEXIF, name: "DigitalZoomRatio", id: ExifTagDigitalZoomRatio, fieldType: cURATIONAL, count: 1},
+	ExifTagFocalLengthIn35mmFilm:     {tag: cIFDEXIF, name: "FocalLengthIn35mmFilm", id: ExifTagFocalLengthIn35mmFilm, fieldType: cUSHORT, count: 1},
+	ExifTagSceneCaptureType:          {tag: cIFDEXIF, name: "SceneCaptureType", id: ExifTagSceneCaptureType, fieldType: cUSHORT, count: 1},
+	ExifTagGainControl:               {tag: cIFDEXIF, name: "GainControl", id: ExifTagGainControl, fieldType: cUSHORT, count: 1},
+	ExifTagContrast:                  {tag: cIFDEXIF, name: "Contrast", id: ExifTagContrast, fieldType: cUSHORT, count: 1},
+	ExifTagSaturation:                {tag: cIFDEXIF, name: "Saturation", id: ExifTagSaturation, fieldType: cUSHORT, count: 1},
+	ExifTagSharpness:                 {tag: cIFDEXIF, name: "Sharpness", id: ExifTagSharpness, fieldType: cUSHORT, count: 1},
+	ExifTagDeviceSettingDescription:  {tag: cIFDEXIF, name: "DeviceSettingDescription", id: ExifTagDeviceSettingDescription, fieldType: cUNDEFINED, count: 0},
+	ExifTagSubjectDistanceRange:      {tag: cIFDEXIF, name: "SubjectDistanceRange", id: ExifTagSubjectDistanceRange, fieldType: cUSHORT, count: 1},
+	ExifTagImageUniqueID:             {tag: cIFDEXIF, name: "ImageUniqueID", id: ExifTagImageUniqueID, fieldType: cASCII, count: 33},
+	ExifTagCameraOwnerName:           {tag: cIFDEXIF, name: "CameraOwnerName", id: ExifTagCameraOwnerName, fieldType: cASCII, count: 0},
+	ExifTagBodySerialNumber:          {tag: cIFDEXIF, name: "BodySerialNumber", id: ExifTagBodySerialNumber, fieldType: cASCII, count: 0},
+	ExifTagLensSpecification:         {tag: cIFDEXIF, name: "LensSpecification", id: ExifTagLensSpecification, fieldType: cURATIONAL, count: 4},
+	ExifTagLensMake:                  {tag: cIFDEXIF, name: "LensMake", id: ExifTagLensMake, fieldType: cASCII, count: 0},
+	ExifTagLensModel:                 {tag: cIFDEXIF, name: "LensModel", id: ExifTagLensModel, fieldType: cASCII, count: 0},
+	ExifTagLensSerialNumber:          {tag: cIFDEXIF, name: "LensSerialNumber", id: ExifTagLensSerialNumber, fieldType: cASCII, count: 0},
+	ExifTagOffsetSchema:              {tag: cIFDEXIF, name: "OffsetSchema", id: ExifTagOffsetSchema, fieldType: cSLONG, count: 1},
 
 	// GPS tags
-	ExifGpsTagGPSVersionID:         {tag: cIFDGPS, name: "GPSVersionID", id: ExifGpsTagGPSVersionID},
-	ExifGpsTagGPSLatitudeRef:       {tag: cIFDGPS, name: "GPSLatitudeRef", id: ExifGpsTagGPSLatitudeRef},
-	ExifGpsTagGPSLatitude:          {tag: cIFDGPS, name: "GPSLatitude", id: ExifGpsTagGPSLatitude},
-	ExifGpsTagGPSLongitudeRef:      {tag: cIFDGPS, name: "GPSLongitudeRef", id: ExifGpsTagGPSLongitudeRef},
-	ExifGpsTagGPSLongitude:         {tag: cIFDGPS, name: "GPSLongitude", id: ExifGpsTagGPSLongitude},
-	ExifGpsTagGPSAltitudeRef:       {tag: cIFDGPS, name: "GPSAltitudeRef", id: ExifGpsTagGPSAltitudeRef},
-	ExifGpsTagGPSAltitude:          {tag: cIFDGPS, name: "GPSAltitude", id: ExifGpsTagGPSAltitude},
-	ExifGpsTagGPSTimestamp:         {tag: cIFDGPS, name: "GPSTimestamp", id: ExifGpsTagGPSTimestamp},
-	ExifGpsTagGPSSatellites:        {tag: cIFDGPS, name: "GPSSatellites", id: ExifGpsTagGPSSatellites},
-	ExifGpsTagGPSStatus:            {tag: cIFDGPS, name: "GPSStatus", id: ExifGpsTagGPSStatus},
-	ExifGpsTagGPSMeasureMode:       {tag: cIFDGPS, name: "GPSMeasureMode", id: ExifGpsTagGPSMeasureMode},
-	ExifGpsTagGPSDOP:               {tag: cIFDGPS, name: "GPSDOP", id: ExifGpsTagGPSDOP},
-	ExifGpsTagGPSSpeedRef:          {tag: cIFDGPS, name: "GPSSpeedRef", id: ExifGpsTagGPSSpeedRef},
-	ExifGpsTagGPSSpeed:             {tag: cIFDGPS, name: "GPSSpeed", id: ExifGpsTagGPSSpeed},
-	ExifGpsTagGPSTrackRef:          {tag: cIFDGPS, name: "GPSTrackRef", id: ExifGpsTagGPSTrackRef},
-	ExifGpsTagGPSTrack:             {tag: cIFDGPS, name: "GPSTrack", id: ExifGpsTagGPSTrack},
-	ExifGpsTagGPSImgDirectionRef:   {tag: cIFDGPS, name: "GPSImgDirectionRef", id: ExifGpsTagGPSImgDirectionRef},
-	ExifGpsTagGPSImgDirection:      {tag: cIFDGPS, name: "GPSImgDirection", id: ExifGpsTagGPSImgDirection},
-	ExifGpsTagGPSMapDatum:          {tag: cIFDGPS, name: "GPSMapDatum", id: ExifGpsTagGPSMapDatum},
-	ExifGpsTagGPSDestLatitudeRef:   {tag: cIFDGPS, name: "GPSDestLatitudeRef", id: ExifGpsTagGPSDestLatitudeRef},
-	ExifGpsTagGPSDestLatitude:      {tag: cIFDGPS, name: "GPSDestLatitude", id: ExifGpsTagGPSDestLatitude},
-	ExifGpsTagGPSDestLongitudeRef:  {tag: cIFDGPS, name: "GPSDestLongitudeRef", id: ExifGpsTagGPSDestLongitudeRef},
-	ExifGpsTagGPSDestLongitude:     {tag: cIFDGPS, name: "GPSDestLongitude", id: ExifGpsTagGPSDestLongitude},
-	ExifGpsTagGPSDestBearingRef:    {tag: cIFDGPS, name: "GPSDestBearingRef", id: ExifGpsTagGPSDestBearingRef},
-	ExifGpsTagGPSDestBearing:       {tag: cIFDGPS, name: "GPSDestBearing", id: ExifGpsTagGPSDestBearing},
-	ExifGpsTagGPSDestDistanceRef:   {tag: cIFDGPS, name: "GPSDestDistanceRef", id: ExifGpsTagGPSDestDistanceRef},
-	ExifGpsTagGPSDestDistance:      {tag: cIFDGPS, name: "GPSDestDistance", id: ExifGpsTagGPSDestDistance},
-	ExifGpsTagGPSProcessingMethod:  {tag: cIFDGPS, name: "GPSProcessingMethod", id: ExifGpsTagGPSProcessingMethod},
-	ExifGpsTagGPSAreaInformation:   {tag: cIFDGPS, name: "GPSAreaInformation", id: ExifGpsTagGPSAreaInformation},
-	ExifGpsTagGPSDateStamp:         {tag: cIFDGPS, name: "GPSDateStamp", id: ExifGpsTagGPSDateStamp},
-	ExifGpsTagGPSDifferential:      {tag: cIFDGPS, name: "GPSDifferential", id: ExifGpsTagGPSDifferential},
-	ExifGpsTagGPSHPositioningError: {tag: cIFDGPS, name: "GPSHPositioningError", id: ExifGpsTagGPSHPositioningError},
+	ExifGpsTagGPSVersionID:         {tag: cIFDGPS, name: "GPSVersionID", id: ExifGpsTagGPSVersionID, fieldType: cUBYTE, count: 4},
+	ExifGpsTagGPSLatitudeRef:       {tag: cIFDGPS, name: "GPSLatitudeRef", id: ExifGpsTagGPSLatitudeRef, fieldType: cASCII, count: 2},
+	ExifGpsTagGPSLatitude:          {tag: cIFDGPS, name: "GPSLatitude", id: ExifGpsTagGPSLatitude, fieldType: cURATIONAL, count: 3},
+	ExifGpsTagGPSLongitudeRef:      {tag: cIFDGPS, name: "GPSLongitudeRef", id: ExifGpsTagGPSLongitudeRef, fieldType: cASCII, count: 2},
+	ExifGpsTagGPSLongitude:         {tag: cIFDGPS, name: "GPSLongitude", id: ExifGpsTagGPSLongitude, fieldType: cURATIONAL, count: 3},
+	ExifGpsTagGPSAltitudeRef:       {tag: cIFDGPS, name: "GPSAltitudeRef", id: ExifGpsTagGPSAltitudeRef, fieldType: cUBYTE, count: 1},
+	ExifGpsTagGPSAltitude:          {tag: cIFDGPS, name: "GPSAltitude", id: ExifGpsTagGPSAltitude, fieldType: cURATIONAL, count: 1},
+	ExifGpsTagGPSTimestamp:         {tag: cIFDGPS, name: "GPSTimestamp", id: ExifGpsTagGPSTimestamp, fieldType: cURATIONAL, count: 3},
+	ExifGpsTagGPSSatellites:        {tag: cIFDGPS, name: "GPSSatellites", id: ExifGpsTagGPSSatellites, fieldType: cASCII, count: 0},
+	ExifGpsTagGPSStatus:            {tag: cIFDGPS, name: "GPSStatus", id: ExifGpsTagGPSStatus, fieldType: cASCII, count: 2},
+	ExifGpsTagGPSMeasureMode:       {tag: cIFDGPS, name: "GPSMeasureMode", id: ExifGpsTagGPSMeasureMode, fieldType: cASCII, count: 2},
+	ExifGpsTagGPSDOP:               {tag: cIFDGPS, name: "GPSDOP", id: ExifGpsTagGPSDOP, fieldType: cURATIONAL, count: 1},
+	ExifGpsTagGPSSpeedRef:          {tag: cIFDGPS, name: "GPSSpeedRef", id: ExifGpsTagGPSSpeedRef, fieldType: cASCII, count: 2},
+	ExifGpsTagGPSSpeed:             {tag: cIFDGPS, name: "GPSSpeed", id: ExifGpsTagGPSSpeed, fieldType: cURATIONAL, count: 1},
+	ExifGpsTagGPSTrackRef:          {tag: cIFDGPS, name: "GPSTrackRef", id: ExifGpsTagGPSTrackRef, fieldType: cASCII, count: 2},
+	ExifGpsTagGPSTrack:             {tag: cIFDGPS, name: "GPSTrack", id: ExifGpsTagGPSTrack, fieldType: cURATIONAL, count: 1},
+	ExifGpsTagGPSImgDirectionRef:   {tag: cIFDGPS, name: "GPSImgDirectionRef", id: ExifGpsTagGPSImgDirectionRef, fieldType: cASCII, count: 2},
+	ExifGpsTagGPSImgDirection:      {tag: cIFDGPS, name: "GPSImgDirection", id: ExifGpsTagGPSImgDirection, fieldType: cURATIONAL, count: 1},
+	ExifGpsTagGPSMapDatum:          {tag: cIFDGPS, name: "GPSMapDatum", id: ExifGpsTagGPSMapDatum, fieldType: cASCII, count: 0},
+	ExifGpsTagGPSDestLatitudeRef:   {tag: cIFDGPS, name: "GPSDestLatitudeRef", id: ExifGpsTagGPSDestLatitudeRef, fieldType: cASCII, count: 2},
+	ExifGpsTagGPSDestLatitude:      {tag: cIFDGPS, name: "GPSDestLatitude", id: ExifGpsTagGPSDestLatitude, fieldType: cURATIONAL, count: 3},
+	ExifGpsTagGPSDestLongitudeRef:  {tag: cIFDGPS, name: "GPSDestLongitudeRef", id: ExifGpsTagGPSDestLongitudeRef, fieldType: cASCII, count: 2},
+	ExifGpsTagGPSDestLongitude:     {tag: cIFDGPS, name: "GPSDestLongitude", id: ExifGpsTagGPSDestLongitude, fieldType: cURATIONAL, count: 3},
+	ExifGpsTagGPSDestBearingRef:    {tag: cIFDGPS, name: "GPSDestBearingRef", id: ExifGpsTagGPSDestBearingRef, fieldType: cASCII, count: 2},
+	ExifGpsTagGPSDestBearing:       {tag: cIFDGPS, name: "GPSDestBearing", id: ExifGpsTagGPSDestBearing, fieldType: cURATIONAL, count: 1},
+	ExifGpsTagGPSDestDistanceRef:   {tag: cIFDGPS, name: "GPSDestDistanceRef", id: ExifGpsTagGPSDestDistanceRef, fieldType: cASCII, count: 2},
+	ExifGpsTagGPSDestDistance:      {tag: cIFDGPS, name: "GPSDestDistance", id: ExifGpsTagGPSDestDistance, fieldType: cURATIONAL, count: 1},
+	ExifGpsTagGPSProcessingMethod:  {tag: cIFDGPS, name: "GPSProcessingMethod", id: ExifGpsTagGPSProcessingMethod, fieldType: cUNDEFINED, count: 0},
+	ExifGpsTagGPSAreaInformation:   {tag: cIFDGPS, name: "GPSAreaInformation", id: ExifGpsTagGPSAreaInformation, fieldType: cUNDEFINED, count: 0},
+	ExifGpsTagGPSDateStamp:         {tag: cIFDGPS, name: "GPSDateStamp", id: ExifGpsTagGPSDateStamp, fieldType: cASCII, count: 11},
+	ExifGpsTagGPSDifferential:      {tag: cIFDGPS, name: "GPSDifferential", id: ExifGpsTagGPSDifferential, fieldType: cUSHORT, count: 1},
+	ExifGpsTagGPSHPositioningError: {tag: cIFDGPS, name: "GPSHPositioningError", id: ExifGpsTagGPSHPositioningError, fieldType: cURATIONAL, count: 1},
 
 	// Microsoft Windows metadata. Non-standard, but ubiquitous
-	ExifXpTagXPTitle:    {tag: cIFDZERO, name: "XPTitle", id: ExifXpTagXPTitle},
-	ExifXpTagXPComment:  {tag: cIFDZERO, name: "XPComment", id: ExifXpTagXPComment},
-	ExifXpTagXPAuthor:   {tag: cIFDZERO, name: "XPAuthor", id: ExifXpTagXPAuthor},
-	ExifXpTagXPKeywords: {tag: cIFDZERO, name: "XPKeywords", id: ExifXpTagXPKeywords},
-	ExifXpTagXPSubject:  {tag: cIFDZERO, name: "XPSubject", id: ExifXpTagXPSubject},
+	ExifXpTagXPTitle:    {tag: cIFDZERO, name: "XPTitle", id: ExifXpTagXPTitle, fieldType: cUBYTE, count: 0},
+	ExifXpTagXPComment:  {tag: cIFDZERO, name: "XPComment", id: ExifXpTagXPComment, fieldType: cUBYTE, count: 0},
+	ExifXpTagXPAuthor:   {tag: cIFDZERO, name: "XPAuthor", id: ExifXpTagXPAuthor, fieldType: cUBYTE, count: 0},
+	ExifXpTagXPKeywords: {tag: cIFDZERO, name: "XPKeywords", id: ExifXpTagXPKeywords, fieldType: cUBYTE, count: 0},
+	ExifXpTagXPSubject:  {tag: cIFDZERO, name: "XPSubject", id: ExifXpTagXPSubject, fieldType: cUBYTE, count: 0},
 }
 
 const (
@@ -0,0 +1,736 @@
+package ImgMeta
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+/*
+Writing EXIF
+
+The ExifWriter holds the IFD tree in memory, tag values are kept in the byte order of the writer. Encode lays out
+the TIFF structure in the following order, every value that does not fit in its tag is placed directly after its IFD
+on a word (2 byte) boundary:
+
+    [Record name]    [description]
+    ---------------------------------------
+    TIFF header      byte order, 42, offset of IFD0 (8)
+    IFD0             + values, the Exif and GPS IFD pointers are added when those IFDs have tags
+    Exif IFD         + values, the Interop IFD pointer is added when that IFD has tags
+    Interop IFD      + values
+    GPS IFD          + values
+    IFD1             + values, linked by IFD0, JPEGInterchangeFormat(Length) are added for the thumbnail
+    Thumbnail        JPEG stream
+    MakerNote        placed last, see placeMakerNote
+
+The segment length is stored in 2 bytes, so the TIFF data can be at most 65527 bytes (65535 minus the length field
+and "Exif\0\0"). When the data is too large the thumbnail is dropped first, then the maker note and then the largest
+values; the dropped tags are reported by Encode.
+
+A maker note that is copied from an existing EXIF segment is written together with an OffsetSchema tag so that
+readers can find its values even when it had to be moved. Some vendors use the byte order of the TIFF header
+in their maker note, so changing the byte order drops a copied maker note.
+
+*/
+
+// EXIF IFDs, for use with ExifWriter
+const (
+	IFD0       = cIFDZERO
+	IFDExif    = cIFDEXIF
+	IFDGPS     = cIFDGPS
+	IFDInterop = cIFDINTEROP
+	IFD1       = cIFDONE
+)
+
+// Interoperability IFD tags, these share their IDs with GPS tags so they are not part of aExifTagDescr
+const (
+	ExifInteropTagInteroperabilityIndex   uint16 = 0x1
+	ExifInteropTagInteroperabilityVersion uint16 = 0x2
+)
+
+var aExifInteropTagDescr = map[uint16]tExifTagDescr{
+	ExifInteropTagInteroperabilityIndex:   {tag: cIFDINTEROP, name: "InteroperabilityIndex", id: ExifInteropTagInteroperabilityIndex, fieldType: cASCII, count: 4},
+	ExifInteropTagInteroperabilityVersion: {tag: cIFDINTEROP, name: "InteroperabilityVersion", id: ExifInteropTagInteroperabilityVersion, fieldType: cUNDEFINED, count: 4},
+}
+
+// cExifMaxTIFFSize is the maximum size of the TIFF data in an EXIF APP1 segment
+const cExifMaxTIFFSize = 0xFFFF - 2 - 6
+
+// Rational is an unsigned EXIF RATIONAL value
+type Rational struct {
+	Numerator   uint32
+	Denominator uint32
+}
+
+// SRational is a signed EXIF SRATIONAL value
+type SRational struct {
+	Numerator   int32
+	Denominator int32
+}
+
+// ExifDroppedTag reports a tag that was left out by Encode because the segment would not fit
+type ExifDroppedTag struct {
+	IFD  uint16
+	Tag  uint16
+	Size uint32 // size of the value in bytes
+}
+
+// tExifValue is the raw value of a tag in the byte order of the writer
+type tExifValue struct {
+	fieldType uint16 // TIFF type, without cARRAY
+	count     uint32
+	data      []byte
+}
+
+// ExifWriter builds an EXIF APP1 segment
+type ExifWriter struct {
+	endian    binary.ByteOrder
+	ifds      map[uint16]map[uint16]tExifValue
+	thumbnail []byte
+	makerNote *tEXIFAPP // EXIF segment the maker note is copied from
+}
+
+// NewExifWriter returns an empty EXIF writer that writes in the given byte order
+func NewExifWriter(endian binary.ByteOrder) *ExifWriter {
+	return &ExifWriter{endian: endian, ifds: map[uint16]map[uint16]tExifValue{}}
+}
+
+// NewExifWriterFrom returns an EXIF writer holding the tags, thumbnail and maker note of the image, when the
+// image has no EXIF an empty big-endian writer is returned. Tags that hold offsets, such as strips, tiles and
+// sub-IFDs other than the Exif, GPS and Interop IFDs, are left out.
+func NewExifWriterFrom(image Image) (*ExifWriter, error) {
+	app, exists := image.apps["EXIF"]
+	if !exists {
+		return NewExifWriter(binary.BigEndian), nil
+	}
+	exif, ok := app.(*tEXIFAPP)
+	if !ok {
+		return nil, &exifError{"'EXIF' meta section was not read as EXIF"}
+	}
+	w := NewExifWriter(exif.TIFFByteOrder())
+	for ifdType, ifd := range exif.IFDs() {
+		if ifdType == cIFDONE {
+			thumbnail, err := exifThumbnail(exif, ifd)
+			if err != nil {
+				// Uncompressed thumbnails are not supported, IFD1 is left out
+				continue
+			}
+			w.thumbnail = thumbnail
+		}
+		n := ifd.NumberOfTags()
+		for i := uint32(0); i < n; i++ {
+			tag := ifd.GetTag(i)
+			id := tag.TagID()
+			if exifWriterManaged(ifdType, id) || exifHoldsOffset(tag) {
+				continue
+			}
+			if ifdType == cIFDEXIF && id == ExifTagMakerNote {
+				if _, _, _, err := exif.rawMakerNote(); err == nil {
+					w.makerNote = exif
+				}
+				continue
+			}
+			data, err := ifd.valueData(tag)
+			if err != nil {
+				continue
+			}
+			w.set(ifdType, id, tExifValue{fieldType: tag.TypeID() &^ cARRAY, count: tag.countOrComponents(), data: append([]byte{}, data...)})
+		}
+	}
+	return w, nil
+}
+
+// exifThumbnail returns the JPEG thumbnail that IFD1 points to
+func exifThumbnail(exif *tEXIFAPP, ifd tExifIFD) ([]byte, error) {
+	offsetTag, found1 := ifd.FindTag(ExifTagJPEGInterchangeFormat)
+	lengthTag, found2 := ifd.FindTag(ExifTagJPEGInterchangeFormatLength)
+	if !found1 || !found2 {
+		return nil, &exifError{"EXIF IFD1 does not have a JPEG thumbnail"}
+	}
+	offsetValue, _ := ifd.ReadValue(offsetTag)
+	lengthValue, _ := ifd.ReadValue(lengthTag)
+	offset, length := exifInts(offsetValue), exifInts(lengthValue)
+	if len(offset) != 1 || len(length) != 1 {
+		return nil, &exifError{"EXIF thumbnail tags are malformed"}
+	}
+	begin := uint64(cEXIFTIFFOffset) + uint64(offset[0])
+	end := begin + uint64(length[0])
+	if end > uint64(len(exif.block)) {
+		return nil, &exifError{"EXIF thumbnail is out of bounds"}
+	}
+	return append([]byte{}, exif.block[begin:end]...), nil
+}
+
+// exifWriterManaged is true for the tags that the writer adds by itself
+func exifWriterManaged(ifdType uint16, id uint16) bool {
+	switch {
+	case ifdType == cIFDZERO && (id == cIFDEXIF || id == cIFDGPS):
+		return true
+	case ifdType == cIFDEXIF && (id == cIFDINTEROP || id == ExifTagOffsetSchema):
+		return true
+	case ifdType == cIFDONE && (id == ExifTagJPEGInterchangeFormat || id == ExifTagJPEGInterchangeFormatLength):
+		return true
+	}
+	return false
+}
+
+// aExifOffsetTags are the tags whose value is an offset into the TIFF structure (or the size of the data at such
+// an offset), the offsets point to nothing once the writer lays out the IFDs again
+var aExifOffsetTags = map[uint16]bool{
+	cIFDEXIF:                           true,
+	cIFDGPS:                            true,
+	cIFDINTEROP:                        true,
+	ExifTagStripOffsets:                true,
+	ExifTagStripByteCounts:             true,
+	0x0120:                             true, // FreeOffsets
+	0x0121:                             true, // FreeByteCounts
+	0x0144:                             true, // TileOffsets
+	0x0145:                             true, // TileByteCounts
+	0x014A:                             true, // SubIFDs
+	0x0190:                             true, // GlobalParametersIFD
+	ExifTagJPEGInterchangeFormat:       true,
+	ExifTagJPEGInterchangeFormatLength: true,
+}
+
+// exifHoldsOffset returns true for the tags of an image that can not be copied to the writer because their value
+// is an offset
+func exifHoldsOffset(tag tExifTag) bool {
+	if fieldType := tag.TypeID() &^ cARRAY; fieldType == cIFD || fieldType == cIFD8 {
+		return true
+	}
+	return aExifOffsetTags[tag.TagID()]
+}
+
+// exifTagDescr returns the description of a tag in an IFD, IFD1 has the same tags as IFD0
+func exifTagDescr(ifdType uint16, id uint16) (tExifTagDescr, bool) {
+	if ifdType == cIFDINTEROP {
+		descr, known := aExifInteropTagDescr[id]
+		return descr, known
+	}
+	descr, known := aExifTagDescr[id]
+	return descr, known
+}
+
+func (w *ExifWriter) set(ifdType uint16, id uint16, value tExifValue) {
+	if _, exists := w.ifds[ifdType]; !exists {
+		w.ifds[ifdType] = map[uint16]tExifValue{}
+	}
+	w.ifds[ifdType][id] = value
+}
+
+// SetTag sets the value of a tag, the value is checked against the type and count of the tag. Values are given
+// as their Go type: string (ASCII), []byte (BYTE or UNDEFINED), uint16, uint32, int8, int16, int32, Rational,
+// SRational, float32, float64 or a slice of those; an int (or []int) is converted to the integer type of the tag.
+func (w *ExifWriter) SetTag(ifdType uint16, id uint16, value interface{}) error {
+	switch ifdType {
+	case cIFDZERO, cIFDEXIF, cIFDGPS, cIFDINTEROP, cIFDONE:
+	default:
+		return &exifError{fmt.Sprintf("0x%X is not an EXIF IFD", ifdType)}
+	}
+	if exifWriterManaged(ifdType, id) || (ifdType == cIFDZERO && id == cIFDINTEROP) {
+		return &exifError{fmt.Sprintf("EXIF tag 0x%X is written by the encoder", id)}
+	}
+	descr, known := exifTagDescr(ifdType, id)
+	if known && descr.tag != ifdType && !(ifdType == cIFDONE && descr.tag == cIFDZERO) {
+		return &exifError{fmt.Sprintf("EXIF tag %s does not belong in IFD 0x%X", descr.name, ifdType)}
+	}
+
+	if known {
+		converted, err := exifIntValue(descr.fieldType, value)
+		if err != nil {
+			return &exifError{fmt.Sprintf("EXIF tag %s: %s", descr.name, err.Error())}
+		}
+		value = converted
+	}
+	encoded, err := encodeExifValue(w.endian, value)
+	if err != nil {
+		return err
+	}
+	if known {
+		switch {
+		case encoded.fieldType == descr.fieldType:
+		case descr.fieldType == cSHORTLONG && (encoded.fieldType == cUSHORT || encoded.fieldType == cULONG):
+		case descr.fieldType == cUNDEFINED && encoded.fieldType == cUBYTE:
+			encoded.fieldType = cUNDEFINED
+		default:
+			return &exifError{fmt.Sprintf("EXIF tag %s does not take a %T", descr.name, value)}
+		}
+		if descr.count != 0 && encoded.count != descr.count {
+			return &exifError{fmt.Sprintf("EXIF tag %s takes %d values, not %d", descr.name, descr.count, encoded.count)}
+		}
+	}
+	if ifdType == cIFDEXIF && id == ExifTagMakerNote {
+		// Replaces the maker note that was copied from the image
+		w.makerNote = nil
+	}
+	w.set(ifdType, id, encoded)
+	return nil
+}
+
+// Tag returns the value of a tag, decoded like ReadValue does
+func (w *ExifWriter) Tag(ifdType uint16, id uint16) (interface{}, bool) {
+	value, exists := w.ifds[ifdType][id]
+	if !exists {
+		return nil, false
+	}
+	typeID := value.fieldType
+	if value.count > 1 {
+		typeID |= cARRAY
+	}
+	decoded, err := decodeExifValue(w.endian, typeID, value.count, value.data)
+	return decoded, err == nil
+}
+
+// RemoveTag removes a tag, it returns false when the tag was not set
+func (w *ExifWriter) RemoveTag(ifdType uint16, id uint16) bool {
+	if ifdType == cIFDEXIF && id == ExifTagMakerNote && w.makerNote != nil {
+		w.makerNote = nil
+		return true
+	}
+	if _, exists := w.ifds[ifdType][id]; !exists {
+		return false
+	}
+	delete(w.ifds[ifdType], id)
+	return true
+}
+
+// SetThumbnail sets the JPEG thumbnail stored after IFD1, nil removes the thumbnail
+func (w *ExifWriter) SetThumbnail(thumbnail []byte) {
+	w.thumbnail = thumbnail
+}
+
+// Thumbnail returns the JPEG thumbnail
+func (w *ExifWriter) Thumbnail() []byte {
+	return w.thumbnail
+}
+
+// ByteOrder returns the byte order the writer writes in
+func (w *ExifWriter) ByteOrder() binary.ByteOrder {
+	return w.endian
+}
+
+// SetByteOrder changes the byte order of the EXIF data, the values that are already set are converted
+func (w *ExifWriter) SetByteOrder(endian binary.ByteOrder) {
+	if endian == w.endian {
+		return
+	}
+	for _, tags := range w.ifds {
+		for id, value := range tags {
+			tags[id] = swapExifValue(value)
+		}
+	}
+	w.endian = endian
+}
+
+// swapExifValue reverses the byte order of each number in a value
+func swapExifValue(value tExifValue) tExifValue {
+	if value.fieldType == 0 || int(value.fieldType) >= len(aExifTagFieldSize) {
+		return value
+	}
+	size := getExifTagFieldSize(tExifTagFieldType(value.fieldType))
+	if value.fieldType == cURATIONAL || value.fieldType == cSRATIONAL {
+		size = 4
+	}
+	data := append([]byte{}, value.data...)
+	for i := 0; i+size <= len(data); i += size {
+		for a, b := i, i+size-1; a < b; a, b = a+1, b-1 {
+			data[a], data[b] = data[b], data[a]
+		}
+	}
+	value.data = data
+	return value
+}
+
+// exifIntValue converts an int or []int to the integer type of a tag
+func exifIntValue(fieldType uint16, value interface{}) (interface{}, error) {
+	var values []int
+	switch v := value.(type) {
+	case int:
+		values = []int{v}
+	case []int:
+		values = v
+	default:
+		return value, nil
+	}
+	min, max := int64(0), int64(0)
+	switch fieldType {
+	case cUBYTE:
+		max = math.MaxUint8
+	case cUSHORT:
+		max = math.MaxUint16
+	case cULONG, cSHORTLONG:
+		max = math.MaxUint32
+	case cSBYTE:
+		min, max = math.MinInt8, math.MaxInt8
+	case cSSHORT:
+		min, max = math.MinInt16, math.MaxInt16
+	case cSLONG:
+		min, max = math.MinInt32, math.MaxInt32
+	default:
+		return nil, &exifError{"an integer value is not allowed"}
+	}
+	for _, v := range values {
+		if int64(v) < min || int64(v) > max {
+			return nil, &exifError{fmt.Sprintf("value %d is out of range", v)}
+		}
+	}
+	if fieldType == cSHORTLONG {
+		fieldType = cUSHORT
+		for _, v := range values {
+			if v > math.MaxUint16 {
+				fieldType = cULONG
+			}
+		}
+	}
+	switch fieldType {
+	case cUBYTE:
+		array := make([]uint8, len(values))
+		for i, v := range values {
+			array[i] = uint8(v)
+		}
+		return array, nil
+	case cUSHORT:
+		array := make([]uint16, len(values))
+		for i, v := range values {
+			array[i] = uint16(v)
+		}
+		return array, nil
+	case cULONG:
+		array := make([]uint32, len(values))
+		for i, v := range values {
+			array[i] = uint32(v)
+		}
+		return array, nil
+	case cSBYTE:
+		array := make([]int8, len(values))
+		for i, v := range values {
+			array[i] = int8(v)
+		}
+		return array, nil
+	case cSSHORT:
+		array := make([]int16, len(values))
+		for i, v := range values {
+			array[i] = int16(v)
+		}
+		return array, nil
+	}
+	array := make([]int32, len(values))
+	for i, v := range values {
+		array[i] = int32(v)
+	}
+	return array, nil
+}
+
+// encodeExifValue encodes a Go value as an EXIF value
+func encodeExifValue(endian binary.ByteOrder, value interface{}) (tExifValue, error) {
+	var encoded tExifValue
+	put := func(fieldType uint16, count int, size int, write func(data []byte, i int)) {
+		encoded = tExifValue{fieldType: fieldType, count: uint32(count), data: make([]byte, count*size)}
+		for i := 0; i < count; i++ {
+			write(encoded.data[i*size:], i)
+		}
+	}
+	switch v := value.(type) {
+	case string:
+		encoded = tExifValue{fieldType: cASCII, count: uint32(len(v) + 1), data: append([]byte(v), 0)}
+	case uint8:
+		put(cUBYTE, 1, 1, func(data []byte, i int) { data[0] = v })
+	case []uint8:
+		put(cUBYTE, len(v), 1, func(data []byte, i int) { data[0] = v[i] })
+	case uint16:
+		put(cUSHORT, 1, 2, func(data []byte, i int) { endian.PutUint16(data, v) })
+	case []uint16:
+		put(cUSHORT, len(v), 2, func(data []byte, i int) { endian.PutUint16(data, v[i]) })
+	case uint32:
+		put(cULONG, 1, 4, func(data []byte, i int) { endian.PutUint32(data, v) })
+	case []uint32:
+		put(cULONG, len(v), 4, func(data []byte, i int) { endian.PutUint32(data, v[i]) })
+	case int8:
+		put(cSBYTE, 1, 1, func(data []byte, i int) { data[0] = uint8(v) })
+	case []int8:
+		put(cSBYTE, len(v), 1, func(data []byte, i int) { data[0] = uint8(v[i]) })
+	case int16:
+		put(cSSHORT, 1, 2, func(data []byte, i int) { endian.PutUint16(data, uint16(v)) })
+	case []int16:
+		put(cSSHORT, len(v), 2, func(data []byte, i int) { endian.PutUint16(data, uint16(v[i])) })
+	case int32:
+		put(cSLONG, 1, 4, func(data []byte, i int) { endian.PutUint32(data, uint32(v)) })
+	case []int32:
+		put(cSLONG, len(v), 4, func(data []byte, i int) { endian.PutUint32(data, uint32(v[i])) })
+	case Rational:
+		put(cURATIONAL, 1, 8, func(data []byte, i int) {
+			endian.PutUint32(data, v.Numerator)
+			endian.PutUint32(data[4:], v.Denominator)
+		})
+	case []Rational:
+		put(cURATIONAL, len(v), 8, func(data []byte, i int) {
+			endian.PutUint32(data, v[i].Numerator)
+			endian.PutUint32(data[4:], v[i].Denominator)
+		})
+	case SRational:
+		put(cSRATIONAL, 1, 8, func(data []byte, i int) {
+			endian.PutUint32(data, uint32(v.Numerator))
+			endian.PutUint32(data[4:], uint32(v.Denominator))
+		})
+	case []SRational:
+		put(cSRATIONAL, len(v), 8, func(data []byte, i int) {
+			endian.PutUint32(data, uint32(v[i].Numerator))
+			endian.PutUint32(data[4:], uint32(v[i].Denominator))
+		})
+	case float32:
+		put(cFLOAT32, 1, 4, func(data []byte, i int) { endian.PutUint32(data, math.Float32bits(v)) })
+	case []float32:
+		put(cFLOAT32, len(v), 4, func(data []byte, i int) { endian.PutUint32(data, math.Float32bits(v[i])) })
+	case float64:
+		put(cFLOAT64, 1, 8, func(data []byte, i int) { endian.PutUint64(data, math.Float64bits(v)) })
+	case []float64:
+		put(cFLOAT64, len(v), 8, func(data []byte, i int) { endian.PutUint64(data, math.Float64bits(v[i])) })
+	default:
+		return encoded, &exifError{fmt.Sprintf("EXIF can not hold a value of type %T", value)}
+	}
+	if encoded.count == 0 {
+		return encoded, &exifError{"EXIF value is empty"}
+	}
+	return encoded, nil
+}
+
+// tExifEntry is a tag of an IFD that is being laid out
+type tExifEntry struct {
+	id     uint16
+	value  tExifValue
+	offset uint32 // TIFF offset of the value when it does not fit in the tag
+}
+
+// tExifLayoutIFD is an IFD that is being laid out
+type tExifLayoutIFD struct {
+	ifdType uint16
+	offset  uint32
+	entries []tExifEntry
+}
+
+func (ifd *tExifLayoutIFD) entry(id uint16) *tExifEntry {
+	for i := range ifd.entries {
+		if ifd.entries[i].id == id {
+			return &ifd.entries[i]
+		}
+	}
+	return nil
+}
+
+// Encode builds the EXIF APP1 segment (marker, length, "Exif\0\0" and the TIFF data), it reports the tags that
+// were left out to make the segment fit.
+func (w *ExifWriter) Encode() (block []byte, dropped []ExifDroppedTag, err error) {
+	ifds := map[uint16]map[uint16]tExifValue{}
+	for ifdType, tags := range w.ifds {
+		ifds[ifdType] = map[uint16]tExifValue{}
+		for id, value := range tags {
+			ifds[ifdType][id] = value
+		}
+	}
+	thumbnail := w.thumbnail
+	makerNote := w.makerNote
+	if makerNote != nil && makerNote.TIFFByteOrder() != w.endian {
+		_, size, _, _ := makerNote.rawMakerNote()
+		dropped = append(dropped, ExifDroppedTag{IFD: cIFDEXIF, Tag: ExifTagMakerNote, Size: size})
+		makerNote = nil
+	}
+
+	for {
+		tiff, err := w.layout(ifds, thumbnail, makerNote)
+		if err != nil {
+			return nil, dropped, err
+		}
+		if len(tiff) <= cExifMaxTIFFSize {
+			block = make([]byte, 4, 4+len(idEXIF)+len(tiff))
+			binary.BigEndian.PutUint16(block, cEXIF)
+			binary.BigEndian.PutUint16(block[2:], uint16(2+len(idEXIF)+len(tiff)))
+			block = append(block, idEXIF...)
+			return append(block, tiff...), dropped, nil
+		}
+
+		// Too large, drop the thumbnail, the maker note or the largest value
+		if thumbnail != nil {
+			dropped = append(dropped, ExifDroppedTag{IFD: cIFDONE, Tag: ExifTagJPEGInterchangeFormat, Size: uint32(len(thumbnail))})
+			thumbnail = nil
+			continue
+		}
+		if makerNote != nil {
+			_, size, _, _ := makerNote.rawMakerNote()
+			dropped = append(dropped, ExifDroppedTag{IFD: cIFDEXIF, Tag: ExifTagMakerNote, Size: size})
+			makerNote = nil
+			continue
+		}
+		largest := ExifDroppedTag{}
+		for _, ifdType := range []uint16{cIFDZERO, cIFDEXIF, cIFDINTEROP, cIFDGPS, cIFDONE} {
+			for id, value := range ifds[ifdType] {
+				size := uint32(len(value.data))
+				if size > largest.Size || (size == largest.Size && ifdType == largest.IFD && id < largest.Tag) {
+					largest = ExifDroppedTag{IFD: ifdType, Tag: id, Size: size}
+				}
+			}
+		}
+		if largest.Size <= 4 {
+			return nil, dropped, &exifError{"EXIF data does not fit in a segment"}
+		}
+		delete(ifds[largest.IFD], largest.Tag)
+		dropped = append(dropped, largest)
+	}
+}
+
+// layout lays out and writes the TIFF data
+func (w *ExifWriter) layout(ifds map[uint16]map[uint16]tExifValue, thumbnail []byte, makerNote *tEXIFAPP) ([]byte, error) {
+	pointer := tExifValue{fieldType: cULONG, count: 1, data: make([]byte, 4)}
+	hasIFD := func(ifdType uint16) bool { return len(ifds[ifdType]) > 0 }
+
+	// Collect the IFDs with the tags that the writer manages
+	layout := []*tExifLayoutIFD{}
+	add := func(ifdType uint16, extra map[uint16]tExifValue) *tExifLayoutIFD {
+		ifd := &tExifLayoutIFD{ifdType: ifdType}
+		for id, value := range ifds[ifdType] {
+			ifd.entries = append(ifd.entries, tExifEntry{id: id, value: value})
+		}
+		for id, value := range extra {
+			value.data = append([]byte{}, value.data...)
+			ifd.entries = append(ifd.entries, tExifEntry{id: id, value: value})
+		}
+		sort.Slice(ifd.entries, func(a, b int) bool { return ifd.entries[a].id < ifd.entries[b].id })
+		layout = append(layout, ifd)
+		return ifd
+	}
+	hasExif := hasIFD(cIFDEXIF) || hasIFD(cIFDINTEROP) || makerNote != nil
+	extra := map[uint16]tExifValue{}
+	if hasExif {
+		extra[cIFDEXIF] = pointer
+	}
+	if hasIFD(cIFDGPS) {
+		extra[cIFDGPS] = pointer
+	}
+	add(cIFDZERO, extra)
+	if hasExif {
+		extra = map[uint16]tExifValue{}
+		if hasIFD(cIFDINTEROP) {
+			extra[cIFDINTEROP] = pointer
+		}
+		if makerNote != nil {
+			_, size, _, _ := makerNote.rawMakerNote()
+			extra[ExifTagMakerNote] = tExifValue{fieldType: cUNDEFINED, count: size}
+			extra[ExifTagOffsetSchema] = tExifValue{fieldType: cSLONG, count: 1, data: make([]byte, 4)}
+		}
+		add(cIFDEXIF, extra)
+	}
+	if hasIFD(cIFDINTEROP) {
+		add(cIFDINTEROP, nil)
+	}
+	if hasIFD(cIFDGPS) {
+		add(cIFDGPS, nil)
+	}
+	if thumbnail != nil || hasIFD(cIFDONE) {
+		extra = map[uint16]tExifValue{}
+		if thumbnail != nil {
+			extra[ExifTagJPEGInterchangeFormat] = pointer
+			extra[ExifTagJPEGInterchangeFormatLength] = pointer
+			if _, exists := ifds[cIFDONE][ExifTagCompression]; !exists {
+				compression, _ := encodeExifValue(w.endian, uint16(6))
+				extra[ExifTagCompression] = compression
+			}
+		}
+		add(cIFDONE, extra)
+	}
+
+	// Assign offsets, values that do not fit in their tag follow their IFD
+	align := func(offset uint32) uint32 { return (offset + 1) &^ 1 }
+	position := uint32(8)
+	for _, ifd := range layout {
+		ifd.offset = position
+		position += 2 + 12*uint32(len(ifd.entries)) + 4
+		for i := range ifd.entries {
+			entry := &ifd.entries[i]
+			if len(entry.value.data) > 4 {
+				position = align(position)
+				entry.offset = position
+				position += uint32(len(entry.value.data))
+			}
+		}
+	}
+	thumbnailOffset := uint32(0)
+	if thumbnail != nil {
+		position = align(position)
+		thumbnailOffset = position
+		position += uint32(len(thumbnail))
+	}
+	var placement tMakerNotePlacement
+	if makerNote != nil {
+		var err error
+		placement, err = makerNote.placeMakerNote(align(position), cExifMaxTIFFSize)
+		if err != nil {
+			return nil, err
+		}
+		position = placement.offset + uint32(len(placement.data))
+	}
+
+	// Fill in the pointers
+	offsets := map[uint16]uint32{}
+	for _, ifd := range layout {
+		offsets[ifd.ifdType] = ifd.offset
+	}
+	for _, ifd := range layout {
+		switch ifd.ifdType {
+		case cIFDZERO:
+			for _, link := range []uint16{cIFDEXIF, cIFDGPS} {
+				if entry := ifd.entry(link); entry != nil {
+					w.endian.PutUint32(entry.value.data, offsets[link])
+				}
+			}
+		case cIFDEXIF:
+			if entry := ifd.entry(cIFDINTEROP); entry != nil {
+				w.endian.PutUint32(entry.value.data, offsets[cIFDINTEROP])
+			}
+			if makerNote != nil {
+				ifd.entry(ExifTagMakerNote).offset = placement.offset
+				w.endian.PutUint32(ifd.entry(ExifTagOffsetSchema).value.data, uint32(placement.schema))
+			}
+		case cIFDONE:
+			if thumbnail != nil {
+				w.endian.PutUint32(ifd.entry(ExifTagJPEGInterchangeFormat).value.data, thumbnailOffset)
+				w.endian.PutUint32(ifd.entry(ExifTagJPEGInterchangeFormatLength).value.data, uint32(len(thumbnail)))
+			}
+		}
+	}
+
+	// Write the TIFF data
+	tiff := make([]byte, position)
+	if w.endian == binary.LittleEndian {
+		binary.BigEndian.PutUint16(tiff, cINTEL)
+	} else {
+		binary.BigEndian.PutUint16(tiff, cMOTOROLA)
+	}
+	w.endian.PutUint16(tiff[2:], 42)
+	w.endian.PutUint32(tiff[4:], 8)
+	for _, ifd := range layout {
+		w.endian.PutUint16(tiff[ifd.offset:], uint16(len(ifd.entries)))
+		for i, entry := range ifd.entries {
+			at := ifd.offset + 2 + 12*uint32(i)
+			w.endian.PutUint16(tiff[at:], entry.id)
+			w.endian.PutUint16(tiff[at+2:], entry.value.fieldType)
+			w.endian.PutUint32(tiff[at+4:], entry.value.count)
+			if len(entry.value.data) > 4 || (ifd.ifdType == cIFDEXIF && entry.id == ExifTagMakerNote) {
+				w.endian.PutUint32(tiff[at+8:], entry.offset)
+				copy(tiff[entry.offset:], entry.value.data)
+			} else {
+				copy(tiff[at+8:], entry.value.data)
+			}
+		}
+		if ifd1, exists := offsets[cIFDONE]; exists && ifd.ifdType == cIFDZERO {
+			w.endian.PutUint32(tiff[ifd.offset+2+12*uint32(len(ifd.entries)):], ifd1)
+		}
+	}
+	if thumbnail != nil {
+		copy(tiff[thumbnailOffset:], thumbnail)
+	}
+	if makerNote != nil {
+		copy(tiff[placement.offset:], placement.data)
+	}
+	return tiff, nil
+}
@@ -0,0 +1,128 @@
+package ImgMeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// readTestImage reads the example image, a big-endian EXIF with XMP and IPTC
+func readTestImage(t *testing.T) Image {
+	data, err := os.ReadFile("../examples/test.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	image, err := readJpegData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return image
+}
+
+func TestExifWriterEncode(t *testing.T) {
+	image := readTestImage(t)
+	original := image.apps["EXIF"].(*tEXIFAPP)
+	w, err := NewExifWriterFrom(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latitude := []Rational{{52, 1}, {22, 1}, {1234, 100}}
+	for _, tag := range []struct {
+		ifdType uint16
+		id      uint16
+		value   interface{}
+	}{
+		{IFD0, ExifTagArtist, "Jane Doe"},
+		{IFDExif, ExifTagDateTimeOriginal, "2024:05:01 10:00:00"},
+		{IFDGPS, ExifGpsTagGPSLatitudeRef, "N"},
+		{IFDGPS, ExifGpsTagGPSLatitude, latitude},
+	} {
+		if err := w.SetTag(tag.ifdType, tag.id, tag.value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	block, dropped, err := w.Encode()
+	if err != nil || len(dropped) > 0 {
+		t.Fatal(err, dropped)
+	}
+
+	jw := NewJpegWriter(image)
+	if err := jw.Replace(block); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := jw.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := readJpegData(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	exif := decoded.apps["EXIF"].(*tEXIFAPP)
+	if exif.endian != original.endian {
+		t.Errorf("byte order changed to %v", exif.endian)
+	}
+	for _, tag := range []struct {
+		ifdType uint16
+		id      uint16
+		want    interface{}
+	}{
+		{IFD0, ExifTagArtist, "Jane Doe"},
+		{IFDExif, ExifTagDateTimeOriginal, "2024:05:01 10:00:00"},
+		{IFDGPS, ExifGpsTagGPSLatitudeRef, "N"},
+	} {
+		if value, err := exif.ReadIFDValue(tag.ifdType, tag.id); err != nil || value != tag.want {
+			t.Errorf("tag 0x%04X: %v %v, want %v", tag.id, value, err, tag.want)
+		}
+	}
+	value, err := exif.ReadIFDValue(IFDGPS, ExifGpsTagGPSLatitude)
+	if want := []float64{52, 22, 12.34}; err != nil || !reflect.DeepEqual(exifFloats(value), want) {
+		t.Errorf("GPSLatitude: %v %v, want %v", value, err, want)
+	}
+	for _, id := range []uint16{ExifTagMake, ExifTagModel} {
+		before, _ := original.ReadIFDValue(IFD0, id)
+		if after, err := exif.ReadIFDValue(IFD0, id); err != nil || after != before {
+			t.Errorf("tag 0x%04X: %v %v, want %v", id, after, err, before)
+		}
+	}
+	if _, exists := exif.IFDs()[IFDGPS]; !exists {
+		t.Errorf("GPS IFD is missing")
+	}
+}
+
+func TestExifWriterFromDropsOffsets(t *testing.T) {
+	// IFD0 with Artist, a SubIFDs pointer and a private tag of type IFD
+	exif := append([]byte{0xFF, 0xE1, 0, 0}, idEXIF...)
+	exif = append(exif, 'M', 'M', 0, 42, 0, 0, 0, 8, 0, 3,
+		0x01, 0x3B, 0, 2, 0, 0, 0, 3, 'J', 'o', 0, 0,
+		0x01, 0x4A, 0, 4, 0, 0, 0, 1, 0, 0, 0, 50,
+		0xC0, 0x00, 0, 13, 0, 0, 0, 1, 0, 0, 0, 50,
+		0, 0, 0, 0)
+	binary.BigEndian.PutUint16(exif[2:], uint16(len(exif)-2))
+	jw := NewJpegWriter(readTestImage(t))
+	if err := jw.Replace(exif); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := jw.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	image, err := readJpegData(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewExifWriterFrom(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := w.ifds[IFD0][ExifTagArtist]; !exists {
+		t.Errorf("Artist was not copied")
+	}
+	for _, id := range []uint16{0x014A, 0xC000} {
+		if _, exists := w.ifds[IFD0][id]; exists {
+			t.Errorf("tag 0x%04X holds an offset and was copied", id)
+		}
+	}
+}
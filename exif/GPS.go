@@ -0,0 +1,205 @@
+package ImgMeta
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+/*
+GPS
+
+GPS decodes the EXIF GPS IFD into plain values:
+
+    Field                 Tags                                   Unit
+    Latitude, Longitude   GPSLatitude(Ref), GPSLongitude(Ref)    signed decimal degrees, north and east positive
+    Altitude              GPSAltitude(Ref)                       meters, negative below sea level
+    Time                  GPSDateStamp, GPSTimestamp             UTC
+    Speed                 GPSSpeed(Ref)                          km/h
+    Track, ImgDirection,  GPSTrack(Ref), GPSImgDirection(Ref),   degrees; the Magnetic flags are set for
+    DestBearing           GPSDestBearing(Ref)                    magnetic (M) instead of true (T) north
+    DestLatitude,         GPSDestLatitude(Ref),                  signed decimal degrees
+    DestLongitude         GPSDestLongitude(Ref)
+    DestDistance          GPSDestDistance(Ref)                   km
+    DOP                   GPSDOP
+    HPositioningError     GPSHPositioningError                   meters
+    ProcessingMethod,     GPSProcessingMethod,                   text, the character code prefix is decoded
+    AreaInformation       GPSAreaInformation
+
+Coordinates are 3 rationals (degrees, minutes, seconds); writers that store (degrees, decimal minutes) or decimal
+degrees only are read as well. Numbers that are missing or malformed (e.g. a zero denominator) are NaN.
+
+*/
+
+// GPSInfo holds the decoded GPS IFD
+type GPSInfo struct {
+	Version              string // e.g. "2.3.0.0"
+	HasPosition          bool
+	Latitude             float64
+	Longitude            float64
+	Altitude             float64
+	Time                 time.Time // zero when missing
+	Satellites           string
+	Status               string // "A" measurement in progress, "V" measurement interrupted
+	MeasureMode          int    // 2 or 3 dimensional, 0 when missing
+	DOP                  float64
+	Speed                float64
+	Track                float64
+	TrackMagnetic        bool
+	ImgDirection         float64
+	ImgDirectionMagnetic bool
+	MapDatum             string
+	DestLatitude         float64
+	DestLongitude        float64
+	DestBearing          float64
+	DestBearingMagnetic  bool
+	DestDistance         float64
+	ProcessingMethod     string
+	AreaInformation      string
+	Differential         bool
+	HPositioningError    float64
+}
+
+// aGPSSpeedUnits are the km/h per unit of GPSSpeedRef and km per unit of GPSDestDistanceRef
+var aGPSSpeedUnits = map[string]float64{
+	"K": 1,        // km/h, km
+	"M": 1.609344, // mph, miles
+	"N": 1.852,    // knots, nautical miles
+}
+
+// GPS returns the decoded GPS IFD of the image.
+// Examples:
+//
+//	gps, err := image.GPS()
+//	if err == nil && gps.HasPosition {
+//		fmt.Printf("%.6f,%.6f\n", gps.Latitude, gps.Longitude)
+//	}
+func (i Image) GPS() (GPSInfo, error) {
+	s := i.metadataSources()
+	if s.exif == nil {
+		return GPSInfo{}, &exifError{"Image does not have 'EXIF' meta section"}
+	}
+	if !s.hasGPS() {
+		return GPSInfo{}, &exifError{"Image does not have GPS information"}
+	}
+	return s.gps(), nil
+}
+
+// hasGPS returns true when the GPS IFD has a position or a time
+func (s tMetadataSources) hasGPS() bool {
+	for _, tag := range []uint16{ExifGpsTagGPSVersionID, ExifGpsTagGPSLatitude, ExifGpsTagGPSLongitude, ExifGpsTagGPSTimestamp, ExifGpsTagGPSDateStamp} {
+		if _, found := s.exifValue(IFDGPS, tag); found {
+			return true
+		}
+	}
+	return false
+}
+
+// gps decodes the GPS IFD, the tags that are missing are left empty (NaN for numbers)
+func (s tMetadataSources) gps() (info GPSInfo) {
+	if value, found := s.exifValue(IFDGPS, ExifGpsTagGPSVersionID); found {
+		parts := []string{}
+		for _, n := range exifInts(value) {
+			parts = append(parts, fmt.Sprint(n))
+		}
+		info.Version = strings.Join(parts, ".")
+	}
+
+	latitude := s.gpsCoordinate(ExifGpsTagGPSLatitude, ExifGpsTagGPSLatitudeRef, "S", 90)
+	longitude := s.gpsCoordinate(ExifGpsTagGPSLongitude, ExifGpsTagGPSLongitudeRef, "W", 180)
+	info.HasPosition = !math.IsNaN(latitude) && !math.IsNaN(longitude)
+	if info.HasPosition {
+		info.Latitude, info.Longitude = latitude, longitude
+	}
+	info.DestLatitude = s.gpsCoordinate(ExifGpsTagGPSDestLatitude, ExifGpsTagGPSDestLatitudeRef, "S", 90)
+	info.DestLongitude = s.gpsCoordinate(ExifGpsTagGPSDestLongitude, ExifGpsTagGPSDestLongitudeRef, "W", 180)
+
+	info.Altitude = s.gpsNumber(ExifGpsTagGPSAltitude)
+	if ref, _ := s.exifValue(IFDGPS, ExifGpsTagGPSAltitudeRef); len(exifInts(ref)) > 0 && exifInts(ref)[0] == 1 {
+		info.Altitude = -info.Altitude
+	}
+	info.Time, _ = s.gpsTime()
+
+	info.Satellites = s.exifString(IFDGPS, ExifGpsTagGPSSatellites)
+	info.Status = strings.ToUpper(s.exifString(IFDGPS, ExifGpsTagGPSStatus))
+	if mode := s.exifString(IFDGPS, ExifGpsTagGPSMeasureMode); mode == "2" || mode == "3" {
+		info.MeasureMode = int(mode[0] - '0')
+	}
+	info.DOP = s.gpsNumber(ExifGpsTagGPSDOP)
+	info.Speed = s.gpsNumber(ExifGpsTagGPSSpeed) * s.gpsUnit(ExifGpsTagGPSSpeedRef)
+	info.DestDistance = s.gpsNumber(ExifGpsTagGPSDestDistance) * s.gpsUnit(ExifGpsTagGPSDestDistanceRef)
+	info.Track, info.TrackMagnetic = s.gpsDirection(ExifGpsTagGPSTrack, ExifGpsTagGPSTrackRef)
+	info.ImgDirection, info.ImgDirectionMagnetic = s.gpsDirection(ExifGpsTagGPSImgDirection, ExifGpsTagGPSImgDirectionRef)
+	info.DestBearing, info.DestBearingMagnetic = s.gpsDirection(ExifGpsTagGPSDestBearing, ExifGpsTagGPSDestBearingRef)
+	info.MapDatum = s.exifString(IFDGPS, ExifGpsTagGPSMapDatum)
+	info.ProcessingMethod = s.gpsText(ExifGpsTagGPSProcessingMethod)
+	info.AreaInformation = s.gpsText(ExifGpsTagGPSAreaInformation)
+	if value, found := s.exifValue(IFDGPS, ExifGpsTagGPSDifferential); found && len(exifInts(value)) > 0 {
+		info.Differential = exifInts(value)[0] == 1
+	}
+	info.HPositioningError = s.gpsNumber(ExifGpsTagGPSHPositioningError)
+	return info
+}
+
+// gpsNumber reads a single rational, NaN when it is missing or malformed
+func (s tMetadataSources) gpsNumber(tag uint16) float64 {
+	value, found := s.exifFloat(IFDGPS, tag)
+	if !found || math.IsInf(value, 0) {
+		return math.NaN()
+	}
+	return value
+}
+
+// gpsCoordinate reads degrees, minutes and seconds as signed decimal degrees, NaN when missing or out of range
+func (s tMetadataSources) gpsCoordinate(tag uint16, refTag uint16, negative string, limit float64) float64 {
+	value, found := s.exifValue(IFDGPS, tag)
+	dms := exifFloats(value)
+	if !found || len(dms) == 0 || len(dms) > 3 {
+		return math.NaN()
+	}
+	degrees := 0.0
+	for n, part := range dms {
+		degrees += part / math.Pow(60, float64(n))
+	}
+	if math.IsNaN(degrees) || math.IsInf(degrees, 0) || degrees < 0 || degrees > limit {
+		return math.NaN()
+	}
+	if strings.EqualFold(s.exifString(IFDGPS, refTag), negative) {
+		degrees = -degrees
+	}
+	return degrees
+}
+
+// gpsDirection reads a direction in degrees and whether it is relative to magnetic north
+func (s tMetadataSources) gpsDirection(tag uint16, refTag uint16) (float64, bool) {
+	return s.gpsNumber(tag), strings.EqualFold(s.exifString(IFDGPS, refTag), "M")
+}
+
+// gpsUnit returns the factor to km/h (or km) of a speed or distance reference, "K" when missing
+func (s tMetadataSources) gpsUnit(refTag uint16) float64 {
+	ref := strings.ToUpper(s.exifString(IFDGPS, refTag))
+	if ref == "" {
+		ref = "K"
+	}
+	if factor, known := aGPSSpeedUnits[ref]; known {
+		return factor
+	}
+	return math.NaN()
+}
+
+// gpsText reads a value with a character code prefix, some writers store it as ASCII instead
+func (s tMetadataSources) gpsText(tag uint16) string {
+	value, found := s.exifValue(IFDGPS, tag)
+	if !found {
+		return ""
+	}
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		text, _, _ := DecodeCharacterCode(v, s.exif.endian)
+		return text
+	}
+	return ""
+}
@@ -0,0 +1,68 @@
+package ImgMeta
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// gpsTestSources returns the sources of an EXIF with a GPS IFD holding the coordinate and its reference, the
+// coordinate is written as is so that it can have any number of rationals
+func gpsTestSources(t *testing.T, tag uint16, refTag uint16, ref string, dms []Rational) tMetadataSources {
+	w := NewExifWriter(binary.BigEndian)
+	if dms != nil {
+		value, err := encodeExifValue(w.endian, dms)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.set(IFDGPS, tag, value)
+	}
+	if ref != "" {
+		if err := w.SetTag(IFDGPS, refTag, ref); err != nil {
+			t.Fatal(err)
+		}
+	}
+	block, _, err := w.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tMetadataSources{exif: &tEXIFAPP{block: block, endian: binary.BigEndian}}
+}
+
+func TestGPSCoordinate(t *testing.T) {
+	latitude := []Rational{{52, 1}, {22, 1}, {1234, 100}}
+	longitude := []Rational{{4, 1}, {53, 1}, {30, 1}}
+	for _, test := range []struct {
+		name string
+		tag  uint16
+		ref  string
+		dms  []Rational
+		want float64
+	}{
+		{"north", ExifGpsTagGPSLatitude, "N", latitude, 52 + 22.0/60 + 12.34/3600},
+		{"south", ExifGpsTagGPSLatitude, "S", latitude, -(52 + 22.0/60 + 12.34/3600)},
+		{"south lower case", ExifGpsTagGPSLatitude, "s", latitude, -(52 + 22.0/60 + 12.34/3600)},
+		{"no reference", ExifGpsTagGPSLatitude, "", latitude, 52 + 22.0/60 + 12.34/3600},
+		{"east", ExifGpsTagGPSLongitude, "E", longitude, 4 + 53.0/60 + 30.0/3600},
+		{"west", ExifGpsTagGPSLongitude, "W", longitude, -(4 + 53.0/60 + 30.0/3600)},
+		{"degrees", ExifGpsTagGPSLatitude, "N", []Rational{{5250, 100}}, 52.5},
+		{"degrees and minutes", ExifGpsTagGPSLatitude, "S", []Rational{{52, 1}, {3075, 100}}, -52.5125},
+		{"zero denominator", ExifGpsTagGPSLatitude, "N", []Rational{{52, 0}, {22, 1}, {0, 1}}, math.NaN()},
+		{"zero over zero", ExifGpsTagGPSLatitude, "N", []Rational{{52, 1}, {0, 0}, {0, 1}}, math.NaN()},
+		{"zero denominator seconds", ExifGpsTagGPSLongitude, "E", []Rational{{4, 1}, {53, 1}, {30, 0}}, math.NaN()},
+		{"four rationals", ExifGpsTagGPSLatitude, "N", []Rational{{52, 1}, {22, 1}, {12, 1}, {0, 1}}, math.NaN()},
+		{"latitude out of range", ExifGpsTagGPSLatitude, "N", []Rational{{90, 1}, {0, 1}, {1, 1}}, math.NaN()},
+		{"longitude at the limit", ExifGpsTagGPSLongitude, "W", []Rational{{180, 1}, {0, 1}, {0, 1}}, -180},
+		{"missing", ExifGpsTagGPSLatitude, "N", nil, math.NaN()},
+	} {
+		refTag, negative, limit := ExifGpsTagGPSLatitudeRef, "S", 90.0
+		if test.tag == ExifGpsTagGPSLongitude {
+			refTag, negative, limit = ExifGpsTagGPSLongitudeRef, "W", 180.0
+		}
+		s := gpsTestSources(t, test.tag, refTag, test.ref, test.dms)
+		got := s.gpsCoordinate(test.tag, refTag, negative, limit)
+		if math.IsNaN(test.want) != math.IsNaN(got) || math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s: %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package ImgMeta
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*
+HDR gain maps
+
+An HDR gain map JPEG is a normal SDR JPEG (the primary image) followed by a second, usually smaller, JPEG that holds
+the gain map. A viewer that understands gain maps applies it to the primary image to reconstruct the HDR rendition,
+every other viewer just shows the primary image. The gain map image is located in one of two ways:
+
+    - MPF: the APP2 "MPF" segment of the primary image lists the gain map as a secondary image (offset + size)
+    - GContainer: the primary XMP holds a Container:Directory with an Item:Semantic="GainMap" item and its
+      Item:Length, the items are appended after the primary image in the order of the directory
+
+Android Ultra HDR (and Adobe) describe the gain map with the 'hdrgm' XMP namespace, the primary image only carries
+hdrgm:Version, the gain map image carries the parameters (all but BaseRenditionIsHDR are log2 values and may be
+given once or per RGB channel as an rdf:Seq):
+
+    [Property]           [default]  [description]
+    ---------------------------------------
+    Version              -          "1.0"
+    BaseRenditionIsHDR   False      the primary image is the HDR rendition
+    GainMapMin           0.0        log2 of the minimum gain
+    GainMapMax           -          log2 of the maximum gain
+    Gamma                1.0        gamma applied to the gain map values
+    OffsetSDR            1/64       offset added to the SDR pixel values
+    OffsetHDR            1/64       offset added to the HDR pixel values
+    HDRCapacityMin       0.0        log2 of the display boost where the gain map starts to be applied
+    HDRCapacityMax       -          log2 of the display boost where the gain map is fully applied
+
+Apple marks its gain map image with apdi:AuxiliaryImageType="urn:com:apple:photo:2020:aux:hdrgainmap" and an
+'HDRGainMap' XMP namespace, the headroom is stored linear and is converted to log2 here. When the gain map does
+not carry the headroom it is derived from the HDRHeadroom and HDRGain tags of the Apple MakerNote.
+
+*/
+
+// GainMapFormat tells which convention the gain map metadata follows
+type GainMapFormat int
+
+const (
+	GainMapFormatNone     GainMapFormat = iota
+	GainMapFormatUltraHDR GainMapFormat = iota // Adobe 'hdrgm' XMP, Android Ultra HDR
+	GainMapFormatApple    GainMapFormat = iota // Apple 'HDRGainMap' auxiliary image
+)
+
+const cAppleGainMapAuxType = "urn:com:apple:photo:2020:aux:hdrgainmap"
+
+// GainMap holds the gain map image and its metadata, per channel values are
+// given as RGB; a single value in the metadata is copied to all three channels.
+type GainMap struct {
+	Format             GainMapFormat
+	Version            string
+	BaseRenditionIsHDR bool
+	GainMapMin         [3]float64
+	GainMapMax         [3]float64
+	Gamma              [3]float64
+	OffsetSDR          [3]float64
+	OffsetHDR          [3]float64
+	HDRCapacityMin     float64
+	HDRCapacityMax     float64
+	Offset             uint64 // Offset of the gain map JPEG in the file
+	Data               []byte // The gain map JPEG stream
+}
+
+// Decode decodes the gain map image
+func (g GainMap) Decode() (image.Image, error) {
+	return jpeg.Decode(bytes.NewReader(g.Data))
+}
+
+// HasGainMap returns true if the image carries an HDR gain map
+func (i Image) HasGainMap() bool {
+	_, err := i.GainMap()
+	return err == nil
+}
+
+// GainMap locates the gain map image that follows the primary image and decodes its metadata
+func (i Image) GainMap() (*GainMap, error) {
+	if mpf, ok := i.apps["MPF"].(*tMPFAPP); ok {
+		entries, images, err := i.MPImages()
+		if err == nil {
+			for n, data := range images {
+				offset := mpf.FileOffset() + uint64(entries[n].Offset)
+				if gm, ok := readGainMap(data, offset); ok {
+					return i.appleGainMapHeadroom(gm), nil
+				}
+			}
+		}
+	}
+
+	// No (usable) MPF, try the GContainer directory of the primary image
+	if xmp, ok := i.apps["XMP"].(*tXMPAPP); ok {
+		root, err := xmp.Root()
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range i.containerItems(root) {
+			if item.semantic == "GainMap" {
+				if gm, ok := readGainMap(i.data[item.begin:item.end], item.begin); ok {
+					return i.appleGainMapHeadroom(gm), nil
+				}
+			}
+		}
+	}
+	return nil, &exifError{"Image does not have a gain map"}
+}
+
+// readGainMap checks if the embedded JPEG is a gain map image and decodes its metadata
+func readGainMap(data []byte, offset uint64) (*GainMap, bool) {
+	img, _ := readJpegData(data)
+	xmp, ok := img.apps["XMP"].(*tXMPAPP)
+	if !ok {
+		return nil, false
+	}
+	root, err := xmp.Root()
+	if err != nil {
+		return nil, false
+	}
+
+	gm := &GainMap{Offset: offset, Data: data}
+	if _, ok := root.property(XmpNsHDRGainMap, "GainMapMax"); ok {
+		gm.Format = GainMapFormatUltraHDR
+		gm.Version = xmpString(root, XmpNsHDRGainMap, "Version")
+		gm.BaseRenditionIsHDR = strings.EqualFold(xmpString(root, XmpNsHDRGainMap, "BaseRenditionIsHDR"), "True")
+		gm.GainMapMin = xmpFloat3(root, XmpNsHDRGainMap, "GainMapMin", 0.0)
+		gm.GainMapMax = xmpFloat3(root, XmpNsHDRGainMap, "GainMapMax", 0.0)
+		gm.Gamma = xmpFloat3(root, XmpNsHDRGainMap, "Gamma", 1.0)
+		gm.OffsetSDR = xmpFloat3(root, XmpNsHDRGainMap, "OffsetSDR", 1.0/64.0)
+		gm.OffsetHDR = xmpFloat3(root, XmpNsHDRGainMap, "OffsetHDR", 1.0/64.0)
+		gm.HDRCapacityMin = xmpFloat3(root, XmpNsHDRGainMap, "HDRCapacityMin", 0.0)[0]
+		gm.HDRCapacityMax = xmpFloat3(root, XmpNsHDRGainMap, "HDRCapacityMax", 0.0)[0]
+		return gm, true
+	}
+
+	_, isApple := root.property(XmpNsAppleHDRGainMap, "HDRGainMapVersion")
+	if isApple || strings.Contains(xmpString(root, XmpNsApplePixelDataInfo, "AuxiliaryImageType"), "hdrgainmap") {
+		gm.Format = GainMapFormatApple
+		gm.Version = xmpString(root, XmpNsAppleHDRGainMap, "HDRGainMapVersion")
+		gm.Gamma = [3]float64{1.0, 1.0, 1.0}
+		headroom := xmpFloat3(root, XmpNsAppleHDRGainMap, "HDRGainMapHeadroom", 1.0)[0]
+		if headroom > 0.0 {
+			gm.HDRCapacityMax = math.Log2(headroom)
+			gm.GainMapMax = [3]float64{gm.HDRCapacityMax, gm.HDRCapacityMax, gm.HDRCapacityMax}
+		}
+		return gm, true
+	}
+	return nil, false
+}
+
+// appleGainMapHeadroom fills in the headroom of an Apple gain map that does not have HDRGainMapHeadroom
+// (older iOS versions) from the maker note of the primary image
+func (i Image) appleGainMapHeadroom(gm *GainMap) *GainMap {
+	if gm.Format != GainMapFormatApple || gm.HDRCapacityMax > 0.0 {
+		return gm
+	}
+	if mn, ok := i.apps["MakerNote"].(*tMakerNoteAPP); ok && mn.vendor == &cMakerNoteApple {
+		if headroom, ok := appleHeadroom(mn); ok && headroom > 1.0 {
+			gm.HDRCapacityMax = math.Log2(headroom)
+			gm.GainMapMax = [3]float64{gm.HDRCapacityMax, gm.HDRCapacityMax, gm.HDRCapacityMax}
+		}
+	}
+	return gm
+}
+
+// xmpNodeValue returns the value of a property of a struct node, given as attribute or element
+func xmpNodeValue(n *tXMPNode, space string, local string) string {
+	if value, ok := n.attr(space, local); ok {
+		return value
+	}
+	if c := n.child(space, local); c != nil {
+		return strings.TrimSpace(c.text)
+	}
+	return ""
+}
+
+func xmpString(root *tXMPNode, space string, local string) string {
+	value, ok := root.property(space, local)
+	if !ok {
+		return ""
+	}
+	switch v := value.(type) {
+	case string:
+		return v
+	case []string:
+		if len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+// xmpFloat3 reads a property that is either a single real or a sequence of 3 reals
+func xmpFloat3(root *tXMPNode, space string, local string, def float64) (values [3]float64) {
+	values = [3]float64{def, def, def}
+	value, ok := root.property(space, local)
+	if !ok {
+		return
+	}
+	items := []string{}
+	switch v := value.(type) {
+	case string:
+		items = []string{v, v, v}
+	case []string:
+		items = v
+		if len(items) == 1 {
+			items = []string{v[0], v[0], v[0]}
+		}
+	}
+	for n := 0; n < 3 && n < len(items); n++ {
+		if f, err := strconv.ParseFloat(items[n], 64); err == nil {
+			values[n] = f
+		}
+	}
+	return
+}
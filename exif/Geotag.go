@@ -0,0 +1,308 @@
+package ImgMeta
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+Geotagging
+
+Geotag sets the position of an image from a GPX 1.1 track log (<trk><trkseg><trkpt lat lon><ele/><time/>). The
+capture time of the image (see CaptureTime) is matched to the track:
+
+    ClockOffset  how far the camera clock was ahead of the GPS clock, subtracted from the capture time
+    Location     the time zone the camera clock was set to, for capture times without an offset (default Local)
+    MaxGap       the maximum time between the two track points the position is interpolated between, and the
+                 maximum time to the nearest point outside that (default 60 seconds)
+
+Positions are interpolated linearly between track points of the same segment, a new <trkseg> means the GPS lost
+its fix and no position is interpolated across it.
+
+The position is written to the EXIF GPS IFD (GPSVersionID, GPSLatitude(Ref), GPSLongitude(Ref), GPSAltitude(Ref),
+GPSTimestamp, GPSDateStamp, GPSMapDatum) and to the XMP exif:GPS* properties. The image data is not touched.
+
+*/
+
+// GPXPoint is a point of a track log
+type GPXPoint struct {
+	Time         time.Time
+	Latitude     float64
+	Longitude    float64
+	Elevation    float64
+	HasElevation bool
+	segment      int
+}
+
+// GPXTrack holds the points of all tracks and segments of a GPX file, in time order
+type GPXTrack struct {
+	Points []GPXPoint
+}
+
+// GeotagOptions control how capture times are matched to the track
+type GeotagOptions struct {
+	ClockOffset time.Duration  // camera clock minus GPS clock
+	Location    *time.Location // time zone of the camera clock, nil for Local
+	MaxGap      time.Duration  // 0 for 60 seconds
+	Overwrite   bool           // replace a position the image already has
+}
+
+const cGeotagMaxGap = 60 * time.Second
+
+// tGPSTag is a GPS IFD tag to write
+type tGPSTag struct {
+	tag   uint16
+	value interface{}
+}
+
+// tGPX is the part of a GPX file that is read, the elements are matched by their local name so GPX 1.0 reads too
+type tGPX struct {
+	Tracks []struct {
+		Segments []struct {
+			Points []struct {
+				Latitude  float64  `xml:"lat,attr"`
+				Longitude float64  `xml:"lon,attr"`
+				Elevation *float64 `xml:"ele"`
+				Time      string   `xml:"time"`
+			} `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+}
+
+// ReadGPX reads the track points of a GPX file, points without a time are skipped
+func ReadGPX(r io.Reader) (*GPXTrack, error) {
+	var gpx tGPX
+	if err := xml.NewDecoder(r).Decode(&gpx); err != nil {
+		return nil, &exifError{"GPX is malformed: " + err.Error()}
+	}
+	track := &GPXTrack{}
+	segment := 0
+	for _, trk := range gpx.Tracks {
+		for _, seg := range trk.Segments {
+			segment++
+			for _, p := range seg.Points {
+				t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(p.Time))
+				if err != nil || math.Abs(p.Latitude) > 90 || math.Abs(p.Longitude) > 180 {
+					continue
+				}
+				point := GPXPoint{Time: t, Latitude: p.Latitude, Longitude: p.Longitude, segment: segment}
+				if p.Elevation != nil {
+					point.Elevation, point.HasElevation = *p.Elevation, true
+				}
+				track.Points = append(track.Points, point)
+			}
+		}
+	}
+	if len(track.Points) == 0 {
+		return nil, &exifError{"GPX has no track points with a time"}
+	}
+	sort.SliceStable(track.Points, func(a, b int) bool { return track.Points[a].Time.Before(track.Points[b].Time) })
+	return track, nil
+}
+
+// ReadGPXFile reads the track points of a GPX file
+func ReadGPXFile(path string) (*GPXTrack, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadGPX(f)
+}
+
+// Position returns the position at a time, interpolated between the surrounding points when they are at most
+// maxGap apart, or else the nearest point when it is at most maxGap away
+func (t *GPXTrack) Position(at time.Time, maxGap time.Duration) (GPXPoint, bool) {
+	points := t.Points
+	n := sort.Search(len(points), func(n int) bool { return !points[n].Time.Before(at) })
+	if n < len(points) && points[n].Time.Equal(at) {
+		return points[n], true
+	}
+	if n > 0 && n < len(points) {
+		before, after := points[n-1], points[n]
+		if before.segment == after.segment && after.Time.Sub(before.Time) <= maxGap {
+			return interpolate(before, after, at), true
+		}
+	}
+	nearest := -1
+	if n > 0 && at.Sub(points[n-1].Time) <= maxGap {
+		nearest = n - 1
+	}
+	if n < len(points) && points[n].Time.Sub(at) <= maxGap && (nearest < 0 || points[n].Time.Sub(at) < at.Sub(points[nearest].Time)) {
+		nearest = n
+	}
+	if nearest < 0 {
+		return GPXPoint{}, false
+	}
+	point := points[nearest]
+	point.Time = at
+	return point, true
+}
+
+// interpolate returns the point at a time between two points, across the antimeridian when that is shorter
+func interpolate(a GPXPoint, b GPXPoint, at time.Time) GPXPoint {
+	f := float64(at.Sub(a.Time)) / float64(b.Time.Sub(a.Time))
+	longitude := b.Longitude
+	if longitude-a.Longitude > 180 {
+		longitude -= 360
+	} else if a.Longitude-longitude > 180 {
+		longitude += 360
+	}
+	point := GPXPoint{Time: at, segment: a.segment}
+	point.Latitude = a.Latitude + f*(b.Latitude-a.Latitude)
+	point.Longitude = math.Remainder(a.Longitude+f*(longitude-a.Longitude), 360)
+	if a.HasElevation && b.HasElevation {
+		point.Elevation, point.HasElevation = a.Elevation+f*(b.Elevation-a.Elevation), true
+	}
+	return point
+}
+
+// Geotag sets the position of the image from the track, it returns the position that was written
+func (m *Metadata) Geotag(track *GPXTrack, options GeotagOptions) (GPXPoint, error) {
+	if !options.Overwrite {
+		if gps, err := m.image.GPS(); err == nil && gps.HasPosition {
+			return GPXPoint{}, &exifError{"Image already has a GPS position"}
+		}
+	}
+	capture := m.image.CaptureTime()
+	at := capture.Time
+	switch capture.Confidence {
+	case CaptureConfidenceNone, CaptureConfidenceDateOnly:
+		return GPXPoint{}, &exifError{"Image does not have a capture time"}
+	case CaptureConfidenceLocal:
+		location := options.Location
+		if location == nil {
+			location = time.Local
+		}
+		at = time.Date(at.Year(), at.Month(), at.Day(), at.Hour(), at.Minute(), at.Second(), at.Nanosecond(), location)
+	}
+	at = at.Add(-options.ClockOffset)
+	maxGap := options.MaxGap
+	if maxGap <= 0 {
+		maxGap = cGeotagMaxGap
+	}
+	point, found := track.Position(at, maxGap)
+	if !found {
+		return GPXPoint{}, &exifError{fmt.Sprintf("Track has no position at %s", at.UTC().Format(time.RFC3339))}
+	}
+	if err := m.SetGPSPosition(point); err != nil {
+		return GPXPoint{}, err
+	}
+	return point, nil
+}
+
+// SetGPSPosition writes a position, with its elevation and time, to the EXIF GPS IFD and the XMP
+func (m *Metadata) SetGPSPosition(point GPXPoint) error {
+	exif, err := m.Exif()
+	if err != nil {
+		return err
+	}
+	delete(exif.ifds, IFDGPS)
+	utc := point.Time.UTC()
+	tags := []tGPSTag{
+		{ExifGpsTagGPSVersionID, []byte{2, 3, 0, 0}},
+		{ExifGpsTagGPSLatitudeRef, hemisphere(point.Latitude, "N", "S")},
+		{ExifGpsTagGPSLatitude, dmsRationals(point.Latitude)},
+		{ExifGpsTagGPSLongitudeRef, hemisphere(point.Longitude, "E", "W")},
+		{ExifGpsTagGPSLongitude, dmsRationals(point.Longitude)},
+		{ExifGpsTagGPSMapDatum, "WGS-84"},
+	}
+	if !utc.IsZero() {
+		seconds := float64(utc.Second()) + float64(utc.Nanosecond())/1e9
+		tags = append(tags,
+			tGPSTag{ExifGpsTagGPSTimestamp, []Rational{{uint32(utc.Hour()), 1}, {uint32(utc.Minute()), 1}, {uint32(math.Round(seconds * 1000)), 1000}}},
+			tGPSTag{ExifGpsTagGPSDateStamp, utc.Format("2006:01:02")})
+	}
+	if point.HasElevation {
+		ref := uint8(0)
+		if point.Elevation < 0 {
+			ref = 1
+		}
+		tags = append(tags,
+			tGPSTag{ExifGpsTagGPSAltitudeRef, ref},
+			tGPSTag{ExifGpsTagGPSAltitude, Rational{uint32(math.Round(math.Abs(point.Elevation) * 100)), 100}})
+	}
+	for _, t := range tags {
+		if err := exif.SetTag(IFDGPS, t.tag, t.value); err != nil {
+			return err
+		}
+	}
+
+	xmp, err := m.XMP()
+	if err != nil {
+		return err
+	}
+	gps := tStripXMPProperty{XmpNsEXIF, "GPS*", cStripGPS}
+	properties := xmp.properties[:0:0]
+	for _, p := range xmp.properties {
+		if !gps.matches(p) {
+			properties = append(properties, p)
+		}
+	}
+	xmp.properties = properties
+	values := map[string]interface{}{
+		"GPSVersionID": "2.3.0.0",
+		"GPSLatitude":  xmpCoordinate(point.Latitude, "N", "S"),
+		"GPSLongitude": xmpCoordinate(point.Longitude, "E", "W"),
+		"GPSMapDatum":  "WGS-84",
+	}
+	if !utc.IsZero() {
+		values["GPSTimeStamp"] = utc
+	}
+	if point.HasElevation {
+		values["GPSAltitudeRef"] = "0"
+		if point.Elevation < 0 {
+			values["GPSAltitudeRef"] = "1"
+		}
+		values["GPSAltitude"] = fmt.Sprintf("%d/100", int64(math.Round(math.Abs(point.Elevation)*100)))
+	}
+	for _, name := range []string{"GPSVersionID", "GPSLatitude", "GPSLongitude", "GPSAltitudeRef", "GPSAltitude", "GPSTimeStamp", "GPSMapDatum"} {
+		if value, exists := values[name]; exists {
+			if err := xmp.Set(XmpNsEXIF, name, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GeotagFile geotags a JPEG file in place, see UpdateFile
+func GeotagFile(path string, track *GPXTrack, options GeotagOptions) (point GPXPoint, err error) {
+	err = UpdateFile(path, func(m *Metadata) error {
+		point, err = m.Geotag(track, options)
+		return err
+	})
+	return point, err
+}
+
+func hemisphere(degrees float64, positive string, negative string) string {
+	if degrees < 0 {
+		return negative
+	}
+	return positive
+}
+
+// dmsRationals returns degrees, minutes and seconds (to 1/10000 second) of a coordinate
+func dmsRationals(degrees float64) []Rational {
+	seconds := math.Round(math.Abs(degrees) * 3600 * 10000)
+	whole := uint32(seconds / (3600 * 10000))
+	seconds -= float64(whole) * 3600 * 10000
+	minutes := uint32(seconds / (60 * 10000))
+	seconds -= float64(minutes) * 60 * 10000
+	return []Rational{{whole, 1}, {minutes, 1}, {uint32(seconds), 10000}}
+}
+
+// xmpCoordinate formats a coordinate as XMP "DDD,MM.mmmmmmK", the minutes are rounded before they are split off
+// so that they never read 60
+func xmpCoordinate(degrees float64, positive string, negative string) string {
+	microMinutes := math.Round(math.Abs(degrees) * 60 * 1000000)
+	whole := math.Floor(microMinutes / (60 * 1000000))
+	minutes := (microMinutes - whole*60*1000000) / 1000000
+	return fmt.Sprintf("%d,%.6f%s", int(whole), minutes, hemisphere(degrees, positive, negative))
+}
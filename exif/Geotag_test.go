@@ -0,0 +1,126 @@
+package ImgMeta
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// cTestGPX has two segments, the second starts 30 seconds after the first ends and has a 200 second gap
+const cTestGPX = `<?xml version="1.0"?>
+<gpx version="1.1" xmlns="http://www.topografix.com/GPX/1/1">
+  <trk>
+    <trkseg>
+      <trkpt lat="52.0" lon="4.0"><ele>10</ele><time>2024-05-01T10:00:00Z</time></trkpt>
+      <trkpt lat="52.1" lon="4.2"><ele>20</ele><time>2024-05-01T10:00:40Z</time></trkpt>
+    </trkseg>
+    <trkseg>
+      <trkpt lat="53.0" lon="5.0"><time>2024-05-01T10:01:10Z</time></trkpt>
+      <trkpt lat="53.2" lon="5.2"><time>2024-05-01T10:04:30Z</time></trkpt>
+    </trkseg>
+  </trk>
+</gpx>`
+
+func TestGPXTrackPosition(t *testing.T) {
+	track, err := ReadGPX(strings.NewReader(cTestGPX))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		name      string
+		offset    time.Duration
+		found     bool
+		latitude  float64
+		longitude float64
+	}{
+		{"first point", 0, true, 52.0, 4.0},
+		{"interpolated", 10 * time.Second, true, 52.025, 4.05},
+		{"segment break, nearest is the end of the first segment", 50 * time.Second, true, 52.1, 4.2},
+		{"segment break, nearest is the start of the second segment", 65 * time.Second, true, 53.0, 5.0},
+		{"gap, nearest is before", 130 * time.Second, true, 53.0, 5.0},
+		{"gap, nearest is after", 240 * time.Second, true, 53.2, 5.2},
+		{"gap, no point within the max gap", 170 * time.Second, false, 0, 0},
+		{"before the track", -60 * time.Second, true, 52.0, 4.0},
+		{"long before the track", -61 * time.Second, false, 0, 0},
+		{"after the track", 330 * time.Second, true, 53.2, 5.2},
+		{"long after the track", 331 * time.Second, false, 0, 0},
+	} {
+		at := start.Add(test.offset)
+		point, found := track.Position(at, cGeotagMaxGap)
+		if found != test.found {
+			t.Errorf("%s: found %v", test.name, found)
+			continue
+		}
+		if !found {
+			continue
+		}
+		if !point.Time.Equal(at) || math.Abs(point.Latitude-test.latitude) > 1e-9 || math.Abs(point.Longitude-test.longitude) > 1e-9 {
+			t.Errorf("%s: %v %v,%v, want %v,%v", test.name, point.Time, point.Latitude, point.Longitude, test.latitude, test.longitude)
+		}
+	}
+	if point, _ := track.Position(start.Add(10*time.Second), cGeotagMaxGap); !point.HasElevation || math.Abs(point.Elevation-12.5) > 1e-9 {
+		t.Errorf("elevation %v %v, want 12.5", point.Elevation, point.HasElevation)
+	}
+	if point, _ := track.Position(start.Add(20*time.Second), 10*time.Second); point.Latitude == 52.05 {
+		t.Errorf("interpolated between points further apart than the max gap")
+	}
+}
+
+func TestInterpolateAntimeridian(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		a, b float64
+		f    float64
+		want float64
+	}{
+		{179, -179, 0.25, 179.5},
+		{179, -179, 0.75, -179.5},
+		{-179, 179, 0.25, -179.5},
+		{-179, 179, 0.75, 179.5},
+		{-10, 10, 0.5, 0},
+	} {
+		a := GPXPoint{Time: start, Longitude: test.a}
+		b := GPXPoint{Time: start.Add(100 * time.Second), Longitude: test.b}
+		point := interpolate(a, b, start.Add(time.Duration(test.f*100)*time.Second))
+		if math.Abs(point.Longitude-test.want) > 1e-9 {
+			t.Errorf("%v to %v at %v: %v, want %v", test.a, test.b, test.f, point.Longitude, test.want)
+		}
+	}
+}
+
+func TestDMSRationals(t *testing.T) {
+	for _, test := range []struct {
+		degrees float64
+		want    []Rational
+	}{
+		{52.5, []Rational{{52, 1}, {30, 1}, {0, 10000}}},
+		{-4.5, []Rational{{4, 1}, {30, 1}, {0, 10000}}},
+		{52 + 22.0/60 + 12.34/3600, []Rational{{52, 1}, {22, 1}, {123400, 10000}}},
+		{52.99999999999, []Rational{{53, 1}, {0, 1}, {0, 10000}}},
+		{0, []Rational{{0, 1}, {0, 1}, {0, 10000}}},
+	} {
+		if got := dmsRationals(test.degrees); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: %v, want %v", test.degrees, got, test.want)
+		}
+	}
+}
+
+func TestXMPCoordinate(t *testing.T) {
+	for _, test := range []struct {
+		degrees float64
+		want    string
+	}{
+		{52.5, "52,30.000000N"},
+		{-4.5, "4,30.000000S"},
+		{52.99999999999, "53,0.000000N"},
+		{-179.9999999999, "180,0.000000S"},
+		{0.25, "0,15.000000N"},
+	} {
+		if got := xmpCoordinate(test.degrees, "N", "S"); got != test.want {
+			t.Errorf("%v: %s, want %s", test.degrees, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package ImgMeta
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+/*
+Google camera XMP
+
+Pixel (and other Android) cameras describe their extras in XMP, the larger parts are usually in the extended packet:
+
+    [Namespace]  [Property]                          [description]
+    ---------------------------------------
+    GCamera      MotionPhoto                         1 if a video is appended to the image
+                 MotionPhotoVersion                  1
+                 MotionPhotoPresentationTimestampUs  time of the still in the video, -1 if unknown
+                 MicroVideo, MicroVideoVersion       older form of MotionPhoto
+                 MicroVideoOffset                    older form, offset of the video from the end of the file
+                 MicroVideoPresentationTimestampUs   older form
+                 SpecialTypeID                       e.g. "com.google.android.apps.camera.gallery.specialtype.SpecialType-PORTRAIT"
+                 BurstID, BurstPrimary               burst grouping
+    GImage       Mime, Data                          the original image (before the portrait blur), base64
+    GDepth       Format, Near, Far, Units,           the depth map, base64
+                 MeasureType, Mime, Data
+    Container    Directory                           media items appended to the file, in order, the first item
+                                                     is the primary image; Item:Semantic is "Primary",
+                                                     "MotionPhoto", "GainMap", "Depth", ...; Item:Length is the
+                                                     size of the item
+
+*/
+
+// Google XMP namespaces
+const (
+	XmpNsGCamera = "http://ns.google.com/photos/1.0/camera/"
+	XmpNsGImage  = "http://ns.google.com/photos/1.0/image/"
+	XmpNsGDepth  = "http://ns.google.com/photos/1.0/depthmap/"
+)
+
+// GooglePhoto holds the Google camera XMP of an image
+type GooglePhoto struct {
+	MotionPhoto             bool
+	MotionPhotoVersion      int
+	PresentationTimestampUs int64
+	VideoOffset             uint64 // offset of the video in the file, 0 if there is none
+	VideoLength             uint64
+	SpecialTypeID           string
+	BurstID                 string
+	BurstPrimary            bool
+	ImageMime               string
+	ImageData               []byte
+	DepthFormat             string
+	DepthNear               float64
+	DepthFar                float64
+	DepthUnits              string
+	DepthMeasureType        string
+	DepthMime               string
+	DepthData               []byte
+}
+
+// tContainerItem is a media item of a GContainer directory, located in the file
+type tContainerItem struct {
+	semantic string
+	mime     string
+	begin    uint64
+	end      uint64
+}
+
+// containerItems locates the media items of the GContainer directory, the items that follow the primary
+// image are appended to the file in directory order so they are located from the end of the file.
+func (i Image) containerItems(root *tXMPNode) (items []tContainerItem) {
+	nodes := []*tXMPNode{}
+	for _, directory := range root.findAll(XmpNsContainer, "Directory") {
+		nodes = append(nodes, directory.findAll(XmpNsContainer, "Item")...)
+	}
+	end := uint64(len(i.data))
+	for n := len(nodes) - 1; n > 0; n-- {
+		length, err := strconv.ParseUint(xmpNodeValue(nodes[n], XmpNsContainerItem, "Length"), 10, 64)
+		if err != nil || length > end {
+			break
+		}
+		item := tContainerItem{
+			semantic: xmpNodeValue(nodes[n], XmpNsContainerItem, "Semantic"),
+			mime:     xmpNodeValue(nodes[n], XmpNsContainerItem, "Mime"),
+			begin:    end - length,
+			end:      end,
+		}
+		items = append([]tContainerItem{item}, items...)
+		end = item.begin
+	}
+	return items
+}
+
+// GooglePhoto decodes the Google camera XMP of the image
+func (i Image) GooglePhoto() (*GooglePhoto, error) {
+	root, err := i.xmpRoot()
+	if err != nil {
+		return nil, err
+	}
+	gp := &GooglePhoto{PresentationTimestampUs: -1}
+	if _, ok := root.property(XmpNsGCamera, "MotionPhoto"); ok {
+		gp.MotionPhoto = xmpString(root, XmpNsGCamera, "MotionPhoto") == "1"
+		gp.MotionPhotoVersion, _ = strconv.Atoi(xmpString(root, XmpNsGCamera, "MotionPhotoVersion"))
+		if ts, err := strconv.ParseInt(xmpString(root, XmpNsGCamera, "MotionPhotoPresentationTimestampUs"), 10, 64); err == nil {
+			gp.PresentationTimestampUs = ts
+		}
+		for _, item := range i.containerItems(root) {
+			if item.semantic == "MotionPhoto" {
+				gp.VideoOffset, gp.VideoLength = item.begin, item.end-item.begin
+			}
+		}
+	} else if xmpString(root, XmpNsGCamera, "MicroVideo") == "1" {
+		gp.MotionPhoto = true
+		if ts, err := strconv.ParseInt(xmpString(root, XmpNsGCamera, "MicroVideoPresentationTimestampUs"), 10, 64); err == nil {
+			gp.PresentationTimestampUs = ts
+		}
+		if offset, err := strconv.ParseUint(xmpString(root, XmpNsGCamera, "MicroVideoOffset"), 10, 64); err == nil && offset <= uint64(len(i.data)) {
+			gp.VideoOffset, gp.VideoLength = uint64(len(i.data))-offset, offset
+		}
+	}
+	gp.SpecialTypeID = xmpString(root, XmpNsGCamera, "SpecialTypeID")
+	gp.BurstID = xmpString(root, XmpNsGCamera, "BurstID")
+	gp.BurstPrimary = xmpString(root, XmpNsGCamera, "BurstPrimary") == "1"
+
+	gp.ImageMime = xmpString(root, XmpNsGImage, "Mime")
+	gp.ImageData = xmpBase64(root, XmpNsGImage, "Data")
+	gp.DepthFormat = xmpString(root, XmpNsGDepth, "Format")
+	gp.DepthNear, _ = strconv.ParseFloat(xmpString(root, XmpNsGDepth, "Near"), 64)
+	gp.DepthFar, _ = strconv.ParseFloat(xmpString(root, XmpNsGDepth, "Far"), 64)
+	gp.DepthUnits = xmpString(root, XmpNsGDepth, "Units")
+	gp.DepthMeasureType = xmpString(root, XmpNsGDepth, "MeasureType")
+	gp.DepthMime = xmpString(root, XmpNsGDepth, "Mime")
+	gp.DepthData = xmpBase64(root, XmpNsGDepth, "Data")
+	return gp, nil
+}
+
+// IsMotionPhoto returns true when a video is appended to the image (Google MotionPhoto or MicroVideo)
+func (i Image) IsMotionPhoto() bool {
+	gp, err := i.GooglePhoto()
+	return err == nil && gp.MotionPhoto && gp.VideoLength > 0
+}
+
+// MotionPhotoVideo returns the video (MP4) that is appended to a motion photo
+func (i Image) MotionPhotoVideo() ([]byte, error) {
+	gp, err := i.GooglePhoto()
+	if err != nil {
+		return nil, err
+	}
+	if !gp.MotionPhoto || gp.VideoLength == 0 {
+		return nil, &exifError{"Image is not a motion photo"}
+	}
+	return i.data[gp.VideoOffset : gp.VideoOffset+gp.VideoLength], nil
+}
+
+// xmpBase64 decodes a base64 encoded property, whitespace (line breaks) in the value is ignored
+func xmpBase64(root *tXMPNode, space string, local string) []byte {
+	value := strings.Join(strings.Fields(xmpString(root, space, local)), "")
+	if value == "" {
+		return nil
+	}
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil
+	}
+	return data
+}
@@ -89,6 +89,9 @@ func (t tIPTCAPP) ID(cid []byte) (id []byte) {
 	return
 }
 func (t tIPTCAPP) HasID(cid []byte) bool {
+	if len(t.block) < 4+len(cid) {
+		return false
+	}
 	id := t.block[4 : 4+len(cid)]
 	for i, b := range id {
 		if b != cid[i] {
@@ -0,0 +1,450 @@
+package ImgMeta
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"time"
+)
+
+/*
+Writing IPTC
+
+The IPTCWriter holds the IPTC datasets and the other Photoshop resources of the APP13 segment(s). The datasets are
+written into the 8BIM 0x0404 resource, the other resources are copied byte for byte in their original order. The
+0x0425 resource holds the MD5 digest of the 0x0404 data, Photoshop uses it to detect IPTC that was changed by other
+programs; it is updated (or added) whenever the IPTC is written. Metadata that changes the IPTC but not the XMP
+keeps the old digest, so readers that follow the MWG guidelines see that the IPTC is newer than the XMP.
+
+    [Resource]  [description]
+    ---------------------------------------
+    0x0404      IPTC-NAA record, the datasets
+    0x0425      MD5 digest of the IPTC-NAA record
+
+Datasets are written in record order, with the record version (x:00) first in its record, a dataset with more than
+32767 bytes of data uses the extended form:
+
+    [Record name]    [size]   [description]
+    ---------------------------------------
+    (Tag marker)     1 byte   0x1c
+    (Record number)  1 byte
+    (Dataset number) 1 byte
+    (Size specifier) 2 bytes  0x8004, the data length follows in 4 bytes
+    (Data length)    4 bytes
+    (Data)            ...
+
+Values are validated against aIPTCFields (minimum and maximum size, repeatability); string values that are not plain
+ASCII are written as UTF-8 and announced with the CodedCharacterSet dataset (1:90, "ESC % G").
+
+A segment holds at most 65519 bytes of resources, larger resource data is continued in the next APP13 segment.
+
+*/
+
+// Photoshop image resources
+const (
+	PhotoshopResourceIPTC       uint16 = 0x0404
+	PhotoshopResourceIPTCDigest uint16 = 0x0425
+)
+
+// cIPTCMaxResourceSize is the maximum size of the resources in an APP13 segment
+const cIPTCMaxResourceSize = 0xFFFF - 2 - 14
+
+// cIPTCUTF8 is the value of the CodedCharacterSet dataset that announces UTF-8
+var cIPTCUTF8 = []byte{0x1B, '%', 'G'}
+
+// tIPTCDataset is a dataset, tag holds the record and dataset number like the keys of aIPTCFields
+type tIPTCDataset struct {
+	tag  uint16
+	data []byte
+}
+
+// tPhotoshopResource is a resource block of an APP13 segment
+type tPhotoshopResource struct {
+	id    uint16
+	block []byte // the full resource block, including padding
+}
+
+// IPTCWriter builds the APP13 segment(s) holding the IPTC datasets
+type IPTCWriter struct {
+	datasets   []tIPTCDataset
+	resources  []tPhotoshopResource
+	keepDigest bool // keep the existing digest, the XMP was not brought in sync
+}
+
+// NewIPTCWriter returns an empty IPTC writer
+func NewIPTCWriter() *IPTCWriter {
+	return &IPTCWriter{}
+}
+
+// NewIPTCWriterFrom returns an IPTC writer holding the datasets and Photoshop resources of the image
+func NewIPTCWriterFrom(image Image) (*IPTCWriter, error) {
+	w := NewIPTCWriter()
+	resources := []byte{}
+	for _, segment := range image.segments {
+		if app, ok := segment.app.(*tIPTCAPP); ok && app.HasID(idIPTC) {
+			resources = append(resources, segment.block[4+len(idIPTC):]...)
+		}
+	}
+	for len(resources) > 0 {
+		resource, size, err := readPhotoshopResource(resources)
+		if err != nil {
+			return w, err
+		}
+		resources = resources[size:]
+		if resource.id == PhotoshopResourceIPTC {
+			datasets, err := readIPTCDatasets(photoshopResourceData(resource.block))
+			if err != nil {
+				return w, err
+			}
+			w.datasets = append(w.datasets, datasets...)
+		}
+		w.resources = append(w.resources, resource)
+	}
+	return w, nil
+}
+
+// readPhotoshopResource reads the resource block at the start of data, it returns the block and its padded size
+func readPhotoshopResource(data []byte) (tPhotoshopResource, int, error) {
+	header := tIPTCHeader{block: data, endian: binary.BigEndian}
+	if len(data) < 12 || !(bytes.HasPrefix(data, []byte("8BIM")) || bytes.HasPrefix(data, []byte("8BPS")) || bytes.HasPrefix(data, []byte("PHUT"))) {
+		return tPhotoshopResource{}, 0, &exifError{"APP13 resource block has an unknown signature"}
+	}
+	offset := uint64(4 + 2 + 1 + (header.NameLen() | 1))
+	if offset+4 > uint64(len(data)) {
+		return tPhotoshopResource{}, 0, &exifError{"APP13 resource block is truncated"}
+	}
+	size := offset + 4 + uint64(header.RecordSize())
+	size = (size + 1) &^ 1
+	if size > uint64(len(data)) {
+		// The padding byte is sometimes left out of the last resource
+		if size-1 != uint64(len(data)) {
+			return tPhotoshopResource{}, 0, &exifError{"APP13 resource block is truncated"}
+		}
+		size--
+	}
+	resource := tPhotoshopResource{id: binary.BigEndian.Uint16(data[4:]), block: append([]byte{}, data[:size]...)}
+	return resource, int(size), nil
+}
+
+// photoshopResourceData returns the data of a resource block
+func photoshopResourceData(block []byte) []byte {
+	header := tIPTCHeader{block: block, endian: binary.BigEndian}
+	offset := 4 + 2 + 1 + (header.NameLen() | 1) + 4
+	return block[offset : offset+header.RecordSize()]
+}
+
+// newPhotoshopResource builds a 8BIM resource block without a name
+func newPhotoshopResource(id uint16, data []byte) tPhotoshopResource {
+	block := make([]byte, 12, 12+len(data)+1)
+	copy(block, "8BIM")
+	binary.BigEndian.PutUint16(block[4:], id)
+	binary.BigEndian.PutUint32(block[8:], uint32(len(data)))
+	block = append(block, data...)
+	if len(block)&1 == 1 {
+		block = append(block, 0)
+	}
+	return tPhotoshopResource{id: id, block: block}
+}
+
+// readIPTCDatasets reads the datasets of an IPTC-NAA record, including datasets with an extended length
+func readIPTCDatasets(data []byte) ([]tIPTCDataset, error) {
+	datasets := []tIPTCDataset{}
+	for cursor := 0; cursor < len(data); {
+		if data[cursor] != 0x1C {
+			// Padding at the end of the record
+			break
+		}
+		if cursor+5 > len(data) {
+			return datasets, &exifError{"IPTC dataset header is truncated"}
+		}
+		tag := uint16(data[cursor+1])<<8 | uint16(data[cursor+2])
+		size := uint64(binary.BigEndian.Uint16(data[cursor+3:]))
+		cursor += 5
+		if size&0x8000 != 0 {
+			// Extended dataset, the size is stored in the next n bytes
+			n := int(size & 0x7FFF)
+			if n == 0 || n > 4 || cursor+n > len(data) {
+				return datasets, &exifError{"IPTC extended dataset length is invalid"}
+			}
+			size = 0
+			for _, b := range data[cursor : cursor+n] {
+				size = size<<8 | uint64(b)
+			}
+			cursor += n
+		}
+		if size > uint64(len(data)-cursor) {
+			return datasets, &exifError{fmt.Sprintf("IPTC dataset %d:%d is out of bounds", tag>>8, tag&0xFF)}
+		}
+		datasets = append(datasets, tIPTCDataset{tag: tag, data: append([]byte{}, data[cursor:cursor+int(size)]...)})
+		cursor += int(size)
+	}
+	return datasets, nil
+}
+
+// encodeIPTCValue encodes and validates a dataset value
+func encodeIPTCValue(tag uint16, value interface{}) ([]byte, error) {
+	field, known := aIPTCFields[tag]
+	if !known {
+		return nil, &exifError{fmt.Sprintf("IPTC dataset %d:%d is not listed in our embedded map", tag>>8, tag&0xFF)}
+	}
+	var data []byte
+	switch field.fieldTypeID {
+	case IptcFieldTypeShort:
+		var v uint16
+		switch n := value.(type) {
+		case uint16:
+			v = n
+		case int:
+			if n < 0 || n > 0xFFFF {
+				return nil, &exifError{fmt.Sprintf("%s: value %d is out of range", field.tagTypeID, n)}
+			}
+			v = uint16(n)
+		default:
+			return nil, &exifError{fmt.Sprintf("%s does not take a %T", field.tagTypeID, value)}
+		}
+		data = []byte{byte(v >> 8), byte(v)}
+	case IptcFieldTypeDate:
+		switch v := value.(type) {
+		case time.Time:
+			data = []byte(v.Format("20060102"))
+		case string:
+			if _, err := time.Parse("20060102", v); err != nil {
+				return nil, &exifError{fmt.Sprintf("%s: '%s' is not a CCYYMMDD date", field.tagTypeID, v)}
+			}
+			data = []byte(v)
+		default:
+			return nil, &exifError{fmt.Sprintf("%s does not take a %T", field.tagTypeID, value)}
+		}
+	case IptcFieldTypeTime:
+		switch v := value.(type) {
+		case time.Time:
+			data = []byte(v.Format("150405-0700"))
+		case string:
+			// the offset is mandatory in IIM, times without one are written by many programs and read everywhere
+			_, err := time.Parse("150405-0700", v)
+			if _, local := time.Parse("150405", v); err != nil && local != nil {
+				return nil, &exifError{fmt.Sprintf("%s: '%s' is not a HHMMSS+HHMM or HHMMSS time", field.tagTypeID, v)}
+			}
+			data = []byte(v)
+		default:
+			return nil, &exifError{fmt.Sprintf("%s does not take a %T", field.tagTypeID, value)}
+		}
+	case IptcFieldTypeString:
+		v, ok := value.(string)
+		if !ok {
+			return nil, &exifError{fmt.Sprintf("%s does not take a %T", field.tagTypeID, value)}
+		}
+		data = []byte(v)
+	default:
+		v, ok := value.([]byte)
+		if !ok {
+			return nil, &exifError{fmt.Sprintf("%s does not take a %T", field.tagTypeID, value)}
+		}
+		data = append([]byte{}, v...)
+	}
+	if len(data) < field.minSizeInBytes || len(data) > field.maxSizeInBytes {
+		return nil, &exifError{fmt.Sprintf("%s takes %d to %d bytes, not %d", field.tagTypeID, field.minSizeInBytes, field.maxSizeInBytes, len(data))}
+	}
+	return data, nil
+}
+
+// decodeIPTCValue decodes a dataset value, shorts as uint16, dates and times as string and unknown data as []byte
+func decodeIPTCValue(dataset tIPTCDataset) interface{} {
+	field, known := aIPTCFields[dataset.tag]
+	if !known {
+		return append([]byte{}, dataset.data...)
+	}
+	switch field.fieldTypeID {
+	case IptcFieldTypeShort:
+		if len(dataset.data) == 2 {
+			return binary.BigEndian.Uint16(dataset.data)
+		}
+	case IptcFieldTypeString, IptcFieldTypeDate, IptcFieldTypeTime:
+		return string(dataset.data)
+	}
+	return append([]byte{}, dataset.data...)
+}
+
+// Set sets the value(s) of a dataset, replacing the current values; more than one value is only allowed for
+// repeatable datasets. Values are given as string, uint16 (or int), time.Time or string for dates and times,
+// or []byte for binary datasets.
+func (w *IPTCWriter) Set(tag uint16, values ...interface{}) error {
+	field, known := aIPTCFields[tag]
+	if known && len(values) > 1 && !field.isRepeatable {
+		return &exifError{fmt.Sprintf("%s is not repeatable", field.tagTypeID)}
+	}
+	encoded := make([]tIPTCDataset, 0, len(values))
+	for _, value := range values {
+		data, err := encodeIPTCValue(tag, value)
+		if err != nil {
+			return err
+		}
+		encoded = append(encoded, tIPTCDataset{tag: tag, data: data})
+	}
+
+	// The new values take the place of the first current value
+	datasets := make([]tIPTCDataset, 0, len(w.datasets)+len(encoded))
+	replaced := false
+	for _, dataset := range w.datasets {
+		if dataset.tag != tag {
+			datasets = append(datasets, dataset)
+		} else if !replaced {
+			datasets = append(datasets, encoded...)
+			replaced = true
+		}
+	}
+	if !replaced {
+		datasets = append(datasets, encoded...)
+	}
+	w.datasets = datasets
+	return nil
+}
+
+// Add adds a value to a repeatable dataset, or sets a dataset that has no value yet
+func (w *IPTCWriter) Add(tag uint16, value interface{}) error {
+	data, err := encodeIPTCValue(tag, value)
+	if err != nil {
+		return err
+	}
+	if field := aIPTCFields[tag]; !field.isRepeatable && len(w.Values(tag)) > 0 {
+		return &exifError{fmt.Sprintf("%s is not repeatable", field.tagTypeID)}
+	}
+	w.datasets = append(w.datasets, tIPTCDataset{tag: tag, data: data})
+	return nil
+}
+
+// Remove removes all values of a dataset, it returns the number of removed values
+func (w *IPTCWriter) Remove(tag uint16) int {
+	datasets := make([]tIPTCDataset, 0, len(w.datasets))
+	for _, dataset := range w.datasets {
+		if dataset.tag != tag {
+			datasets = append(datasets, dataset)
+		}
+	}
+	removed := len(w.datasets) - len(datasets)
+	w.datasets = datasets
+	return removed
+}
+
+// Values returns the values of a dataset in order
+func (w *IPTCWriter) Values(tag uint16) (values []interface{}) {
+	for _, dataset := range w.datasets {
+		if dataset.tag == tag {
+			values = append(values, decodeIPTCValue(dataset))
+		}
+	}
+	return values
+}
+
+// record builds the IPTC-NAA record from the datasets
+func (w *IPTCWriter) record() []byte {
+	datasets := append([]tIPTCDataset{}, w.datasets...)
+
+	// Legacy readers expect the record version and a character set when the text is not ASCII
+	hasRecord := map[uint16]bool{}
+	hasTag := map[uint16]bool{}
+	utf8 := false
+	for _, dataset := range datasets {
+		hasRecord[dataset.tag&0xFF00] = true
+		hasTag[dataset.tag] = true
+		if field, known := aIPTCFields[dataset.tag]; known && field.fieldTypeID == IptcFieldTypeString {
+			for _, b := range dataset.data {
+				if b >= 0x80 {
+					utf8 = true
+				}
+			}
+		}
+	}
+	if hasRecord[IptcTagGroupApplication] && !hasTag[IptcTagApplication2RecordVersion] {
+		datasets = append(datasets, tIPTCDataset{tag: IptcTagApplication2RecordVersion, data: []byte{0, 4}})
+	}
+	if utf8 && !hasTag[IptcTagEnvelopeCharacterSet] {
+		datasets = append(datasets, tIPTCDataset{tag: IptcTagEnvelopeCharacterSet, data: cIPTCUTF8})
+		if !hasTag[IptcTagEnvelopeModelVersion] {
+			datasets = append(datasets, tIPTCDataset{tag: IptcTagEnvelopeModelVersion, data: []byte{0, 4}})
+		}
+	}
+	sort.SliceStable(datasets, func(a, b int) bool {
+		ra, rb := datasets[a].tag>>8, datasets[b].tag>>8
+		if ra != rb {
+			return ra < rb
+		}
+		return datasets[a].tag&0xFF == 0 && datasets[b].tag&0xFF != 0
+	})
+
+	record := []byte{}
+	for _, dataset := range datasets {
+		record = append(record, 0x1C, byte(dataset.tag>>8), byte(dataset.tag))
+		if len(dataset.data) > 0x7FFF {
+			size := len(dataset.data)
+			record = append(record, 0x80, 0x04, byte(size>>24), byte(size>>16), byte(size>>8), byte(size))
+		} else {
+			record = append(record, byte(len(dataset.data)>>8), byte(len(dataset.data)))
+		}
+		record = append(record, dataset.data...)
+	}
+	return record
+}
+
+// Encode builds the APP13 segment(s), nil when there are neither datasets nor other resources
+func (w *IPTCWriter) Encode() ([][]byte, error) {
+	resources := []byte{}
+	written := false
+	record := []byte{}
+	if len(w.datasets) > 0 {
+		record = w.record()
+	}
+	digest := md5.Sum(record)
+	for _, resource := range w.resources {
+		switch resource.id {
+		case PhotoshopResourceIPTC:
+			if !written && len(record) > 0 {
+				resources = append(resources, newPhotoshopResource(PhotoshopResourceIPTC, record).block...)
+				written = true
+			}
+		case PhotoshopResourceIPTCDigest:
+			if w.keepDigest {
+				resources = append(resources, resource.block...)
+			} else if len(record) > 0 {
+				resources = append(resources, newPhotoshopResource(PhotoshopResourceIPTCDigest, digest[:]).block...)
+			}
+		default:
+			resources = append(resources, resource.block...)
+		}
+	}
+	if !written && len(record) > 0 {
+		resources = append(resources, newPhotoshopResource(PhotoshopResourceIPTC, record).block...)
+	}
+	if len(record) > 0 && !w.keepDigest && !w.hasResource(PhotoshopResourceIPTCDigest) {
+		resources = append(resources, newPhotoshopResource(PhotoshopResourceIPTCDigest, digest[:]).block...)
+	}
+	if len(resources) == 0 {
+		return nil, nil
+	}
+
+	segments := [][]byte{}
+	for len(resources) > 0 {
+		size := len(resources)
+		if size > cIPTCMaxResourceSize {
+			size = cIPTCMaxResourceSize
+		}
+		block := make([]byte, 4, 4+len(idIPTC)+size)
+		binary.BigEndian.PutUint16(block, cIPTC)
+		binary.BigEndian.PutUint16(block[2:], uint16(2+len(idIPTC)+size))
+		block = append(block, idIPTC...)
+		segments = append(segments, append(block, resources[:size]...))
+		resources = resources[size:]
+	}
+	return segments, nil
+}
+
+func (w *IPTCWriter) hasResource(id uint16) bool {
+	for _, resource := range w.resources {
+		if resource.id == id {
+			return true
+		}
+	}
+	return false
+}
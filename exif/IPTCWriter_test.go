@@ -0,0 +1,26 @@
+package ImgMeta
+
+import "testing"
+
+func TestReadIPTCDatasetsMalformed(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		data []byte
+	}{
+		{"truncated header", []byte{0x1C, 2, 120, 0}},
+		{"out of bounds", []byte{0x1C, 2, 120, 0, 10, 'a'}},
+		{"no length bytes", []byte{0x1C, 2, 120, 0x80, 0}},
+		{"truncated length", []byte{0x1C, 2, 120, 0x80, 4, 0, 0}},
+		{"8 length bytes", []byte{0x1C, 2, 120, 0x80, 8, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 'a'}},
+		{"4 length bytes", []byte{0x1C, 2, 120, 0x80, 4, 0xFF, 0xFF, 0xFF, 0xFF, 'a'}},
+	} {
+		if _, err := readIPTCDatasets(test.data); err == nil {
+			t.Errorf("%s: no error", test.name)
+		}
+	}
+
+	datasets, err := readIPTCDatasets([]byte{0x1C, 2, 120, 0x80, 2, 0, 2, 'h', 'i', 0x1C, 2, 5, 0, 0, 0})
+	if err != nil || len(datasets) != 2 || string(datasets[0].data) != "hi" || len(datasets[1].data) != 0 {
+		t.Errorf("extended dataset: %v %v", datasets, err)
+	}
+}
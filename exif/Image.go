@@ -6,7 +6,17 @@ import (
 
 // Image holds both 'Image Data' and 'AP'
 type Image struct {
-	apps map[string]APP
+	apps     map[string]APP
+	segments []tSegment // all segments in file order, up to the start of scan
+	data     []byte     // the full JPEG stream, including any trailing (MPF) images
+	scan     uint64     // offset of the SOS marker in data
+}
+
+// tSegment is a segment together with its raw bytes (marker, length and payload)
+type tSegment struct {
+	app      APP
+	block    []byte
+	inserted bool // not read from the file, offsets in the segment are not relative to the file
 }
 
 // ReadTagValue reads the value of a tag given as an ID
@@ -24,16 +34,39 @@ func (i Image) ReadTagValue(appname string, tagID uint16) (value interface{}, er
 	return
 }
 
+// Segments returns all segments of the image in the order they appear in the file,
+// including segments that are not decoded by this package.
+func (i Image) Segments() []APP {
+	apps := make([]APP, 0, len(i.segments))
+	for _, segment := range i.segments {
+		apps = append(apps, segment.app)
+	}
+	return apps
+}
+
+// hasMarker returns true if the image has a section with the given marker
+func (i Image) hasMarker(marker uint16) bool {
+	for _, app := range i.apps {
+		if app.Marker() == marker {
+			return true
+		}
+	}
+	return false
+}
+
 // Image Sections
 const (
 	cSOI = 0xFFD8
 	cEOI = 0xFFD9
 
-	cJFIF = 0xFFE0 // APP0, "JFIF\x00" or "JFXX\x00", JFIF
-	cEXIF = 0xFFE1 // APP1, "EXIF\x00\x00" or "EXIF\x00\xFF" or "http://ns.adobe.com/xap/1.0/\x00"
-	cICC  = 0xFFE2 // APP2, "ICC_PROFILE\x00"
-	cMETA = 0xFFE3 // APP3, "META\x00\x00" or "Meta\x00\x00"
-	cIPTC = 0xFFED // APP13, "Photoshop 3.0\x00"
+	cJFIF  = 0xFFE0 // APP0, "JFIF\x00" or "JFXX\x00", JFIF
+	cEXIF  = 0xFFE1 // APP1, "EXIF\x00\x00" or "EXIF\x00\xFF" or "http://ns.adobe.com/xap/1.0/\x00"
+	cICC   = 0xFFE2 // APP2, "ICC_PROFILE\x00"
+	cMETA  = 0xFFE3 // APP3, "META\x00\x00" or "Meta\x00\x00"
+	cIPTC  = 0xFFED // APP13, "Photoshop 3.0\x00"
+	cADOBE = 0xFFEE // APP14, "Adobe"
+	cAPP0  = 0xFFE0 // APP0 to APP15, identified by a string following the length
+	cAPP15 = 0xFFEF //
 
 	cSOF0  = 0xFFC0 // Start of Frame (baseline JPEG)
 	cSOF1  = 0xFFC1 // Start of Frame (baseline JPEG)
@@ -1,5 +1,14 @@
 package ImgMeta
 
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+)
+
 /*
 Structure of a JFIF APP0 segment
 
@@ -60,3 +69,210 @@ If the thumbnail is stored using three bytes per pixel, there is no colour palet
     3BytesThumbnail 3n bytes 24-bit RGB values for the thumbnail
 
 */
+
+// JFIF APP0 fields, given as offset in the segment
+const (
+	JFIFVersion       = 0x0009
+	JFIFUnits         = 0x000B
+	JFIFXDensity      = 0x000C
+	JFIFYDensity      = 0x000E
+	JFIFXThumbnail    = 0x0010
+	JFIFYThumbnail    = 0x0011
+	JFXXExtensionCode = 0x0009
+)
+
+// JFIF density units
+const (
+	JFIFUnitsAspectRatio uint8 = 0
+	JFIFUnitsInch        uint8 = 1
+	JFIFUnitsCm          uint8 = 2
+)
+
+// JFXX thumbnail extension codes
+const (
+	JFXXThumbnailJPEG    uint8 = 0x10
+	JFXXThumbnailPalette uint8 = 0x11
+	JFXXThumbnailRGB     uint8 = 0x13
+)
+
+type tJFIFAPP struct {
+	offset uint64           // Offset of this APP in the file
+	endian binary.ByteOrder // Byte-Order
+	block  []byte           // full APP block
+}
+
+func (t tJFIFAPP) Name() string {
+	if t.IsExtension() {
+		return "JFXX"
+	}
+	return "JFIF"
+}
+func (t tJFIFAPP) Marker() uint16 {
+	return t.endian.Uint16(t.block)
+}
+func (t tJFIFAPP) Length() uint16 {
+	return t.endian.Uint16(t.block[2:])
+}
+func (t tJFIFAPP) ID(cid []byte) (id []byte) {
+	id = t.block[4 : 4+len(cid)]
+	return
+}
+func (t tJFIFAPP) HasID(cid []byte) bool {
+	if len(t.block) < 4+len(cid) {
+		return false
+	}
+	id := t.block[4 : 4+len(cid)]
+	for i, b := range id {
+		if b != cid[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// IsExtension returns true for a JFXX (extension) segment
+func (t tJFIFAPP) IsExtension() bool {
+	return t.HasID(idJFXX)
+}
+
+func (t tJFIFAPP) ReadValue(tagID2Find uint16) (interface{}, error) {
+	if t.IsExtension() {
+		if tagID2Find == JFXXExtensionCode {
+			return t.block[JFXXExtensionCode], nil
+		}
+		return int(0), &exifError{"Reading JFXX tag value failed"}
+	}
+	switch tagID2Find {
+	case JFIFVersion, JFIFXDensity, JFIFYDensity:
+		return t.endian.Uint16(t.block[tagID2Find:]), nil
+	case JFIFUnits, JFIFXThumbnail, JFIFYThumbnail:
+		return t.block[tagID2Find], nil
+	}
+	return int(0), &exifError{"Reading JFIF tag value failed"}
+}
+
+// Thumbnail decodes the thumbnail of a JFIF or JFXX segment, it returns nil if there is none
+func (t tJFIFAPP) Thumbnail() (image.Image, error) {
+	if !t.IsExtension() {
+		w, h := int(t.block[JFIFXThumbnail]), int(t.block[JFIFYThumbnail])
+		return readRGBThumbnail(t.block[JFIFYThumbnail+1:], w, h)
+	}
+
+	data := t.block[JFXXExtensionCode+1:]
+	switch t.block[JFXXExtensionCode] {
+	case JFXXThumbnailJPEG:
+		return jpeg.Decode(bytes.NewReader(data))
+	case JFXXThumbnailPalette:
+		if len(data) < 2+768 {
+			return nil, &exifError{"JFXX palette thumbnail is truncated"}
+		}
+		w, h := int(data[0]), int(data[1])
+		palette := make(color.Palette, 256)
+		for i := range palette {
+			palette[i] = color.RGBA{data[2+i*3], data[2+i*3+1], data[2+i*3+2], 0xFF}
+		}
+		pixels := data[2+768:]
+		if len(pixels) < w*h {
+			return nil, &exifError{"JFXX palette thumbnail is truncated"}
+		}
+		img := image.NewPaletted(image.Rect(0, 0, w, h), palette)
+		copy(img.Pix, pixels[:w*h])
+		return img, nil
+	case JFXXThumbnailRGB:
+		if len(data) < 2 {
+			return nil, &exifError{"JFXX RGB thumbnail is truncated"}
+		}
+		return readRGBThumbnail(data[2:], int(data[0]), int(data[1]))
+	}
+	return nil, &exifError{"JFXX segment has an unknown extension code"}
+}
+
+func readRGBThumbnail(data []byte, w int, h int) (image.Image, error) {
+	if w == 0 || h == 0 {
+		return nil, nil
+	}
+	if len(data) < w*h*3 {
+		return nil, &exifError{"JFIF RGB thumbnail is truncated"}
+	}
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < w*h; i++ {
+		img.Pix[i*4+0] = data[i*3+0]
+		img.Pix[i*4+1] = data[i*3+1]
+		img.Pix[i*4+2] = data[i*3+2]
+		img.Pix[i*4+3] = 0xFF
+	}
+	return img, nil
+}
+
+// JFIFInfo holds the decoded JFIF APP0 segment, the thumbnail is taken from the JFXX
+// segment when present, otherwise from the JFIF segment; it is nil if there is none.
+type JFIFInfo struct {
+	MajorVersion uint8
+	MinorVersion uint8
+	Units        uint8
+	XDensity     uint16
+	YDensity     uint16
+	Thumbnail    image.Image
+}
+
+// DPI returns the density in dots per inch, ok is false when the density only gives the aspect ratio
+func (j JFIFInfo) DPI() (x float64, y float64, ok bool) {
+	switch j.Units {
+	case JFIFUnitsInch:
+		return float64(j.XDensity), float64(j.YDensity), true
+	case JFIFUnitsCm:
+		return float64(j.XDensity) * 2.54, float64(j.YDensity) * 2.54, true
+	}
+	return 0, 0, false
+}
+
+// JFIF returns the decoded JFIF (and JFXX) information of the image
+func (i Image) JFIF() (info JFIFInfo, err error) {
+	app, exists := i.apps["JFIF"]
+	if !exists {
+		return info, &exifError{"Image does not have 'JFIF' meta section"}
+	}
+	jfif, ok := app.(*tJFIFAPP)
+	if !ok {
+		return info, &exifError{"'JFIF' meta section was not read as JFIF"}
+	}
+	info.MajorVersion = jfif.block[JFIFVersion]
+	info.MinorVersion = jfif.block[JFIFVersion+1]
+	info.Units = jfif.block[JFIFUnits]
+	info.XDensity = jfif.endian.Uint16(jfif.block[JFIFXDensity:])
+	info.YDensity = jfif.endian.Uint16(jfif.block[JFIFYDensity:])
+
+	if jfxx, ok := i.apps["JFXX"].(*tJFIFAPP); ok {
+		info.Thumbnail, err = jfxx.Thumbnail()
+	} else {
+		info.Thumbnail, err = jfif.Thumbnail()
+	}
+	return info, err
+}
+
+// DPI returns the print resolution of the image in dots per inch, from the EXIF XResolution, YResolution and
+// ResolutionUnit, or from the JFIF density when the EXIF resolution is missing or has no unit
+func (i Image) DPI() (x float64, y float64, err error) {
+	s := i.metadataSources()
+	xResolution, hasX := s.exifFloat(IFD0, ExifTagXResolution)
+	yResolution, hasY := s.exifFloat(IFD0, ExifTagYResolution)
+	unit := int64(2) // inches when ResolutionUnit is missing
+	if value, found := s.exifValue(IFD0, ExifTagResolutionUnit); found && len(exifInts(value)) > 0 {
+		unit = exifInts(value)[0]
+	}
+	if hasX && hasY && xResolution > 0 && yResolution > 0 && !math.IsInf(xResolution, 0) && !math.IsInf(yResolution, 0) {
+		switch unit {
+		case 2:
+			return xResolution, yResolution, nil
+		case 3:
+			return xResolution * 2.54, yResolution * 2.54, nil
+		}
+	}
+	if _, exists := i.apps["JFIF"]; exists {
+		info, _ := i.JFIF() // a broken thumbnail does not matter for the density
+		if x, y, ok := info.DPI(); ok && x > 0 && y > 0 {
+			return x, y, nil
+		}
+	}
+	return 0, 0, &exifError{"Image does not have a print resolution"}
+}
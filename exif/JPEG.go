@@ -3,6 +3,7 @@ package ImgMeta
 import (
 	"encoding/binary"
 	"fmt"
+	"io"
 	"os"
 )
 
@@ -17,11 +18,22 @@ func (e *exifError) Error() string {
 
 // ReadJpeg will read all sections from the image data
 func ReadJpeg(fhnd *os.File) (image Image, err error) {
-	image = Image{apps: map[string]APP{}}
 	reader, n, err := newJpegReader(fhnd)
 	if n == 0 || err != nil {
-		return
+		return Image{apps: map[string]APP{}}, err
 	}
+	return readJpeg(reader)
+}
+
+// readJpegData reads all sections from a JPEG stream that is already in memory,
+// e.g. a secondary image that is embedded after the primary image.
+func readJpegData(data []byte) (image Image, err error) {
+	return readJpeg(&JpegReader{cursor: 0, data: data})
+}
+
+func readJpeg(reader *JpegReader) (image Image, err error) {
+	image = Image{apps: map[string]APP{}, data: reader.data}
+	n := 0
 
 	marker := uint16(0)
 	binary.Read(reader, binary.BigEndian, &marker)
@@ -43,22 +55,19 @@ func ReadJpeg(fhnd *os.File) (image Image, err error) {
 			}
 
 			marker = binary.BigEndian.Uint16(appHeader)
-			segment, ok := aSegments[marker]
-			if !ok {
-				return image, &exifError{"Unidentified APP marker encountered"}
-			}
-			//fmt.Printf("Encountered marker %s\n", segment.name)
-
-			app, err := segment.reader(marker, reader)
+			start := reader.pos() - 2
+			app, err := fAPPReadSegment(marker, reader)
 			if err != nil {
 				return image, err
 			}
 
 			if app == nil {
+				// Start of scan, from here on it is compressed image data
+				image.scan = start
 				break
 			}
-			fmt.Printf("Registering APP %s, Length:%v\n", app.Name(), app.Length())
 			image.apps[app.Name()] = app
+			image.segments = append(image.segments, tSegment{app: app, block: reader.data[start:reader.pos()]})
 
 		} else {
 			// Not a section marker
@@ -66,9 +75,23 @@ func ReadJpeg(fhnd *os.File) (image Image, err error) {
 			return image, &exifError{fmt.Sprintf("Encountered invalid section marker 0x%X", marker)}
 		}
 	}
+
+	// The maker note lives inside the EXIF segment, it is registered as an APP of its own so that
+	// its tags can be read with ReadTagValue("MakerNote", tag).
+	if exif, exists := image.apps["EXIF"].(*tEXIFAPP); exists {
+		if makerNote, err := exif.makerNote(); err == nil {
+			image.apps[makerNote.Name()] = makerNote
+		}
+	}
 	return image, nil
 }
 
+// WriteJpeg writes the image with its (modified) segments to w, the compressed
+// image data is copied as-is.
+func (i Image) WriteJpeg(w io.Writer) error {
+	return NewJpegWriter(i).Write(w)
+}
+
 type JpegReader struct {
 	cursor uint64
 	data   []byte
@@ -76,6 +99,9 @@ type JpegReader struct {
 
 func (b *JpegReader) Read(p []byte) (n int, err error) {
 	for i := 0; i < len(p); i++ {
+		if b.cursor >= uint64(len(b.data)) {
+			return i, io.EOF
+		}
 		p[i] = b.data[b.cursor]
 		b.cursor++
 	}
@@ -83,6 +109,9 @@ func (b *JpegReader) Read(p []byte) (n int, err error) {
 }
 
 func (b *JpegReader) ReadByte() byte {
+	if b.cursor >= uint64(len(b.data)) {
+		return 0
+	}
 	v := b.data[b.cursor]
 	b.cursor++
 	return v
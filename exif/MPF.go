@@ -0,0 +1,257 @@
+package ImgMeta
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+/*
+Structure of a MPF APP2 segment
+
+The CIPA DC-007 Multi-Picture Format stores additional images (large thumbnails, stereo pairs, panorama frames and,
+these days, HDR gain maps) after the EOI of the primary image. The primary image carries an APP2 segment describing
+all images in the file:
+
+    [Record name]    [size]   [description]
+    ---------------------------------------
+    Identifier       4 bytes  ("MPF\000" = 0x4D504600)
+    Endianness       2 bytes  'II' (little-endian) or 'MM' (big-endian)
+    Signature        2 bytes  a fixed value = 42
+    IFD_Pointer      4 bytes  offset of the MP Index IFD (usually 8)
+    MP Index IFD        ...   same layout as an EXIF IFD
+
+All offsets, both inside the IFD and the image offsets in the MP entries, are relative to the start of the
+Endianness field. The MP Index IFD holds the following tags:
+
+    [Tag]   [Name]            [description]
+    ---------------------------------------
+    0xB000  MPFVersion        4 bytes, "0100"
+    0xB001  NumberOfImages    ULONG
+    0xB002  MPEntry           16 bytes per image
+    0xB003  ImageUIDList      33 bytes per image (optional)
+    0xB004  TotalFrames       ULONG (optional)
+
+An MP entry has the following structure:
+
+    [Record name]    [size]   [description]
+    ---------------------------------------
+    Attribute        4 bytes  flags (upper byte) and image type (lower 24 bits)
+    Size             4 bytes  size of the image in bytes
+    Offset           4 bytes  offset of the image (0 for the primary image)
+    Dependent1       2 bytes  entry number of the first dependent image
+    Dependent2       2 bytes  entry number of the second dependent image
+
+*/
+
+// MPF tags
+const (
+	MpfTagVersion        uint16 = 0xB000
+	MpfTagNumberOfImages uint16 = 0xB001
+	MpfTagMPEntry        uint16 = 0xB002
+	MpfTagImageUIDList   uint16 = 0xB003
+	MpfTagTotalFrames    uint16 = 0xB004
+)
+
+// MPF image types (lower 24 bits of the MP entry attribute)
+const (
+	MpfTypeUndefined         uint32 = 0x000000
+	MpfTypeLargeThumbnailVGA uint32 = 0x010001
+	MpfTypeLargeThumbnailHD  uint32 = 0x010002
+	MpfTypePanorama          uint32 = 0x020001
+	MpfTypeDisparity         uint32 = 0x020002
+	MpfTypeMultiAngle        uint32 = 0x020003
+	MpfTypeBaselinePrimary   uint32 = 0x030000
+)
+
+// MPEntry describes one of the images in a Multi-Picture file
+type MPEntry struct {
+	Attribute  uint32
+	Size       uint32
+	Offset     uint32 // relative to the MPF TIFF header, 0 for the primary image
+	Dependent1 uint16
+	Dependent2 uint16
+}
+
+// Type returns the MP image type of the entry
+func (e MPEntry) Type() uint32 {
+	return e.Attribute & 0x00FFFFFF
+}
+
+type tMPFAPP struct {
+	offset uint64           // Offset of this APP in the file
+	endian binary.ByteOrder // Byte-Order
+	block  []byte           // full APP block
+}
+
+func (t tMPFAPP) Name() string {
+	return "MPF"
+}
+func (t tMPFAPP) Marker() uint16 {
+	return t.endian.Uint16(t.block)
+}
+func (t tMPFAPP) Length() uint16 {
+	return t.endian.Uint16(t.block[2:])
+}
+func (t tMPFAPP) ID(cid []byte) (id []byte) {
+	id = t.block[4 : 4+len(cid)]
+	return
+}
+func (t tMPFAPP) HasID(cid []byte) bool {
+	id := t.block[4 : 4+len(cid)]
+	for i, b := range id {
+		if b != cid[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// tiffOffset is the offset of the MPF TIFF header in the APP block
+func (t tMPFAPP) tiffOffset() uint32 {
+	return 4 + uint32(len(idMPF))
+}
+
+func (t tMPFAPP) TIFFByteOrder() binary.ByteOrder {
+	bo := binary.BigEndian.Uint16(t.block[t.tiffOffset():])
+	if bo == cINTEL {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
+
+// FileOffset returns the offset in the file that MP entry offsets are relative to
+func (t tMPFAPP) FileOffset() uint64 {
+	return t.offset + uint64(t.tiffOffset())
+}
+
+func (t tMPFAPP) indexIFD() (tExifIFD, error) {
+	if len(t.block) < int(t.tiffOffset())+8 {
+		return tExifIFD{}, &exifError{"MPF segment is too small"}
+	}
+	endian := t.TIFFByteOrder()
+	offset := t.tiffOffset() + endian.Uint32(t.block[t.tiffOffset()+4:])
+	if int(offset)+2 > len(t.block) {
+		return tExifIFD{}, &exifError{"MPF index IFD is out of bounds"}
+	}
+	return tExifIFD{offset: offset, endian: endian, appblock: t.block, base: t.tiffOffset()}, nil
+}
+
+func (t tMPFAPP) ReadValue(tagID2Find uint16) (interface{}, error) {
+	ifd, err := t.indexIFD()
+	if err != nil {
+		return nil, err
+	}
+	tag, found := ifd.FindTag(tagID2Find)
+	if !found {
+		return nil, &exifError{fmt.Sprintf("MPF tag 0x%X not found", tagID2Find)}
+	}
+	switch tagID2Find {
+	case MpfTagNumberOfImages, MpfTagTotalFrames:
+		return tag.valueOrOffset(), nil
+	case MpfTagMPEntry:
+		return t.Entries()
+	}
+	return t.rawValue(tag)
+}
+
+// rawValue returns the bytes of an (UNDEFINED) tag value
+func (t tMPFAPP) rawValue(tag tExifTag) ([]byte, error) {
+	count := tag.countOrComponents()
+	if count <= 4 {
+		return append([]byte{}, tag.appblock[tag.offset+8:tag.offset+8+count]...), nil
+	}
+	offset := t.tiffOffset() + tag.valueOrOffset()
+	if uint64(offset)+uint64(count) > uint64(len(t.block)) {
+		return nil, &exifError{"MPF tag value is out of bounds"}
+	}
+	return append([]byte{}, t.block[offset:offset+count]...), nil
+}
+
+// Entries returns the MP entries, the first entry is the primary image
+func (t tMPFAPP) Entries() ([]MPEntry, error) {
+	ifd, err := t.indexIFD()
+	if err != nil {
+		return nil, err
+	}
+	tag, found := ifd.FindTag(MpfTagMPEntry)
+	if !found {
+		return nil, &exifError{"MPF segment has no MP entries"}
+	}
+	block, err := t.rawValue(tag)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]MPEntry, 0, len(block)/16)
+	for i := 0; i+16 <= len(block); i += 16 {
+		entries = append(entries, MPEntry{
+			Attribute:  ifd.endian.Uint32(block[i:]),
+			Size:       ifd.endian.Uint32(block[i+4:]),
+			Offset:     ifd.endian.Uint32(block[i+8:]),
+			Dependent1: ifd.endian.Uint16(block[i+12:]),
+			Dependent2: ifd.endian.Uint16(block[i+14:]),
+		})
+	}
+	return entries, nil
+}
+
+// shiftedOffsets returns a copy of the segment with shift added to the offsets of the secondary images
+func (t tMPFAPP) shiftedOffsets(shift int64) ([]byte, error) {
+	block := append([]byte{}, t.block...)
+	if shift == 0 {
+		return block, nil
+	}
+	ifd, err := t.indexIFD()
+	if err != nil {
+		return nil, err
+	}
+	tag, found := ifd.FindTag(MpfTagMPEntry)
+	if !found {
+		return block, nil // no images that move
+	}
+	count := tag.countOrComponents()
+	offset := t.tiffOffset() + tag.valueOrOffset()
+	if count <= 4 || uint64(offset)+uint64(count) > uint64(len(block)) {
+		return nil, &exifError{"MPF tag value is out of bounds"}
+	}
+	for i := offset; i+16 <= offset+count; i += 16 {
+		if entry := ifd.endian.Uint32(block[i+8:]); entry != 0 {
+			moved := int64(entry) + shift
+			if moved <= 0 || moved > math.MaxUint32 {
+				return nil, &exifError{"MPF image offset is out of range after the move"}
+			}
+			ifd.endian.PutUint32(block[i+8:], uint32(moved))
+		}
+	}
+	return block, nil
+}
+
+// MPImages returns the MP entries of the image together with the JPEG stream of each
+// secondary image; the primary image is not included.
+func (i Image) MPImages() (entries []MPEntry, images [][]byte, err error) {
+	app, exists := i.apps["MPF"]
+	if !exists {
+		return nil, nil, &exifError{"Image does not have 'MPF' meta section"}
+	}
+	mpf, ok := app.(*tMPFAPP)
+	if !ok {
+		return nil, nil, &exifError{"'MPF' meta section was not read as MPF"}
+	}
+	all, err := mpf.Entries()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, entry := range all {
+		if entry.Offset == 0 {
+			continue
+		}
+		begin := mpf.FileOffset() + uint64(entry.Offset)
+		end := begin + uint64(entry.Size)
+		if end > uint64(len(i.data)) {
+			return entries, images, &exifError{fmt.Sprintf("MPF image at offset %d is out of bounds", begin)}
+		}
+		entries = append(entries, entry)
+		images = append(images, i.data[begin:end])
+	}
+	return entries, images, nil
+}
@@ -0,0 +1,483 @@
+package ImgMeta
+
+import (
+	"bytes"
+	"crypto/md5"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+/*
+Reconciling EXIF, IPTC and XMP
+
+The same properties are stored in EXIF, IPTC-IIM and XMP. The Metadata Working Group guidelines (MWG 2.0) tell
+which one to believe when they differ:
+
+    Property           EXIF                  IPTC-IIM                    XMP
+    Description        ImageDescription      2:120 Caption-Abstract      dc:description
+    Keywords           -                     2:25 Keywords               dc:subject
+    Creator            Artist                2:80 By-line                dc:creator
+    Copyright          Copyright             2:116 CopyrightNotice       dc:rights
+    DateTimeOriginal   DateTimeOriginal      2:55 DateCreated            photoshop:DateCreated
+                       + SubsecTimeOriginal  + 2:60 TimeCreated
+                       + OffsetTimeOriginal
+
+The IPTC digest (Photoshop resource 0x0425) is the MD5 of the IPTC-IIM when the XMP was last brought in sync:
+
+    Digest      Precedence
+    matches     EXIF, XMP             (the IPTC-IIM is a copy of the XMP)
+    differs     EXIF, IPTC-IIM, XMP   (the IPTC-IIM was changed by a program that does not know XMP)
+    missing     EXIF, XMP, IPTC-IIM
+
+EXIF text is read as UTF-8; EXIF text that is not valid UTF-8 (a legacy code page, read as Latin-1) only counts
+after the XMP. IPTC-IIM text is UTF-8 when 1:90 says so or when it is valid UTF-8, and Latin-1 otherwise.
+
+The resolved value is reported with its source, together with the values of the other sources that differ from it.
+SetMWG and SyncMWG write a property to all three: EXIF and XMP always, IPTC-IIM only when the image has IPTC, after
+which the IPTC digest matches again.
+
+Dates are compared by their wall clock. A date without a UTC offset takes the offset of another source with the
+same wall clock; when none has one, SyncMWG writes the date without offset (no OffsetTimeOriginal, an IPTC time
+without offset and an XMP date without time zone).
+
+*/
+
+// MetadataSource names where a value was read from
+type MetadataSource int
+
+// Sources of a value
+const (
+	SourceNone MetadataSource = iota
+	SourceEXIF
+	SourceIPTC
+	SourceXMP
+)
+
+func (s MetadataSource) String() string {
+	return [...]string{"none", "EXIF", "IPTC", "XMP"}[s]
+}
+
+// MWGProperty is a property that is reconciled across EXIF, IPTC and XMP
+type MWGProperty int
+
+// Reconciled properties
+const (
+	MWGDescription MWGProperty = iota
+	MWGKeywords
+	MWGCreator
+	MWGCopyright
+	MWGDateTimeOriginal
+)
+
+// IPTCDigestState tells whether the IPTC-IIM matches its digest
+type IPTCDigestState int
+
+// IPTC digest states
+const (
+	IPTCDigestMissing IPTCDigestState = iota
+	IPTCDigestMatches
+	IPTCDigestDiffers
+)
+
+// MetadataConflict is a value of another source that differs from the resolved value
+type MetadataConflict struct {
+	Source MetadataSource
+	Values []string
+}
+
+// ReconciledValue is the resolved value of a property, a list property (keywords, creator) has more than one value
+type ReconciledValue struct {
+	Values    []string
+	Time      time.Time // DateTimeOriginal only
+	HasOffset bool      // Time has a UTC offset, otherwise it is a wall clock (in UTC)
+	Source    MetadataSource
+	Conflicts []MetadataConflict
+}
+
+// String returns the value, the values of a list joined by "; "
+func (v ReconciledValue) String() string {
+	return strings.Join(v.Values, "; ")
+}
+
+// Reconciled holds the reconciled properties of an image
+type Reconciled struct {
+	IPTCDigest       IPTCDigestState
+	Description      ReconciledValue
+	Keywords         ReconciledValue
+	Creator          ReconciledValue
+	Copyright        ReconciledValue
+	DateTimeOriginal ReconciledValue
+}
+
+// tMWGMapping gives where a property is stored in each source
+type tMWGMapping struct {
+	exifIFD  uint16
+	exifTag  uint16 // 0 when not in EXIF
+	iptcTag  uint16
+	iptcTime uint16 // the time dataset that goes with a date
+	xmpNs    string
+	xmpName  string
+	list     bool
+}
+
+var aMWGMappings = map[MWGProperty]tMWGMapping{
+	MWGDescription:      {exifIFD: IFD0, exifTag: ExifTagImageDescription, iptcTag: IptcTagApplication2Caption, xmpNs: XmpNsDC, xmpName: "description"},
+	MWGKeywords:         {iptcTag: IptcTagApplication2Keywords, xmpNs: XmpNsDC, xmpName: "subject", list: true},
+	MWGCreator:          {exifIFD: IFD0, exifTag: ExifTagArtist, iptcTag: IptcTagApplication2Byline, xmpNs: XmpNsDC, xmpName: "creator", list: true},
+	MWGCopyright:        {exifIFD: IFD0, exifTag: ExifTagCopyright, iptcTag: IptcTagApplication2Copyright, xmpNs: XmpNsDC, xmpName: "rights"},
+	MWGDateTimeOriginal: {exifIFD: IFDExif, exifTag: ExifTagDateTimeOriginal, iptcTag: IptcTagApplication2DateCreated, iptcTime: IptcTagApplication2TimeCreated, xmpNs: XmpNsPhotoshop, xmpName: "DateCreated"},
+}
+
+// tMWGCandidate is the value of a property in one source
+type tMWGCandidate struct {
+	source    MetadataSource
+	values    []string
+	time      time.Time
+	hasOffset bool
+}
+
+// iptcDigest compares the MD5 of the IPTC-IIM record with the digest resource
+func (s tMetadataSources) iptcDigest() IPTCDigestState {
+	if s.iptc == nil {
+		return IPTCDigestMissing
+	}
+	var record, digest []byte
+	for _, resource := range s.iptc.resources {
+		switch resource.id {
+		case PhotoshopResourceIPTC:
+			record = photoshopResourceData(resource.block)
+		case PhotoshopResourceIPTCDigest:
+			digest = photoshopResourceData(resource.block)
+		}
+	}
+	if digest == nil || record == nil {
+		return IPTCDigestMissing
+	}
+	sum := md5.Sum(record)
+	if bytes.Equal(sum[:], digest) {
+		return IPTCDigestMatches
+	}
+	return IPTCDigestDiffers
+}
+
+// latin1 decodes ISO 8859-1 text
+func latin1(text string) string {
+	runes := make([]rune, len(text))
+	for n := 0; n < len(text); n++ {
+		runes[n] = rune(text[n])
+	}
+	return string(runes)
+}
+
+// candidates returns the value of the property in each source, in MWG precedence
+func (s tMetadataSources) candidates(property MWGProperty, digest IPTCDigestState) []tMWGCandidate {
+	mapping := aMWGMappings[property]
+	var exif, exifLegacy, iptc, xmp *tMWGCandidate
+
+	if mapping.exifTag != 0 {
+		text := s.exifString(mapping.exifIFD, mapping.exifTag)
+		legacy := !utf8.ValidString(text)
+		if legacy {
+			text = latin1(text)
+		}
+		candidate := &tMWGCandidate{source: SourceEXIF}
+		if property == MWGDateTimeOriginal {
+			if t, err := parseExifDateTime(text, s.exifString(IFDExif, ExifTagSubsecTimeOriginal)); err == nil {
+				candidate.time, candidate.values = t, []string{text}
+				if offset, err := parseUTCOffset(s.exifString(IFDExif, ExifTagOffsetTimeOriginal)); err == nil {
+					candidate.time, candidate.hasOffset = withOffset(t, offset), true
+				}
+			}
+		} else if mapping.list {
+			for _, name := range strings.Split(text, ";") {
+				if name = strings.TrimSpace(name); name != "" {
+					candidate.values = append(candidate.values, name)
+				}
+			}
+		} else if text != "" {
+			candidate.values = []string{text}
+		}
+		if len(candidate.values) > 0 {
+			if legacy {
+				exifLegacy = candidate
+			} else {
+				exif = candidate
+			}
+		}
+	}
+
+	if values := s.iptcStrings(mapping.iptcTag); len(values) > 0 {
+		utf8Declared := false
+		for _, charset := range s.iptc.Values(IptcTagEnvelopeCharacterSet) {
+			if text, ok := charset.(string); ok && text == string(cIPTCUTF8) {
+				utf8Declared = true
+			}
+		}
+		for n, value := range values {
+			if !utf8Declared && !utf8.ValidString(value) {
+				values[n] = latin1(value)
+			}
+		}
+		candidate := &tMWGCandidate{source: SourceIPTC, values: values}
+		if property == MWGDateTimeOriginal {
+			clock := firstString(s.iptcStrings(mapping.iptcTime))
+			t, err := parseIPTCDateTime(values[0], clock)
+			_, offsetErr := time.Parse("150405-0700", strings.TrimSpace(clock))
+			candidate.time, candidate.hasOffset = t, offsetErr == nil
+			if err != nil {
+				candidate = nil
+			}
+		} else if !mapping.list {
+			candidate.values = values[:1]
+		}
+		iptc = candidate
+	}
+
+	if values := s.xmpStrings(mapping.xmpNs, mapping.xmpName); len(values) > 0 {
+		candidate := &tMWGCandidate{source: SourceXMP, values: values}
+		if property == MWGDateTimeOriginal {
+			t, err := parseXMPDate(values[0])
+			clock := ""
+			if n := strings.IndexByte(values[0], 'T'); n >= 0 {
+				clock = strings.TrimSpace(values[0][n+1:])
+			}
+			candidate.time, candidate.hasOffset = t, strings.HasSuffix(clock, "Z") || strings.ContainsAny(clock, "+-")
+			if err != nil {
+				candidate = nil
+			}
+		} else if !mapping.list {
+			candidate.values = values[:1]
+		}
+		xmp = candidate
+	}
+
+	order := []*tMWGCandidate{exif}
+	switch digest {
+	case IPTCDigestMatches:
+		order = append(order, xmp, exifLegacy)
+	case IPTCDigestDiffers:
+		order = append(order, iptc, xmp, exifLegacy)
+	default:
+		order = append(order, xmp, exifLegacy, iptc)
+	}
+	candidates := []tMWGCandidate{}
+	for _, candidate := range order {
+		if candidate != nil {
+			candidates = append(candidates, *candidate)
+		}
+	}
+	return candidates
+}
+
+// sameMWGValue compares two candidates; dates by their wall clock, and by their offset when both have one
+func sameMWGValue(a tMWGCandidate, b tMWGCandidate) bool {
+	if !a.time.IsZero() || !b.time.IsZero() {
+		if a.hasOffset && b.hasOffset && !a.time.Equal(b.time) {
+			return false
+		}
+		return a.time.Format("2006-01-02T15:04:05") == b.time.Format("2006-01-02T15:04:05")
+	}
+	if len(a.values) != len(b.values) {
+		return false
+	}
+	for n := range a.values {
+		if strings.TrimSpace(a.values[n]) != strings.TrimSpace(b.values[n]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s tMetadataSources) reconcile(property MWGProperty, digest IPTCDigestState) (value ReconciledValue) {
+	candidates := s.candidates(property, digest)
+	if len(candidates) == 0 {
+		return value
+	}
+	value.Values, value.Time, value.Source = candidates[0].values, candidates[0].time, candidates[0].source
+	value.HasOffset = candidates[0].hasOffset
+	for _, other := range candidates[1:] {
+		if other.source != value.Source && !sameMWGValue(candidates[0], other) {
+			value.Conflicts = append(value.Conflicts, MetadataConflict{Source: other.source, Values: other.values})
+		} else if !value.HasOffset && other.hasOffset {
+			// a date without offset takes the offset of a source with the same wall clock
+			_, offset := other.time.Zone()
+			value.Time, value.HasOffset = withOffset(value.Time, time.Duration(offset)*time.Second), true
+		}
+	}
+	return value
+}
+
+// Reconcile resolves the properties that are stored in more than one of EXIF, IPTC and XMP
+func (i Image) Reconcile() Reconciled {
+	s := i.metadataSources()
+	digest := s.iptcDigest()
+	return Reconciled{
+		IPTCDigest:       digest,
+		Description:      s.reconcile(MWGDescription, digest),
+		Keywords:         s.reconcile(MWGKeywords, digest),
+		Creator:          s.reconcile(MWGCreator, digest),
+		Copyright:        s.reconcile(MWGCopyright, digest),
+		DateTimeOriginal: s.reconcile(MWGDateTimeOriginal, digest),
+	}
+}
+
+// SetMWG writes a property to EXIF, XMP and, when the image has IPTC, to the IPTC-IIM. A date is given as
+// time.Time, a list as []string and other properties as string.
+func (m *Metadata) SetMWG(property MWGProperty, value interface{}) error {
+	return m.setMWG(property, value, true)
+}
+
+// setMWG writes a property, a date is written without UTC offset when hasOffset is false
+func (m *Metadata) setMWG(property MWGProperty, value interface{}, hasOffset bool) error {
+	mapping, known := aMWGMappings[property]
+	if !known {
+		return &exifError{"Unknown MWG property"}
+	}
+	var values []string
+	var date time.Time
+	switch v := value.(type) {
+	case string:
+		values = []string{v}
+	case []string:
+		values = v
+	case time.Time:
+		date = v
+	default:
+		return &exifError{"MWG properties take a string, []string or time.Time"}
+	}
+	isDate := property == MWGDateTimeOriginal
+	if isDate == date.IsZero() || (!isDate && !mapping.list && len(values) != 1) {
+		return &exifError{"Value does not match the MWG property"}
+	}
+
+	if mapping.exifTag != 0 {
+		exif, err := m.Exif()
+		if err != nil {
+			return err
+		}
+		text := strings.Join(values, "; ")
+		if isDate {
+			text = date.Format("2006:01:02 15:04:05")
+			exif.RemoveTag(IFDExif, ExifTagSubsecTimeOriginal)
+			if date.Nanosecond() != 0 {
+				subsec := strings.TrimRight(date.Format(".000000000")[1:], "0")
+				if err := exif.SetTag(IFDExif, ExifTagSubsecTimeOriginal, subsec); err != nil {
+					return err
+				}
+			}
+			exif.RemoveTag(IFDExif, ExifTagOffsetTimeOriginal)
+			if hasOffset {
+				if err := exif.SetTag(IFDExif, ExifTagOffsetTimeOriginal, date.Format("-07:00")); err != nil {
+					return err
+				}
+			}
+		}
+		if text == "" {
+			exif.RemoveTag(mapping.exifIFD, mapping.exifTag)
+		} else if err := exif.SetTag(mapping.exifIFD, mapping.exifTag, text); err != nil {
+			return err
+		}
+	}
+
+	if _, exists := m.image.apps["IPTC"]; exists || m.iptc != nil {
+		iptc, err := m.IPTC()
+		if err != nil {
+			return err
+		}
+		iptc.Remove(mapping.iptcTag)
+		if isDate {
+			iptc.Remove(mapping.iptcTime)
+			if err := iptc.Set(mapping.iptcTag, date.Format("20060102")); err != nil {
+				return err
+			}
+			layout := "150405"
+			if hasOffset {
+				layout = "150405-0700"
+			}
+			if err := iptc.Set(mapping.iptcTime, date.Format(layout)); err != nil {
+				return err
+			}
+		} else {
+			set := []interface{}{}
+			for _, v := range values {
+				if v != "" {
+					set = append(set, v)
+				}
+			}
+			if len(set) > 0 {
+				if err := iptc.Set(mapping.iptcTag, set...); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	xmp, err := m.XMP()
+	if err != nil {
+		return err
+	}
+	switch {
+	case isDate && !hasOffset:
+		return xmp.Set(mapping.xmpNs, mapping.xmpName, date.Format("2006-01-02T15:04:05.999999999"))
+	case isDate:
+		return xmp.Set(mapping.xmpNs, mapping.xmpName, date)
+	case len(values) == 0 || (len(values) == 1 && values[0] == ""):
+		xmp.Remove(mapping.xmpNs, mapping.xmpName)
+		return nil
+	case property == MWGKeywords:
+		return xmp.Set(mapping.xmpNs, mapping.xmpName, XMPBag(values))
+	case property == MWGCreator:
+		return xmp.Set(mapping.xmpNs, mapping.xmpName, XMPSeq(values))
+	}
+	return xmp.Set(mapping.xmpNs, mapping.xmpName, XMPLangAlt{"x-default": values[0]})
+}
+
+// SyncMWG writes the reconciled value of every property to EXIF, XMP and IPTC-IIM, it returns the values that
+// were written.
+func (m *Metadata) SyncMWG() (Reconciled, error) {
+	reconciled := m.image.Reconcile()
+	properties := map[MWGProperty]ReconciledValue{
+		MWGDescription:      reconciled.Description,
+		MWGKeywords:         reconciled.Keywords,
+		MWGCreator:          reconciled.Creator,
+		MWGCopyright:        reconciled.Copyright,
+		MWGDateTimeOriginal: reconciled.DateTimeOriginal,
+	}
+	for _, property := range []MWGProperty{MWGDescription, MWGKeywords, MWGCreator, MWGCopyright, MWGDateTimeOriginal} {
+		value := properties[property]
+		if value.Source == SourceNone || len(value.Conflicts) == 0 && m.complete(property, value) {
+			continue
+		}
+		var err error
+		switch {
+		case property == MWGDateTimeOriginal:
+			err = m.setMWG(property, value.Time, value.HasOffset)
+		case aMWGMappings[property].list:
+			err = m.SetMWG(property, value.Values)
+		default:
+			err = m.SetMWG(property, value.Values[0])
+		}
+		if err != nil {
+			return reconciled, err
+		}
+	}
+	return reconciled, nil
+}
+
+// complete returns true when every source that should hold the property has it
+func (m *Metadata) complete(property MWGProperty, value ReconciledValue) bool {
+	s := m.image.metadataSources()
+	present := map[MetadataSource]bool{}
+	for _, candidate := range s.candidates(property, IPTCDigestMissing) {
+		present[candidate.source] = true
+	}
+	mapping := aMWGMappings[property]
+	if mapping.exifTag != 0 && !present[SourceEXIF] {
+		return false
+	}
+	if _, hasIPTC := m.image.apps["IPTC"]; hasIPTC && !present[SourceIPTC] {
+		return false
+	}
+	return present[SourceXMP]
+}
@@ -0,0 +1,477 @@
+package ImgMeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+/*
+Structure of a MakerNote
+
+The MakerNote tag (0x927C) of the Exif IFD is an UNDEFINED blob that belongs to the camera vendor. There is no
+standard for its content, but almost all vendors use the EXIF IFD layout, with or without a header, and differ in:
+
+    - the header in front of the IFD, e.g. "Nikon\000", "FUJIFILM", "OLYMPUS\000II", "SONY DSC \000\000\000"
+    - the byte order, usually that of the EXIF segment, but some vendors write their own (Nikon, Olympus)
+    - the base of the value offsets:
+        * the TIFF header of the EXIF segment (Canon, Sony, Panasonic, Pentax)
+        * the start of the maker note (Fujifilm, Apple, old Olympus)
+        * a TIFF header embedded in the maker note (Nikon type 3, new Olympus)
+
+The vendor is detected from the Make tag of IFD0 and the header of the maker note, after which the IFD is read
+with the same tExifIFD machinery as the standard EXIF IFDs. The raw maker note tags can be read with
+ReadTagValue("MakerNote", tag), the decoded vendor independent information with MakerNote().
+
+Some maker notes have a footer holding the offset the maker note had when it was written (Canon writes
+"II*\000" followed by that offset as the last 8 bytes), when an editor moved the maker note the base is
+corrected with the difference. Microsoft tools do the same with the OffsetSchema tag (0xEA1D) of the Exif IFD,
+which holds the distance the maker note was moved over.
+
+Rewriting EXIF moves data around, a maker note with offsets relative to the TIFF header breaks when it is moved.
+The EXIF encoder therefore places the maker note as follows:
+
+    1. at its original offset when the rest of the EXIF data ends before it (padding the gap)
+    2. anywhere when all its offsets are relative to the maker note itself (Nikon, Fujifilm, Apple, ...)
+    3. anywhere, with its value offsets (and the Canon footer) fixed up, when the vendor is known
+    4. anywhere, as is, when the vendor is unknown; OffsetSchema tells the reader how far it moved
+
+*/
+
+// MakerNote holds the decoded maker note, the common fields are filled in when the vendor
+// records them (lens model and serial numbers fall back to the EXIF tags); Fields holds all
+// decoded vendor specific values keyed by name.
+type MakerNote struct {
+	Vendor             string
+	LensModel          string
+	LensID             string
+	SerialNumber       string
+	LensSerialNumber   string
+	ShutterCount       uint32
+	PictureMode        string // picture style, picture control, film simulation, ...
+	ImageStabilization string // "On", "Off" or a vendor specific mode
+	Fields             map[string]interface{}
+}
+
+// tMakerNoteVendor describes how to detect, open and decode the maker note of a vendor
+type tMakerNoteVendor struct {
+	name   string
+	detect func(make string, note []byte) bool
+	open   func(mn *tMakerNoteAPP) (tExifIFD, error)
+	decode func(mn *tMakerNoteAPP, info *MakerNote)
+}
+
+var aMakerNoteVendors = []*tMakerNoteVendor{
+	&cMakerNoteApple,
+	&cMakerNoteFujifilm,
+	&cMakerNoteOlympus,
+	&cMakerNotePanasonic,
+	&cMakerNotePentax,
+	&cMakerNoteNikon,
+	&cMakerNoteSony,
+	&cMakerNoteCanon,
+}
+
+type tMakerNoteAPP struct {
+	exif   *tEXIFAPP
+	vendor *tMakerNoteVendor
+	offset uint32 // Offset of the maker note in the EXIF block
+	size   uint32 // Size of the maker note
+	make   string
+	model  string
+	ifd    tExifIFD
+}
+
+func (t tMakerNoteAPP) Name() string {
+	return "MakerNote"
+}
+func (t tMakerNoteAPP) Marker() uint16 {
+	return t.exif.Marker()
+}
+func (t tMakerNoteAPP) Length() uint16 {
+	return t.exif.Length()
+}
+func (t tMakerNoteAPP) ID(cid []byte) (id []byte) {
+	id = t.Block()[:len(cid)]
+	return
+}
+func (t tMakerNoteAPP) HasID(cid []byte) bool {
+	return bytes.HasPrefix(t.Block(), cid)
+}
+
+// Block returns the raw maker note
+func (t tMakerNoteAPP) Block() []byte {
+	return t.exif.block[t.offset : t.offset+t.size]
+}
+
+// Vendor returns the name of the detected vendor
+func (t tMakerNoteAPP) Vendor() string {
+	return t.vendor.name
+}
+
+func (t tMakerNoteAPP) ReadValue(tagID2Find uint16) (interface{}, error) {
+	tag, found := t.ifd.FindTag(tagID2Find)
+	if !found {
+		return nil, &exifError{fmt.Sprintf("%s MakerNote tag 0x%X not found", t.vendor.name, tagID2Find)}
+	}
+	return t.ifd.ReadValue(tag)
+}
+
+// readInts reads a numeric tag as a slice of integers
+func (t tMakerNoteAPP) readInts(tagID uint16) []int64 {
+	value, err := t.ReadValue(tagID)
+	if err != nil {
+		return nil
+	}
+	return exifInts(value)
+}
+
+// readString reads an ASCII tag, trimming the padding some vendors use
+func (t tMakerNoteAPP) readString(tagID uint16) string {
+	value, err := t.ReadValue(tagID)
+	if err != nil {
+		return ""
+	}
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case []byte:
+		if n := bytes.IndexByte(v, 0); n >= 0 {
+			v = v[:n]
+		}
+		return strings.TrimSpace(string(v))
+	}
+	return ""
+}
+
+// readBytes reads the raw bytes of a tag value, whatever its type
+func (t tMakerNoteAPP) readBytes(tagID uint16) []byte {
+	tag, found := t.ifd.FindTag(tagID)
+	if !found {
+		return nil
+	}
+	data, err := t.ifd.valueData(tag)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// subIFD opens a sub-IFD of the maker note, the tag holds the offset of the IFD relative to the same
+// base as the other value offsets (the IFD type, LONG and UNDEFINED are used for such tags).
+func (t tMakerNoteAPP) subIFD(tagID uint16) (*tMakerNoteAPP, bool) {
+	tag, found := t.ifd.FindTag(tagID)
+	if !found {
+		return nil, false
+	}
+	offset := uint64(t.ifd.base) + uint64(tag.valueOrOffset())
+	if offset+2 > uint64(len(t.exif.block)) {
+		return nil, false
+	}
+	sub := t
+	sub.ifd = tExifIFD{offset: uint32(offset), endian: t.ifd.endian, appblock: t.exif.block, base: t.ifd.base}
+	return &sub, true
+}
+
+// mainIFD opens the maker note IFD that starts skip bytes into the maker note, with the value
+// offsets relative to base (an offset in the EXIF block) and the given byte order.
+func (t tMakerNoteAPP) mainIFD(skip uint32, base uint32, endian binary.ByteOrder) (tExifIFD, error) {
+	if skip+2 > t.size {
+		return tExifIFD{}, &exifError{fmt.Sprintf("%s MakerNote is too small", t.vendor.name)}
+	}
+	return tExifIFD{offset: t.offset + skip, endian: endian, appblock: t.exif.block, base: base}, nil
+}
+
+// embeddedTIFF opens a maker note IFD that follows a TIFF header which starts skip bytes into the maker
+// note, value offsets are relative to that TIFF header.
+func (t tMakerNoteAPP) embeddedTIFF(skip uint32) (tExifIFD, error) {
+	if skip+8 > t.size {
+		return tExifIFD{}, &exifError{fmt.Sprintf("%s MakerNote is too small", t.vendor.name)}
+	}
+	base := t.offset + skip
+	var endian binary.ByteOrder = binary.BigEndian
+	if binary.BigEndian.Uint16(t.exif.block[base:]) == cINTEL {
+		endian = binary.LittleEndian
+	}
+	return t.mainIFD(skip+endian.Uint32(t.exif.block[base+4:]), base, endian)
+}
+
+// makerNoteByteOrder returns the byte order given by "II" or "MM" in a maker note header
+func makerNoteByteOrder(order string) binary.ByteOrder {
+	if strings.HasPrefix(order, "MM") {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// footerBase corrects the base of the value offsets with the original offset of the maker note that
+// some vendors record in a footer ("II*\0" or "MM\0*" followed by the offset).
+func (t tMakerNoteAPP) footerBase(base uint32) uint32 {
+	note := t.Block()
+	if len(note) < 8 {
+		return base
+	}
+	footer := note[len(note)-8:]
+	var endian binary.ByteOrder
+	if bytes.Equal(footer[:4], []byte{'I', 'I', 0x2A, 0}) {
+		endian = binary.LittleEndian
+	} else if bytes.Equal(footer[:4], []byte{'M', 'M', 0, 0x2A}) {
+		endian = binary.BigEndian
+	} else {
+		return base
+	}
+	original := endian.Uint32(footer[4:])
+	current := t.offset - cEXIFTIFFOffset
+	return base + current - original
+}
+
+// rawMakerNote locates the maker note in the Exif IFD, it returns the offset of the maker note in the EXIF
+// block, its size and the value of the OffsetSchema tag.
+func (t *tEXIFAPP) rawMakerNote() (offset uint32, size uint32, schema int32, err error) {
+	ifds := t.IFDs()
+	exifIFD, exists := ifds[cIFDEXIF]
+	if !exists {
+		return 0, 0, 0, &exifError{"EXIF does not have an Exif IFD"}
+	}
+	tag, found := exifIFD.FindTag(ExifTagMakerNote)
+	if !found {
+		return 0, 0, 0, &exifError{"EXIF does not have a MakerNote"}
+	}
+	size = tag.countOrComponents()
+	offset = cEXIFTIFFOffset + tag.valueOrOffset()
+	if size <= 4 || uint64(offset)+uint64(size) > uint64(len(t.block)) {
+		return 0, 0, 0, &exifError{"EXIF MakerNote is out of bounds"}
+	}
+	if tag, found := exifIFD.FindTag(ExifTagOffsetSchema); found {
+		schema = int32(tag.valueOrOffset())
+	}
+	return offset, size, schema, nil
+}
+
+// makerNote locates the maker note in the Exif IFD and detects the vendor
+func (t *tEXIFAPP) makerNote() (*tMakerNoteAPP, error) {
+	offset, size, schema, err := t.rawMakerNote()
+	if err != nil {
+		return nil, err
+	}
+
+	mn := &tMakerNoteAPP{exif: t, offset: offset, size: size}
+	if value, err := t.ReadIFDValue(cIFDZERO, ExifTagMake); err == nil {
+		mn.make, _ = value.(string)
+	}
+	if value, err := t.ReadIFDValue(cIFDZERO, ExifTagModel); err == nil {
+		mn.model, _ = value.(string)
+	}
+	for _, vendor := range aMakerNoteVendors {
+		if vendor.detect(mn.make, mn.Block()) {
+			mn.vendor = vendor
+			ifd, err := vendor.open(mn)
+			if err != nil {
+				return nil, err
+			}
+			if ifd.base == cEXIFTIFFOffset {
+				// Not corrected by a footer, the maker note may have been moved by a Microsoft tool
+				ifd.base = uint32(int64(ifd.base) + int64(schema))
+			}
+			mn.ifd = ifd
+			return mn, nil
+		}
+	}
+	return nil, &exifError{fmt.Sprintf("MakerNote of '%s' is not supported", mn.make)}
+}
+
+// selfContained is true when all offsets in the maker note are relative to the maker note itself
+func (t tMakerNoteAPP) selfContained() bool {
+	return t.ifd.base >= t.offset && t.ifd.base < t.offset+t.size
+}
+
+// relocate returns a copy of the maker note with the value offsets of the main IFD fixed up for placing the
+// maker note at TIFF offset newOffset, a Canon style footer is updated as well. Values that are stored
+// outside of the maker note can not be moved along, relocate fails for those.
+func (t tMakerNoteAPP) relocate(newOffset uint32) ([]byte, error) {
+	note := append([]byte{}, t.Block()...)
+	if t.selfContained() {
+		return note, nil
+	}
+	n := t.ifd.NumberOfTags()
+	for i := uint32(0); i < n; i++ {
+		tag := t.ifd.GetTag(i)
+		fieldType := tag.TypeID() &^ cARRAY
+		if fieldType == 0 || int(fieldType) >= len(aExifTagFieldSize) {
+			continue
+		}
+		size := uint64(getExifTagFieldSize(tExifTagFieldType(fieldType))) * uint64(tag.countOrComponents())
+		if size <= 4 {
+			continue
+		}
+		position := int64(t.ifd.base) + int64(tag.valueOrOffset()) - int64(t.offset)
+		if position < 0 || uint64(position)+size > uint64(t.size) {
+			return nil, &exifError{fmt.Sprintf("%s MakerNote tag 0x%X has its value outside of the MakerNote", t.vendor.name, tag.TagID())}
+		}
+		entry := t.ifd.offset - t.offset + 2 + i*12
+		t.ifd.endian.PutUint32(note[entry+8:], newOffset+uint32(position))
+	}
+	if t.hasFooter() {
+		footer := note[len(note)-4:]
+		if note[len(note)-8] == 'I' {
+			binary.LittleEndian.PutUint32(footer, newOffset)
+		} else {
+			binary.BigEndian.PutUint32(footer, newOffset)
+		}
+	}
+	return note, nil
+}
+
+// hasFooter is true when the maker note ends with a Canon style footer
+func (t tMakerNoteAPP) hasFooter() bool {
+	note := t.Block()
+	if len(note) < 8 {
+		return false
+	}
+	footer := note[len(note)-8 : len(note)-4]
+	return bytes.Equal(footer, []byte{'I', 'I', 0x2A, 0}) || bytes.Equal(footer, []byte{'M', 'M', 0, 0x2A})
+}
+
+// tMakerNotePlacement tells the EXIF encoder where and how to write the maker note
+type tMakerNotePlacement struct {
+	offset uint32 // TIFF offset of the maker note
+	data   []byte // the (possibly fixed up) maker note
+	schema int32  // value for the OffsetSchema tag
+}
+
+// placeMakerNote places the maker note of an EXIF segment in a new EXIF segment, free is the first (word
+// aligned) TIFF offset after all other data and limit the maximum size of the TIFF data.
+func (t *tEXIFAPP) placeMakerNote(free uint32, limit uint32) (tMakerNotePlacement, error) {
+	offset, size, schema, err := t.rawMakerNote()
+	if err != nil {
+		return tMakerNotePlacement{}, err
+	}
+	note := t.block[offset : offset+size]
+	original := offset - cEXIFTIFFOffset
+
+	// 1. keep it where it is
+	if free <= original && original+size <= limit {
+		return tMakerNotePlacement{offset: original, data: note, schema: schema}, nil
+	}
+	if mn, err := t.makerNote(); err == nil {
+		// 2. and 3. move it, the offsets are correct for the new position
+		if data, err := mn.relocate(free); err == nil {
+			return tMakerNotePlacement{offset: free, data: data, schema: 0}, nil
+		}
+	}
+	// 4. move it as is
+	return tMakerNotePlacement{offset: free, data: note, schema: schema + int32(free) - int32(original)}, nil
+}
+
+// MakerNote decodes the maker note of the image
+func (i Image) MakerNote() (info MakerNote, err error) {
+	app, exists := i.apps["MakerNote"]
+	if !exists {
+		return info, &exifError{"Image does not have a supported MakerNote"}
+	}
+	mn, ok := app.(*tMakerNoteAPP)
+	if !ok {
+		return info, &exifError{"'MakerNote' meta section was not read as a MakerNote"}
+	}
+	info.Vendor = mn.vendor.name
+	info.Fields = map[string]interface{}{}
+	mn.vendor.decode(mn, &info)
+
+	exif := mn.exif
+	for _, fallback := range []struct {
+		field *string
+		tagID uint16
+	}{
+		{&info.LensModel, ExifTagLensModel},
+		{&info.SerialNumber, ExifTagBodySerialNumber},
+		{&info.LensSerialNumber, ExifTagLensSerialNumber},
+	} {
+		if *fallback.field == "" {
+			if value, err := exif.ReadIFDValue(cIFDEXIF, fallback.tagID); err == nil {
+				if text, ok := value.(string); ok {
+					*fallback.field = strings.TrimSpace(text)
+				}
+			}
+		}
+	}
+	return info, nil
+}
+
+// tMakerNoteField names an entry of an array valued maker note tag
+type tMakerNoteField struct {
+	index int
+	name  string
+}
+
+// decodeMakerNoteArray stores the named entries of an array valued tag in fields
+func decodeMakerNoteArray(values []int64, names []tMakerNoteField, fields map[string]interface{}) {
+	for _, field := range names {
+		if field.index < len(values) {
+			fields[field.name] = values[field.index]
+		}
+	}
+}
+
+// makerNoteName looks up the name of a vendor specific value, unknown values are printed as number
+func makerNoteName(names map[int64]string, value int64) string {
+	if name, known := names[value]; known {
+		return name
+	}
+	return fmt.Sprintf("Unknown (%d)", value)
+}
+
+// exifInts converts any numeric EXIF value (single or array) to a slice of integers
+func exifInts(value interface{}) []int64 {
+	switch v := value.(type) {
+	case uint8:
+		return []int64{int64(v)}
+	case uint16:
+		return []int64{int64(v)}
+	case uint32:
+		return []int64{int64(v)}
+	case int8:
+		return []int64{int64(v)}
+	case int16:
+		return []int64{int64(v)}
+	case int32:
+		return []int64{int64(v)}
+	case []uint8:
+		ints := make([]int64, len(v))
+		for i, n := range v {
+			ints[i] = int64(n)
+		}
+		return ints
+	case []uint16:
+		ints := make([]int64, len(v))
+		for i, n := range v {
+			ints[i] = int64(n)
+		}
+		return ints
+	case []uint32:
+		ints := make([]int64, len(v))
+		for i, n := range v {
+			ints[i] = int64(n)
+		}
+		return ints
+	case []int8:
+		ints := make([]int64, len(v))
+		for i, n := range v {
+			ints[i] = int64(n)
+		}
+		return ints
+	case []int16:
+		ints := make([]int64, len(v))
+		for i, n := range v {
+			ints[i] = int64(n)
+		}
+		return ints
+	case []int32:
+		ints := make([]int64, len(v))
+		for i, n := range v {
+			ints[i] = int64(n)
+		}
+		return ints
+	}
+	return nil
+}
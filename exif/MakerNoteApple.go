@@ -0,0 +1,176 @@
+package ImgMeta
+
+import (
+	"encoding/binary"
+	"math"
+	"strings"
+)
+
+/*
+Structure of an Apple MakerNote
+
+    [Record name]    [size]   [description]
+    ---------------------------------------
+    Identifier      10 bytes  ("Apple iOS\000")
+    Version          2 bytes  0x0001 (or 0x0002 on newer devices)
+    Byte order       2 bytes  "MM"
+    IFD                 ...   big-endian, value offsets relative to the start of the maker note
+
+    [Tag]   [Name]              [description]
+    ---------------------------------------
+    0x0001  MakerNoteVersion    int32s
+    0x0003  RunTime             binary plist, time since boot
+    0x0008  AccelerationVector  SRATIONAL[3], x/y/z acceleration in units of g
+    0x000A  HDRImageType        int32s, 3 = HDR image, 4 = original image
+    0x000B  BurstUUID           ASCII, shared by all images of a burst
+    0x000C  FocusDistanceRange  SRATIONAL[2]
+    0x000F  OISMode             int32s
+    0x0011  ContentIdentifier   ASCII, shared by the still and video of a Live Photo
+    0x0014  ImageCaptureType    int32s, 1 = ProRAW, 2 = Portrait, 10 = Photo, 11 = Manual Focus, 12 = Scene
+    0x0015  ImageUniqueID       ASCII
+    0x0017  LivePhotoVideoIndex int32u
+    0x0021  HDRHeadroom         SRATIONAL
+    0x0030  HDRGain             SRATIONAL
+
+The HDR headroom of the gain map is derived from HDRHeadroom and HDRGain as published by Apple:
+
+    if HDRHeadroom < 1.0: stops = HDRGain <= 0.01 ? -20.0 * HDRGain + 1.8 : -0.101 * HDRGain + 1.601
+    else:                 stops = HDRGain <= 0.01 ? -70.0 * HDRGain + 3.0 : -0.303 * HDRGain + 2.303
+    headroom = 2 ^ max(stops, 0)
+
+*/
+
+// Apple MakerNote tags
+const (
+	AppleTagMakerNoteVersion    uint16 = 0x0001
+	AppleTagRunTime             uint16 = 0x0003
+	AppleTagAccelerationVector  uint16 = 0x0008
+	AppleTagHDRImageType        uint16 = 0x000A
+	AppleTagBurstUUID           uint16 = 0x000B
+	AppleTagFocusDistanceRange  uint16 = 0x000C
+	AppleTagOISMode             uint16 = 0x000F
+	AppleTagContentIdentifier   uint16 = 0x0011
+	AppleTagImageCaptureType    uint16 = 0x0014
+	AppleTagImageUniqueID       uint16 = 0x0015
+	AppleTagLivePhotoVideoIndex uint16 = 0x0017
+	AppleTagHDRHeadroom         uint16 = 0x0021
+	AppleTagHDRGain             uint16 = 0x0030
+)
+
+var aAppleHDRImageType = map[int64]string{
+	3: "HDR Image",
+	4: "Original Image",
+}
+
+var aAppleImageCaptureType = map[int64]string{
+	1:  "ProRAW",
+	2:  "Portrait",
+	10: "Photo",
+	11: "Manual Focus",
+	12: "Scene",
+}
+
+var cMakerNoteApple = tMakerNoteVendor{
+	name: "Apple",
+	detect: func(make string, note []byte) bool {
+		return strings.HasPrefix(string(note), "Apple iOS\x00")
+	},
+	open: func(mn *tMakerNoteAPP) (tExifIFD, error) {
+		return mn.mainIFD(14, mn.offset, binary.BigEndian)
+	},
+	decode: decodeAppleMakerNote,
+}
+
+func decodeAppleMakerNote(mn *tMakerNoteAPP, info *MakerNote) {
+	for tag, name := range map[uint16]string{
+		AppleTagBurstUUID:         "BurstUUID",
+		AppleTagContentIdentifier: "ContentIdentifier",
+		AppleTagImageUniqueID:     "ImageUniqueID",
+	} {
+		if value := mn.readString(tag); value != "" {
+			info.Fields[name] = value
+		}
+	}
+	for tag, name := range map[uint16]string{
+		AppleTagMakerNoteVersion:    "MakerNoteVersion",
+		AppleTagOISMode:             "OISMode",
+		AppleTagLivePhotoVideoIndex: "LivePhotoVideoIndex",
+	} {
+		if values := mn.readInts(tag); len(values) == 1 {
+			info.Fields[name] = values[0]
+		}
+	}
+	if values := mn.readInts(AppleTagHDRImageType); len(values) == 1 {
+		info.Fields["HDRImageType"] = makerNoteName(aAppleHDRImageType, values[0])
+	}
+	if values := mn.readInts(AppleTagImageCaptureType); len(values) == 1 {
+		info.Fields["ImageCaptureType"] = makerNoteName(aAppleImageCaptureType, values[0])
+	}
+	if value, err := mn.ReadValue(AppleTagAccelerationVector); err == nil {
+		if vector, ok := value.([]float64); ok && len(vector) == 3 {
+			info.Fields["AccelerationVector"] = vector
+		}
+	}
+	if value, err := mn.ReadValue(AppleTagFocusDistanceRange); err == nil {
+		if distances, ok := value.([]float64); ok && len(distances) == 2 {
+			info.Fields["FocusDistanceRange"] = distances
+		}
+	}
+	if headroom, ok := appleHeadroom(mn); ok {
+		info.Fields["HDRHeadroom"] = headroom
+	}
+	if values := mn.readInts(AppleTagOISMode); len(values) == 1 {
+		if values[0] == 0 {
+			info.ImageStabilization = "Off"
+		} else {
+			info.ImageStabilization = "On"
+		}
+	}
+}
+
+// appleHeadroom computes the HDR headroom (the linear boost of the brightest pixel) from the
+// HDRHeadroom and HDRGain tags.
+func appleHeadroom(mn *tMakerNoteAPP) (float64, bool) {
+	headroomValue, err := mn.ReadValue(AppleTagHDRHeadroom)
+	if err != nil {
+		return 0, false
+	}
+	gainValue, err := mn.ReadValue(AppleTagHDRGain)
+	if err != nil {
+		return 0, false
+	}
+	headroom, ok1 := headroomValue.(float64)
+	gain, ok2 := gainValue.(float64)
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+
+	var stops float64
+	if headroom < 1.0 {
+		if gain <= 0.01 {
+			stops = -20.0*gain + 1.8
+		} else {
+			stops = -0.101*gain + 1.601
+		}
+	} else {
+		if gain <= 0.01 {
+			stops = -70.0*gain + 3.0
+		} else {
+			stops = -0.303*gain + 2.303
+		}
+	}
+	return math.Pow(2, math.Max(stops, 0)), true
+}
+
+// ContentIdentifier returns the identifier that an iPhone writes in both the still image and the
+// video of a Live Photo, it can be used to pair them.
+func (i Image) ContentIdentifier() (string, error) {
+	mn, ok := i.apps["MakerNote"].(*tMakerNoteAPP)
+	if !ok || mn.vendor != &cMakerNoteApple {
+		return "", &exifError{"Image does not have an Apple MakerNote"}
+	}
+	if id := mn.readString(AppleTagContentIdentifier); id != "" {
+		return id, nil
+	}
+	return "", &exifError{"Apple MakerNote does not have a ContentIdentifier"}
+}
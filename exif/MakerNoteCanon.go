@@ -0,0 +1,279 @@
+package ImgMeta
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+Structure of a Canon MakerNote
+
+Canon writes a plain IFD without a header, in the byte order of the EXIF segment and with the value offsets
+relative to the TIFF header of the EXIF segment. Newer models add a footer ("II*\000" + original offset) that
+is used to correct the offsets when the maker note was moved by an editor.
+
+Many tags hold an array of int16 values where each entry is a separate setting; the index tables below use
+the entry number (the first entry, index 0, holds the size of the array in bytes):
+
+    [Tag]   [Name]              [description]
+    ---------------------------------------
+    0x0001  CameraSettings      int16s[], macro mode, quality, focus mode, lens type, focal range, ...
+    0x0002  FocalLength         int16u[4], focal type, focal length, focal plane x/y size
+    0x0004  ShotInfo            int16s[], ISO, measured EV, white balance, focus distance, ...
+    0x0006  ImageType           ASCII, e.g. "Canon EOS 5D Mark III"
+    0x0007  FirmwareVersion     ASCII
+    0x0008  FileNumber          int32u, directory number * 10000 + file number
+    0x0009  OwnerName           ASCII
+    0x000C  SerialNumber        int32u, camera body serial number
+    0x0010  ModelID             int32u
+    0x0093  FileInfo            int16s[], bracketing, raw+jpeg settings, live view, ...
+    0x0095  LensModel           ASCII
+    0x0096  InternalSerialNumber ASCII
+    0x00A0  ProcessingInfo      int16s[], tone curve, sharpness, picture style, white balance, ...
+    0x4019  LensInfo            UNDEFINED[30], the first 5 bytes are the lens serial number
+
+Canon does not record a shutter count in a model independent place, ShutterCount is left 0.
+
+*/
+
+// Canon MakerNote tags
+const (
+	CanonTagCameraSettings       uint16 = 0x0001
+	CanonTagFocalLength          uint16 = 0x0002
+	CanonTagShotInfo             uint16 = 0x0004
+	CanonTagPanorama             uint16 = 0x0005
+	CanonTagImageType            uint16 = 0x0006
+	CanonTagFirmwareVersion      uint16 = 0x0007
+	CanonTagFileNumber           uint16 = 0x0008
+	CanonTagOwnerName            uint16 = 0x0009
+	CanonTagSerialNumber         uint16 = 0x000C
+	CanonTagCameraInfo           uint16 = 0x000D
+	CanonTagModelID              uint16 = 0x0010
+	CanonTagFileInfo             uint16 = 0x0093
+	CanonTagLensModel            uint16 = 0x0095
+	CanonTagInternalSerialNumber uint16 = 0x0096
+	CanonTagProcessingInfo       uint16 = 0x00A0
+	CanonTagLensInfo             uint16 = 0x4019
+)
+
+var aCanonCameraSettings = []tMakerNoteField{
+	{1, "MacroMode"},
+	{2, "SelfTimer"},
+	{3, "Quality"},
+	{4, "CanonFlashMode"},
+	{5, "ContinuousDrive"},
+	{7, "FocusMode"},
+	{9, "RecordMode"},
+	{10, "CanonImageSize"},
+	{11, "EasyMode"},
+	{12, "DigitalZoom"},
+	{13, "Contrast"},
+	{14, "Saturation"},
+	{15, "Sharpness"},
+	{16, "CameraISO"},
+	{17, "MeteringMode"},
+	{18, "FocusRange"},
+	{19, "AFPoint"},
+	{20, "CanonExposureMode"},
+	{22, "LensType"},
+	{23, "MaxFocalLength"},
+	{24, "MinFocalLength"},
+	{25, "FocalUnits"},
+	{26, "MaxAperture"},
+	{27, "MinAperture"},
+	{28, "FlashActivity"},
+	{29, "FlashBits"},
+	{32, "FocusContinuous"},
+	{33, "AESetting"},
+	{34, "ImageStabilization"},
+	{35, "DisplayAperture"},
+	{36, "ZoomSourceWidth"},
+	{37, "ZoomTargetWidth"},
+	{39, "SpotMeteringMode"},
+	{40, "PhotoEffect"},
+	{41, "ManualFlashOutput"},
+	{42, "ColorTone"},
+	{46, "SRAWQuality"},
+}
+
+var aCanonShotInfo = []tMakerNoteField{
+	{1, "AutoISO"},
+	{2, "BaseISO"},
+	{3, "MeasuredEV"},
+	{4, "TargetAperture"},
+	{5, "TargetExposureTime"},
+	{6, "ExposureCompensation"},
+	{7, "WhiteBalance"},
+	{8, "SlowShutter"},
+	{9, "SequenceNumber"},
+	{10, "OpticalZoomCode"},
+	{12, "CameraTemperature"},
+	{13, "FlashGuideNumber"},
+	{14, "AFPointsInFocus"},
+	{15, "FlashExposureComp"},
+	{16, "AutoExposureBracketing"},
+	{17, "AEBBracketValue"},
+	{18, "ControlMode"},
+	{19, "FocusDistanceUpper"},
+	{20, "FocusDistanceLower"},
+	{21, "FNumber"},
+	{22, "ExposureTime"},
+	{23, "MeasuredEV2"},
+	{24, "BulbDuration"},
+	{26, "CameraType"},
+	{27, "AutoRotate"},
+	{28, "NDFilter"},
+	{29, "SelfTimer2"},
+	{33, "FlashOutput"},
+}
+
+var aCanonFileInfo = []tMakerNoteField{
+	{3, "BracketMode"},
+	{4, "BracketValue"},
+	{5, "BracketShotNumber"},
+	{6, "RawJpgQuality"},
+	{7, "RawJpgSize"},
+	{8, "LongExposureNoiseReduction2"},
+	{9, "WBBracketMode"},
+	{12, "WBBracketValueAB"},
+	{13, "WBBracketValueGM"},
+	{14, "FilterEffect"},
+	{15, "ToningEffect"},
+	{16, "MacroMagnification"},
+	{19, "LiveViewShooting"},
+	{25, "FlashExposureLock"},
+}
+
+var aCanonImageStabilization = map[int64]string{
+	0:   "Off",
+	1:   "On",
+	2:   "Shoot Only",
+	3:   "Panning",
+	4:   "Dynamic",
+	256: "Off",
+	257: "On",
+	258: "Shoot Only",
+	259: "Panning",
+	260: "Dynamic",
+}
+
+var aCanonPictureStyle = map[int64]string{
+	0x00: "None",
+	0x01: "Standard",
+	0x02: "Portrait",
+	0x03: "High Saturation",
+	0x04: "Adobe RGB",
+	0x05: "Low Saturation",
+	0x06: "CM Set 1",
+	0x07: "CM Set 2",
+	0x21: "User Def. 1",
+	0x22: "User Def. 2",
+	0x23: "User Def. 3",
+	0x41: "PC 1",
+	0x42: "PC 2",
+	0x43: "PC 3",
+	0x81: "Standard",
+	0x82: "Portrait",
+	0x83: "Landscape",
+	0x84: "Neutral",
+	0x85: "Faithful",
+	0x86: "Monochrome",
+	0x87: "Auto",
+	0x88: "Fine Detail",
+}
+
+var cMakerNoteCanon = tMakerNoteVendor{
+	name: "Canon",
+	detect: func(make string, note []byte) bool {
+		return strings.HasPrefix(strings.ToUpper(make), "CANON")
+	},
+	open: func(mn *tMakerNoteAPP) (tExifIFD, error) {
+		return mn.mainIFD(0, mn.footerBase(cEXIFTIFFOffset), mn.exif.TIFFByteOrder())
+	},
+	decode: decodeCanonMakerNote,
+}
+
+// canonInt16s reads an int16s[] array as signed values (-1 is "n/a"), the entries at the given indexes are
+// unsigned
+func canonInt16s(values []int64, unsigned ...int) []int64 {
+	converted := make([]int64, len(values))
+	for n, value := range values {
+		converted[n] = int64(int16(value))
+	}
+	for _, n := range unsigned {
+		if n < len(values) {
+			converted[n] = int64(uint16(values[n]))
+		}
+	}
+	return converted
+}
+
+func decodeCanonMakerNote(mn *tMakerNoteAPP, info *MakerNote) {
+	// the lens type and the focal lengths are the unsigned entries of the camera settings
+	settings := canonInt16s(mn.readInts(CanonTagCameraSettings), 22, 23, 24)
+	decodeMakerNoteArray(settings, aCanonCameraSettings, info.Fields)
+	decodeMakerNoteArray(canonInt16s(mn.readInts(CanonTagShotInfo), 19, 20), aCanonShotInfo, info.Fields)
+	decodeMakerNoteArray(canonInt16s(mn.readInts(CanonTagFileInfo)), aCanonFileInfo, info.Fields)
+
+	if len(settings) > 34 && settings[34] != -1 {
+		info.ImageStabilization = makerNoteName(aCanonImageStabilization, settings[34])
+	}
+	if processing := canonInt16s(mn.readInts(CanonTagProcessingInfo)); len(processing) > 10 && processing[10] != 0xFF && processing[10] != -1 {
+		info.PictureMode = makerNoteName(aCanonPictureStyle, processing[10])
+	}
+
+	for tag, name := range map[uint16]string{
+		CanonTagImageType:       "ImageType",
+		CanonTagFirmwareVersion: "FirmwareVersion",
+		CanonTagOwnerName:       "OwnerName",
+	} {
+		if value := mn.readString(tag); value != "" {
+			info.Fields[name] = value
+		}
+	}
+	if values := mn.readInts(CanonTagFileNumber); len(values) == 1 {
+		info.Fields["FileNumber"] = values[0]
+	}
+	if values := mn.readInts(CanonTagModelID); len(values) == 1 {
+		info.Fields["ModelID"] = values[0]
+	}
+
+	// Lens, the lens type is an index in a (long and ambiguous) list of Canon and third party lenses
+	// and is reported as is; without a lens model the focal range is used.
+	if len(settings) > 22 && settings[22] != 0 && settings[22] != 0xFFFF {
+		info.LensID = fmt.Sprintf("%d", settings[22])
+	}
+	info.LensModel = mn.readString(CanonTagLensModel)
+	if info.LensModel == "" {
+		if value, err := mn.exif.ReadIFDValue(cIFDEXIF, ExifTagLensModel); err == nil {
+			info.LensModel, _ = value.(string)
+		}
+	}
+	if info.LensModel == "" && len(settings) > 25 {
+		units := settings[25]
+		if units <= 0 {
+			units = 1
+		}
+		long, short := settings[23]/units, settings[24]/units
+		if short > 0 && long > short {
+			info.LensModel = fmt.Sprintf("%d-%dmm", short, long)
+		} else if long > 0 {
+			info.LensModel = fmt.Sprintf("%dmm", long)
+		}
+	}
+
+	// Serial numbers
+	if values := mn.readInts(CanonTagSerialNumber); len(values) == 1 {
+		info.SerialNumber = fmt.Sprintf("%010d", values[0])
+	} else {
+		info.SerialNumber = mn.readString(CanonTagInternalSerialNumber)
+	}
+	if lensInfo := mn.readBytes(CanonTagLensInfo); len(lensInfo) >= 5 {
+		for _, b := range lensInfo[:5] {
+			if b != 0 {
+				info.LensSerialNumber = fmt.Sprintf("%x", lensInfo[:5])
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package ImgMeta
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+/*
+Structure of a Fujifilm MakerNote
+
+    [Record name]    [size]   [description]
+    ---------------------------------------
+    Identifier       8 bytes  ("FUJIFILM")
+    IFD_Pointer      4 bytes  offset of the IFD relative to the start of the maker note, little-endian
+    IFD                 ...   always little-endian, value offsets relative to the start of the maker note
+
+    [Tag]   [Name]              [description]
+    ---------------------------------------
+    0x0000  Version             UNDEFINED[4], e.g. "0130"
+    0x0010  InternalSerialNumber ASCII
+    0x1003  Saturation          int16u, also holds the monochrome (B&W, Acros) modes
+    0x1401  FilmMode            int16u, film simulation for colour images
+    0x1404  MinFocalLength      RATIONAL
+    0x1405  MaxFocalLength      RATIONAL
+    0x1406  MaxApertureAtMinFocal RATIONAL
+    0x1407  MaxApertureAtMaxFocal RATIONAL
+    0x1422  ImageStabilization  int16u[3], type, mode and (unknown)
+
+*/
+
+// Fujifilm MakerNote tags
+const (
+	FujifilmTagVersion               uint16 = 0x0000
+	FujifilmTagInternalSerialNumber  uint16 = 0x0010
+	FujifilmTagQuality               uint16 = 0x1000
+	FujifilmTagSharpness             uint16 = 0x1001
+	FujifilmTagWhiteBalance          uint16 = 0x1002
+	FujifilmTagSaturation            uint16 = 0x1003
+	FujifilmTagFilmMode              uint16 = 0x1401
+	FujifilmTagDynamicRange          uint16 = 0x1402
+	FujifilmTagMinFocalLength        uint16 = 0x1404
+	FujifilmTagMaxFocalLength        uint16 = 0x1405
+	FujifilmTagMaxApertureAtMinFocal uint16 = 0x1406
+	FujifilmTagMaxApertureAtMaxFocal uint16 = 0x1407
+	FujifilmTagImageStabilization    uint16 = 0x1422
+)
+
+var aFujifilmFilmMode = map[int64]string{
+	0x000: "F0/Standard (Provia)",
+	0x100: "F1/Studio Portrait",
+	0x110: "F1a/Studio Portrait Enhanced Saturation",
+	0x120: "F1b/Studio Portrait Smooth Skin Tone (Astia)",
+	0x130: "F1c/Studio Portrait Increased Sharpness",
+	0x200: "F2/Fujichrome (Velvia)",
+	0x300: "F3/Studio Portrait Ex",
+	0x400: "F4/Velvia",
+	0x500: "Pro Neg. Std",
+	0x501: "Pro Neg. Hi",
+	0x600: "Classic Chrome",
+	0x700: "Eterna",
+	0x800: "Classic Negative",
+	0x900: "Bleach Bypass",
+	0xA00: "Nostalgic Neg",
+	0xB00: "Reala ACE",
+}
+
+var aFujifilmMonochrome = map[int64]string{
+	0x300: "B&W",
+	0x301: "B&W Red Filter",
+	0x302: "B&W Yellow Filter",
+	0x303: "B&W Green Filter",
+	0x310: "B&W Sepia",
+	0x500: "Acros",
+	0x501: "Acros Red Filter",
+	0x502: "Acros Yellow Filter",
+	0x503: "Acros Green Filter",
+}
+
+var aFujifilmStabilizationType = map[int64]string{
+	0:   "None",
+	1:   "Optical",
+	2:   "Sensor-shift",
+	3:   "OIS Lens",
+	258: "IBIS/OIS + DIS",
+	512: "Digital",
+}
+
+var aFujifilmStabilizationMode = map[int64]string{
+	0: "Off",
+	1: "On (mode 1, continuous)",
+	2: "On (mode 2, shooting only)",
+}
+
+var cMakerNoteFujifilm = tMakerNoteVendor{
+	name: "Fujifilm",
+	detect: func(make string, note []byte) bool {
+		return strings.HasPrefix(string(note), "FUJIFILM")
+	},
+	open: func(mn *tMakerNoteAPP) (tExifIFD, error) {
+		if mn.size < 12 {
+			return tExifIFD{}, &exifError{"Fujifilm MakerNote is too small"}
+		}
+		skip := binary.LittleEndian.Uint32(mn.Block()[8:])
+		return mn.mainIFD(skip, mn.offset, binary.LittleEndian)
+	},
+	decode: decodeFujifilmMakerNote,
+}
+
+func decodeFujifilmMakerNote(mn *tMakerNoteAPP, info *MakerNote) {
+	info.SerialNumber = mn.readString(FujifilmTagInternalSerialNumber)
+	if version := mn.readString(FujifilmTagVersion); version != "" {
+		info.Fields["Version"] = version
+	}
+
+	// Film simulation, monochrome simulations are stored in the Saturation tag
+	if values := mn.readInts(FujifilmTagSaturation); len(values) == 1 {
+		if name, monochrome := aFujifilmMonochrome[values[0]]; monochrome {
+			info.PictureMode = name
+		}
+	}
+	if values := mn.readInts(FujifilmTagFilmMode); len(values) == 1 && info.PictureMode == "" {
+		info.PictureMode = makerNoteName(aFujifilmFilmMode, values[0])
+	}
+	if info.PictureMode != "" {
+		info.Fields["FilmSimulation"] = info.PictureMode
+	}
+
+	if values := mn.readInts(FujifilmTagImageStabilization); len(values) >= 2 {
+		info.Fields["ImageStabilizationType"] = makerNoteName(aFujifilmStabilizationType, values[0])
+		info.ImageStabilization = makerNoteName(aFujifilmStabilizationMode, values[1])
+	}
+
+	// Lens, only the focal range and apertures are recorded
+	var lens [4]float64
+	for n, tag := range []uint16{FujifilmTagMinFocalLength, FujifilmTagMaxFocalLength, FujifilmTagMaxApertureAtMinFocal, FujifilmTagMaxApertureAtMaxFocal} {
+		if value, err := mn.ReadValue(tag); err == nil {
+			lens[n], _ = value.(float64)
+		}
+	}
+	if lens[0] > 0 {
+		info.Fields["LensSpec"] = fujifilmLensSpec(lens)
+	}
+}
+
+// fujifilmLensSpec formats the focal range and apertures as e.g. "18-55mm F2.8-4"
+func fujifilmLensSpec(lens [4]float64) string {
+	text := fmt.Sprintf("%gmm", lens[0])
+	if lens[1] > lens[0] {
+		text = fmt.Sprintf("%g-%gmm", lens[0], lens[1])
+	}
+	if lens[2] > 0 {
+		text += fmt.Sprintf(" F%g", lens[2])
+		if lens[3] > lens[2] {
+			text += fmt.Sprintf("-%g", lens[3])
+		}
+	}
+	return text
+}
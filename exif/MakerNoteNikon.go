@@ -0,0 +1,287 @@
+package ImgMeta
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*
+Structure of a Nikon MakerNote
+
+Nikon used three formats over the years:
+
+    [Type]  [Header]                  [description]
+    ---------------------------------------
+    1       "Nikon\000\001\000"       early Coolpix, IFD at offset 8, offsets relative to the EXIF TIFF header
+    2       none                      early D1/E-series, IFD at offset 0, offsets relative to the EXIF TIFF header
+    3       "Nikon\000\002\020\000\000" all later cameras, a complete TIFF header ("II*\000" or "MM\000*") at
+                                      offset 10 with its own byte order, offsets relative to that TIFF header
+
+Some blocks of a type 3 maker note are encrypted, they start with a 4 byte ASCII version (e.g. "0204") that is
+not encrypted, the rest of the block is XOR-ed with a key stream derived from the SerialNumber (0x001D) and
+ShutterCount (0x00A7) tags:
+
+    key = XOR of the 4 bytes of ShutterCount
+    ci  = xlat[0][SerialNumber & 0xFF], cj = xlat[1][key], ck = 0x60
+    for every byte: cj += ci * ck; ck++; byte ^= cj
+
+A SerialNumber that is not numeric is replaced by 0x22 for the D50 and 0x60 for other models.
+
+    [Tag]   [Name]              [description]
+    ---------------------------------------
+    0x0091  ShotInfo            encrypted from offset 4 for version 02xx and up
+    0x0097  ColorBalance        encrypted from offset 4 (0205-0208) or 284 (0209 and up)
+    0x0098  LensData            encrypted from offset 4 for version 0201 and up
+
+The lens is identified by 8 bytes of LensData and the LensType tag, printed as hex these form the key that is
+used by lens databases (e.g. "92 2C 2D 88 2C 40 4B 0E" for the AF-S 24-70mm f/2.8G), this key is returned as
+the LensID.
+
+*/
+
+// Nikon MakerNote tags
+const (
+	NikonTagMakerNoteVersion   uint16 = 0x0001
+	NikonTagISO                uint16 = 0x0002
+	NikonTagQuality            uint16 = 0x0004
+	NikonTagWhiteBalance       uint16 = 0x0005
+	NikonTagFocusMode          uint16 = 0x0007
+	NikonTagSerialNumber       uint16 = 0x001D
+	NikonTagVRInfo             uint16 = 0x001F
+	NikonTagActiveDLighting    uint16 = 0x0022
+	NikonTagPictureControlData uint16 = 0x0023
+	NikonTagLensType           uint16 = 0x0083
+	NikonTagLens               uint16 = 0x0084
+	NikonTagManualFocusDist    uint16 = 0x0085
+	NikonTagAFInfo             uint16 = 0x0088
+	NikonTagShotInfo           uint16 = 0x0091
+	NikonTagColorBalance       uint16 = 0x0097
+	NikonTagLensData           uint16 = 0x0098
+	NikonTagShutterCount       uint16 = 0x00A7
+	NikonTagFlashInfo          uint16 = 0x00A8
+)
+
+var aNikonXlat = [2][256]byte{
+	{0xc1, 0xbf, 0x6d, 0x0d, 0x59, 0xc5, 0x13, 0x9d, 0x83, 0x61, 0x6b, 0x4f, 0xc7, 0x7f, 0x3d, 0x3d,
+		0x53, 0x59, 0xe3, 0xc7, 0xe9, 0x2f, 0x95, 0xa7, 0x95, 0x1f, 0xdf, 0x7f, 0x2b, 0x29, 0xc7, 0x0d,
+		0xdf, 0x07, 0xef, 0x71, 0x89, 0x3d, 0x13, 0x3d, 0x3b, 0x13, 0xfb, 0x0d, 0x89, 0xc1, 0x65, 0x1f,
+		0xb3, 0x0d, 0x6b, 0x29, 0xe3, 0xfb, 0xef, 0xa3, 0x6b, 0x47, 0x7f, 0x95, 0x35, 0xa7, 0x47, 0x4f,
+		0xc7, 0xf1, 0x59, 0x95, 0x35, 0x11, 0x29, 0x61, 0xf1, 0x3d, 0xb3, 0x2b, 0x0d, 0x43, 0x89, 0xc1,
+		0x9d, 0x9d, 0x89, 0x65, 0xf1, 0xe9, 0xdf, 0xbf, 0x3d, 0x7f, 0x53, 0x97, 0xe5, 0xe9, 0x95, 0x17,
+		0x1d, 0x3d, 0x8b, 0xfb, 0xc7, 0xe3, 0x67, 0xa7, 0x07, 0xf1, 0x71, 0xa7, 0x53, 0xb5, 0x29, 0x89,
+		0xe5, 0x2b, 0xa7, 0x17, 0x29, 0xe9, 0x4f, 0xc5, 0x65, 0x6d, 0x6b, 0xef, 0x0d, 0x89, 0x49, 0x2f,
+		0xb3, 0x43, 0x53, 0x65, 0x1d, 0x49, 0xa3, 0x13, 0x89, 0x59, 0xef, 0x6b, 0xef, 0x65, 0x1d, 0x0b,
+		0x59, 0x13, 0xe3, 0x4f, 0x9d, 0xb3, 0x29, 0x43, 0x2b, 0x07, 0x1d, 0x95, 0x59, 0x59, 0x47, 0xfb,
+		0xe5, 0xe9, 0x61, 0x47, 0x2f, 0x35, 0x7f, 0x17, 0x7f, 0xef, 0x7f, 0x95, 0x95, 0x71, 0xd3, 0xa3,
+		0x0b, 0x71, 0xa3, 0xad, 0x0b, 0x3b, 0xb5, 0xfb, 0xa3, 0xbf, 0x4f, 0x83, 0x1d, 0xad, 0xe9, 0x2f,
+		0x71, 0x65, 0xa3, 0xe5, 0x07, 0x35, 0x3d, 0x0d, 0xb5, 0xe9, 0xe5, 0x47, 0x3b, 0x9d, 0xef, 0x35,
+		0xa3, 0xbf, 0xb3, 0xdf, 0x53, 0xd3, 0x97, 0x53, 0x49, 0x71, 0x07, 0x35, 0x61, 0x71, 0x2f, 0x43,
+		0x2f, 0x11, 0xdf, 0x17, 0x97, 0xfb, 0x95, 0x3b, 0x7f, 0x6b, 0xd3, 0x25, 0xbf, 0xad, 0xc7, 0xc5,
+		0xc5, 0xb5, 0x8b, 0xef, 0x2f, 0xd3, 0x07, 0x6b, 0x25, 0x49, 0x95, 0x25, 0x49, 0x6d, 0x71, 0xc7},
+	{0xa7, 0xbc, 0xc9, 0xad, 0x91, 0xdf, 0x85, 0xe5, 0xd4, 0x78, 0xd5, 0x17, 0x46, 0x7c, 0x29, 0x4c,
+		0x4d, 0x03, 0xe9, 0x25, 0x68, 0x11, 0x86, 0xb3, 0xbd, 0xf7, 0x6f, 0x61, 0x22, 0xa2, 0x26, 0x34,
+		0x2a, 0xbe, 0x1e, 0x46, 0x14, 0x68, 0x9d, 0x44, 0x18, 0xc2, 0x40, 0xf4, 0x7e, 0x5f, 0x1b, 0xad,
+		0x0b, 0x94, 0xb6, 0x67, 0xb4, 0x0b, 0xe1, 0xea, 0x95, 0x9c, 0x66, 0xdc, 0xe7, 0x5d, 0x6c, 0x05,
+		0xda, 0xd5, 0xdf, 0x7a, 0xef, 0xf6, 0xdb, 0x1f, 0x82, 0x4c, 0xc0, 0x68, 0x47, 0xa1, 0xbd, 0xee,
+		0x39, 0x50, 0x56, 0x4a, 0xdd, 0xdf, 0xa5, 0xf8, 0xc6, 0xda, 0xca, 0x90, 0xca, 0x01, 0x42, 0x9d,
+		0x8b, 0x0c, 0x73, 0x43, 0x75, 0x05, 0x94, 0xde, 0x24, 0xb3, 0x80, 0x34, 0xe5, 0x2c, 0xdc, 0x9b,
+		0x3f, 0xca, 0x33, 0x45, 0xd0, 0xdb, 0x5f, 0xf5, 0x52, 0xc3, 0x21, 0xda, 0xe2, 0x22, 0x72, 0x6b,
+		0x3e, 0xd0, 0x5b, 0xa8, 0x87, 0x8c, 0x06, 0x5d, 0x0f, 0xdd, 0x09, 0x19, 0x93, 0xd0, 0xb9, 0xfc,
+		0x8b, 0x0f, 0x84, 0x60, 0x33, 0x1c, 0x9b, 0x45, 0xf1, 0xf0, 0xa3, 0x94, 0x3a, 0x12, 0x77, 0x33,
+		0x4d, 0x44, 0x78, 0x28, 0x3c, 0x9e, 0xfd, 0x65, 0x57, 0x16, 0x94, 0x6b, 0xfb, 0x59, 0xd0, 0xc8,
+		0x22, 0x36, 0xdb, 0xd2, 0x63, 0x98, 0x43, 0xa1, 0x04, 0x87, 0x86, 0xf7, 0xa6, 0x26, 0xbb, 0xd6,
+		0x59, 0x4d, 0xbf, 0x6a, 0x2e, 0xaa, 0x2b, 0xef, 0xe6, 0x78, 0xb6, 0x4e, 0xe0, 0x2f, 0xdc, 0x7c,
+		0xbe, 0x57, 0x19, 0x32, 0x7e, 0x2a, 0xd0, 0xb8, 0xba, 0x29, 0x00, 0x3c, 0x52, 0x7d, 0xa8, 0x49,
+		0x3b, 0x2d, 0xeb, 0x25, 0x49, 0xfa, 0xa3, 0xaa, 0x39, 0xa7, 0xc5, 0xa7, 0x50, 0x11, 0x36, 0xfb,
+		0xc6, 0x67, 0x4a, 0xf5, 0xa5, 0x12, 0x65, 0x7e, 0xb0, 0xdf, 0xaf, 0x4e, 0xb3, 0x61, 0x7f, 0x2f},
+}
+
+// tNikonLensData gives the position of the lens fields in the (decrypted) LensData block per version
+type tNikonLensData struct {
+	focusDistance int
+	focalLength   int
+	lensIDNumber  int // followed by LensFStops, Min/MaxFocalLength, MaxApertureAtMin/MaxFocal and MCUVersion
+}
+
+var aNikonLensData = map[string]tNikonLensData{
+	"0100": {focusDistance: -1, focalLength: -1, lensIDNumber: 6},
+	"0101": {focusDistance: 9, focalLength: 10, lensIDNumber: 11},
+	"0201": {focusDistance: 9, focalLength: 10, lensIDNumber: 11},
+	"0202": {focusDistance: 9, focalLength: 10, lensIDNumber: 11},
+	"0203": {focusDistance: 9, focalLength: 10, lensIDNumber: 11},
+	"0204": {focusDistance: 10, focalLength: 11, lensIDNumber: 12},
+}
+
+var cMakerNoteNikon = tMakerNoteVendor{
+	name: "Nikon",
+	detect: func(make string, note []byte) bool {
+		return strings.HasPrefix(string(note), "Nikon\x00") || strings.HasPrefix(strings.ToUpper(make), "NIKON")
+	},
+	open: func(mn *tMakerNoteAPP) (tExifIFD, error) {
+		note := mn.Block()
+		if strings.HasPrefix(string(note), "Nikon\x00") && len(note) > 6 {
+			if note[6] == 1 {
+				return mn.mainIFD(8, cEXIFTIFFOffset, mn.exif.TIFFByteOrder())
+			}
+			return mn.embeddedTIFF(10)
+		}
+		return mn.mainIFD(0, cEXIFTIFFOffset, mn.exif.TIFFByteOrder())
+	},
+	decode: decodeNikonMakerNote,
+}
+
+// nikonDecrypt decrypts data from offset start on with the key derived from serial and shutter count
+func nikonDecrypt(data []byte, start int, serial uint32, count uint32) []byte {
+	decrypted := append([]byte{}, data...)
+	key := byte(count) ^ byte(count>>8) ^ byte(count>>16) ^ byte(count>>24)
+	ci := aNikonXlat[0][serial&0xFF]
+	cj := aNikonXlat[1][key]
+	ck := byte(0x60)
+	for i := start; i < len(decrypted); i++ {
+		cj += ci * ck
+		ck++
+		decrypted[i] ^= cj
+	}
+	return decrypted
+}
+
+// nikonSerialKey returns the serial number used for decryption
+func nikonSerialKey(serial string, model string) uint32 {
+	if n, err := strconv.ParseUint(serial, 10, 32); err == nil {
+		return uint32(n)
+	}
+	if strings.HasSuffix(strings.TrimSpace(model), "D50") {
+		return 0x22
+	}
+	return 0x60
+}
+
+func decodeNikonMakerNote(mn *tMakerNoteAPP, info *MakerNote) {
+	info.SerialNumber = mn.readString(NikonTagSerialNumber)
+	if values := mn.readInts(NikonTagShutterCount); len(values) == 1 {
+		info.ShutterCount = uint32(values[0])
+	}
+	serial := nikonSerialKey(info.SerialNumber, mn.model)
+
+	for tag, name := range map[uint16]string{
+		NikonTagQuality:      "Quality",
+		NikonTagWhiteBalance: "WhiteBalance",
+		NikonTagFocusMode:    "FocusMode",
+	} {
+		if value := mn.readString(tag); value != "" {
+			info.Fields[name] = value
+		}
+	}
+	if values := mn.readInts(NikonTagISO); len(values) == 2 {
+		info.Fields["ISO"] = values[1]
+	}
+
+	// Picture control, the name and base are at the same position in all versions
+	if data := mn.readBytes(NikonTagPictureControlData); len(data) >= 44 {
+		info.PictureMode = nikonString(data[4:24])
+		info.Fields["PictureControlVersion"] = string(data[:4])
+		info.Fields["PictureControlName"] = info.PictureMode
+		info.Fields["PictureControlBase"] = nikonString(data[24:44])
+	}
+
+	// Vibration reduction, the version is followed by 1 = On, 2 = Off
+	if data := mn.readBytes(NikonTagVRInfo); len(data) > 4 {
+		switch data[4] {
+		case 1:
+			info.ImageStabilization = "On"
+		case 2:
+			info.ImageStabilization = "Off"
+		}
+	}
+
+	// Encrypted blocks
+	if data := mn.readBytes(NikonTagShotInfo); len(data) > 4 {
+		version := string(data[:4])
+		if version >= "0200" {
+			data = nikonDecrypt(data, 4, serial, info.ShutterCount)
+		}
+		info.Fields["ShotInfoVersion"] = version
+		info.Fields["ShotInfo"] = data
+	}
+	if data := mn.readBytes(NikonTagColorBalance); len(data) > 4 {
+		version := string(data[:4])
+		if version >= "0209" {
+			if len(data) > 284 {
+				data = nikonDecrypt(data, 284, serial, info.ShutterCount)
+			}
+		} else if version >= "0205" {
+			data = nikonDecrypt(data, 4, serial, info.ShutterCount)
+		}
+		info.Fields["ColorBalanceVersion"] = version
+		info.Fields["ColorBalance"] = data
+	}
+	if data := mn.readBytes(NikonTagLensData); len(data) > 4 {
+		version := string(data[:4])
+		if version >= "0201" {
+			data = nikonDecrypt(data, 4, serial, info.ShutterCount)
+		}
+		info.Fields["LensDataVersion"] = version
+		info.Fields["LensData"] = data
+		decodeNikonLensData(mn, version, data, info)
+	}
+
+	// Lens, given as min/max focal length and min/max aperture
+	if value, err := mn.ReadValue(NikonTagLens); err == nil {
+		if lens, ok := value.([]float64); ok && len(lens) == 4 {
+			info.LensModel = nikonLensModel(lens)
+		}
+	}
+}
+
+// decodeNikonLensData decodes the lens id and focus distance from a decrypted LensData block
+func decodeNikonLensData(mn *tMakerNoteAPP, version string, data []byte, info *MakerNote) {
+	layout, known := aNikonLensData[version]
+	if !known {
+		return
+	}
+	if layout.focusDistance >= 0 && layout.focusDistance < len(data) && data[layout.focusDistance] != 0 {
+		// meters, 0.01 * 10^(value/40)
+		info.Fields["FocusDistance"] = 0.01 * math.Pow(10, float64(data[layout.focusDistance])/40)
+	}
+	if layout.focalLength >= 0 && layout.focalLength < len(data) && data[layout.focalLength] != 0 {
+		// mm, 5 * 2^(value/24)
+		info.Fields["FocalLength"] = 5 * math.Pow(2, float64(data[layout.focalLength])/24)
+	}
+	if layout.lensIDNumber+7 <= len(data) {
+		lensType := byte(0)
+		if values := mn.readInts(NikonTagLensType); len(values) == 1 {
+			lensType = byte(values[0])
+		}
+		id := append(append([]byte{}, data[layout.lensIDNumber:layout.lensIDNumber+7]...), lensType)
+		info.LensID = strings.ToUpper(fmt.Sprintf("% x", id))
+		info.Fields["LensIDNumber"] = int64(id[0])
+	}
+}
+
+// nikonLensModel formats the Lens tag as e.g. "24-70mm f/2.8"
+func nikonLensModel(lens []float64) string {
+	format := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	model := format(lens[0]) + "mm"
+	if lens[1] != lens[0] {
+		model = format(lens[0]) + "-" + format(lens[1]) + "mm"
+	}
+	if lens[2] > 0 {
+		model += " f/" + format(lens[2])
+		if lens[3] != lens[2] && lens[3] > 0 {
+			model += "-" + format(lens[3])
+		}
+	}
+	return model
+}
+
+// nikonString returns a NUL padded string
+func nikonString(data []byte) string {
+	if n := strings.IndexByte(string(data), 0); n >= 0 {
+		data = data[:n]
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,137 @@
+package ImgMeta
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+Structure of an Olympus / OM System MakerNote
+
+    [Header]                        [description]
+    ---------------------------------------
+    "OLYMP\000\001\000"             old models, IFD at offset 8, offsets relative to the EXIF TIFF header
+    "OLYMP\000\002\000"             idem
+    "OLYMPUS\000II\003\000"         new models, byte order at offset 8, IFD at offset 12, offsets relative to
+                                    the start of the maker note
+    "OM SYSTEM\000\000\000II\004\000" OM Digital Solutions, byte order at offset 12, IFD at offset 16, offsets
+                                    relative to the start of the maker note
+
+The new format keeps almost everything in sub-IFDs, the main IFD holds their offsets:
+
+    [Tag]   [Name]              [description]
+    ---------------------------------------
+    0x2010  Equipment           serial numbers, lens type and model
+    0x2020  CameraSettings      picture mode, image stabilization, focus, ...
+    0x2030  RawDevelopment
+    0x2040  ImageProcessing
+    0x2050  FocusInfo
+
+*/
+
+// Olympus MakerNote tags
+const (
+	OlympusTagEquipment       uint16 = 0x2010
+	OlympusTagCameraSettings  uint16 = 0x2020
+	OlympusTagRawDevelopment  uint16 = 0x2030
+	OlympusTagImageProcessing uint16 = 0x2040
+	OlympusTagFocusInfo       uint16 = 0x2050
+)
+
+// Olympus Equipment tags
+const (
+	OlympusTagCameraType2          uint16 = 0x0100
+	OlympusTagSerialNumber         uint16 = 0x0101
+	OlympusTagInternalSerialNumber uint16 = 0x0102
+	OlympusTagBodyFirmwareVersion  uint16 = 0x0104
+	OlympusTagLensType             uint16 = 0x0201
+	OlympusTagLensSerialNumber     uint16 = 0x0202
+	OlympusTagLensModel            uint16 = 0x0203
+	OlympusTagLensFirmwareVersion  uint16 = 0x0204
+)
+
+// Olympus CameraSettings tags
+const (
+	OlympusTagPictureMode        uint16 = 0x0520
+	OlympusTagImageStabilization uint16 = 0x0604
+	OlympusTagArtFilter          uint16 = 0x0529
+)
+
+var aOlympusPictureMode = map[int64]string{
+	1:   "Vivid",
+	2:   "Natural",
+	3:   "Muted",
+	4:   "Portrait",
+	5:   "i-Enhance",
+	6:   "e-Portrait",
+	7:   "Color Creator",
+	9:   "Color Profile 1",
+	10:  "Color Profile 2",
+	11:  "Color Profile 3",
+	12:  "Monochrome Profile 1",
+	13:  "Monochrome Profile 2",
+	14:  "Monochrome Profile 3",
+	17:  "Art Mode",
+	18:  "Monochrome Profile 4",
+	256: "Monotone",
+	512: "Sepia",
+}
+
+var aOlympusImageStabilization = map[int64]string{
+	0: "Off",
+	1: "On, Mode 1",
+	2: "On, Mode 2",
+	3: "On, Mode 3",
+	4: "On, Mode 4",
+}
+
+var cMakerNoteOlympus = tMakerNoteVendor{
+	name: "Olympus",
+	detect: func(make string, note []byte) bool {
+		return strings.HasPrefix(string(note), "OLYMP\x00") || strings.HasPrefix(string(note), "OLYMPUS\x00") ||
+			strings.HasPrefix(string(note), "OM SYSTEM\x00")
+	},
+	open: func(mn *tMakerNoteAPP) (tExifIFD, error) {
+		note := string(mn.Block())
+		switch {
+		case strings.HasPrefix(note, "OLYMPUS\x00"):
+			return mn.mainIFD(12, mn.offset, makerNoteByteOrder(note[8:]))
+		case strings.HasPrefix(note, "OM SYSTEM\x00"):
+			if len(note) < 16 {
+				return tExifIFD{}, &exifError{"Olympus MakerNote is too small"}
+			}
+			return mn.mainIFD(16, mn.offset, makerNoteByteOrder(note[12:]))
+		}
+		return mn.mainIFD(8, cEXIFTIFFOffset, mn.exif.TIFFByteOrder())
+	},
+	decode: decodeOlympusMakerNote,
+}
+
+func decodeOlympusMakerNote(mn *tMakerNoteAPP, info *MakerNote) {
+	if equipment, ok := mn.subIFD(OlympusTagEquipment); ok {
+		info.SerialNumber = equipment.readString(OlympusTagSerialNumber)
+		info.LensSerialNumber = equipment.readString(OlympusTagLensSerialNumber)
+		info.LensModel = equipment.readString(OlympusTagLensModel)
+		if lensType := equipment.readInts(OlympusTagLensType); len(lensType) == 6 {
+			// make, unknown, model and sub-model
+			info.LensID = fmt.Sprintf("%x %02x %02x", lensType[0], lensType[2], lensType[3])
+		}
+		if value := equipment.readString(OlympusTagCameraType2); value != "" {
+			info.Fields["CameraType2"] = value
+		}
+		if value := equipment.readString(OlympusTagInternalSerialNumber); value != "" {
+			info.Fields["InternalSerialNumber"] = value
+		}
+	}
+	if settings, ok := mn.subIFD(OlympusTagCameraSettings); ok {
+		if values := settings.readInts(OlympusTagPictureMode); len(values) >= 1 {
+			info.PictureMode = makerNoteName(aOlympusPictureMode, values[0])
+		}
+		if values := settings.readInts(OlympusTagImageStabilization); len(values) == 1 {
+			info.ImageStabilization = makerNoteName(aOlympusImageStabilization, values[0])
+		}
+		if values := settings.readInts(OlympusTagArtFilter); len(values) >= 1 {
+			info.Fields["ArtFilter"] = values[0]
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package ImgMeta
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+Structure of a Panasonic MakerNote
+
+    [Record name]    [size]   [description]
+    ---------------------------------------
+    Identifier      12 bytes  ("Panasonic\000\000\000")
+    IFD                 ...   byte order of the EXIF segment, offsets relative to the EXIF TIFF header
+
+    [Tag]   [Name]              [description]
+    ---------------------------------------
+    0x0002  FirmwareVersion     UNDEFINED[4]
+    0x001A  ImageStabilization  int16u
+    0x0025  InternalSerialNumber UNDEFINED[16]
+    0x0051  LensType            ASCII, the lens model
+    0x0052  LensSerialNumber    ASCII
+    0x0089  PhotoStyle          int16u
+
+*/
+
+// Panasonic MakerNote tags
+const (
+	PanasonicTagFirmwareVersion      uint16 = 0x0002
+	PanasonicTagImageStabilization   uint16 = 0x001A
+	PanasonicTagInternalSerialNumber uint16 = 0x0025
+	PanasonicTagLensType             uint16 = 0x0051
+	PanasonicTagLensSerialNumber     uint16 = 0x0052
+	PanasonicTagPhotoStyle           uint16 = 0x0089
+)
+
+var aPanasonicImageStabilization = map[int64]string{
+	2:  "On, Optical",
+	3:  "Off",
+	4:  "On, Mode 2",
+	5:  "On, Optical Panning",
+	6:  "On, Body-only",
+	7:  "On, Body-only Panning",
+	9:  "Dual IS",
+	10: "Dual IS Panning",
+	11: "Dual2 IS",
+	12: "Dual2 IS Panning",
+}
+
+var aPanasonicPhotoStyle = map[int64]string{
+	0:  "Auto",
+	1:  "Standard or Custom",
+	2:  "Vivid",
+	3:  "Natural",
+	4:  "Monochrome",
+	5:  "Scenery",
+	6:  "Portrait",
+	8:  "Cinelike D",
+	9:  "Cinelike V",
+	11: "L. Monochrome",
+	12: "Like709",
+	15: "L. Monochrome D",
+	17: "V-Log",
+	18: "Cinelike D2",
+}
+
+var cMakerNotePanasonic = tMakerNoteVendor{
+	name: "Panasonic",
+	detect: func(make string, note []byte) bool {
+		return strings.HasPrefix(string(note), "Panasonic\x00")
+	},
+	open: func(mn *tMakerNoteAPP) (tExifIFD, error) {
+		return mn.mainIFD(12, cEXIFTIFFOffset, mn.exif.TIFFByteOrder())
+	},
+	decode: decodePanasonicMakerNote,
+}
+
+func decodePanasonicMakerNote(mn *tMakerNoteAPP, info *MakerNote) {
+	info.SerialNumber = mn.readString(PanasonicTagInternalSerialNumber)
+	info.LensModel = mn.readString(PanasonicTagLensType)
+	info.LensSerialNumber = mn.readString(PanasonicTagLensSerialNumber)
+	if values := mn.readInts(PanasonicTagImageStabilization); len(values) == 1 {
+		info.ImageStabilization = makerNoteName(aPanasonicImageStabilization, values[0])
+	}
+	if values := mn.readInts(PanasonicTagPhotoStyle); len(values) == 1 {
+		info.PictureMode = makerNoteName(aPanasonicPhotoStyle, values[0])
+	}
+	if version := mn.readBytes(PanasonicTagFirmwareVersion); len(version) == 4 {
+		info.Fields["FirmwareVersion"] = panasonicFirmwareVersion(version)
+	}
+}
+
+// panasonicFirmwareVersion formats the 4 version bytes, most bodies store the numbers (0 1 2 1 is "0.1.2.1") and
+// some the ASCII digits ("0121")
+func panasonicFirmwareVersion(version []byte) string {
+	for _, b := range version {
+		if b >= 10 {
+			return strings.TrimRight(string(version), "\x00 ")
+		}
+	}
+	return fmt.Sprintf("%d.%d.%d.%d", version[0], version[1], version[2], version[3])
+}
@@ -0,0 +1,100 @@
+package ImgMeta
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+/*
+Structure of a Pentax MakerNote
+
+    [Header]                  [description]
+    ---------------------------------------
+    "AOC\000" + "MM"/"II"     IFD at offset 6, offsets relative to the EXIF TIFF header; two spaces instead
+                              of the byte order mean the byte order of the EXIF segment
+    "PENTAX \000" + "MM"/"II" IFD at offset 10, offsets relative to the start of the maker note
+
+    [Tag]   [Name]              [description]
+    ---------------------------------------
+    0x0006  Date                UNDEFINED[4], used to encrypt the shutter count
+    0x0007  Time                UNDEFINED[3], used to encrypt the shutter count
+    0x003F  LensRec             int8u[2], lens type
+    0x004F  ImageTone           int16u, custom image
+    0x005C  ShakeReductionInfo  UNDEFINED[4..], the second byte holds the shake reduction state
+    0x005D  ShutterCount        UNDEFINED[4], encrypted: count ^ Date ^ ^(Time << 8)
+    0x0229  SerialNumber        ASCII
+
+*/
+
+// Pentax MakerNote tags
+const (
+	PentaxTagDate               uint16 = 0x0006
+	PentaxTagTime               uint16 = 0x0007
+	PentaxTagLensRec            uint16 = 0x003F
+	PentaxTagImageTone          uint16 = 0x004F
+	PentaxTagShakeReductionInfo uint16 = 0x005C
+	PentaxTagShutterCount       uint16 = 0x005D
+	PentaxTagSerialNumber       uint16 = 0x0229
+)
+
+var aPentaxImageTone = map[int64]string{
+	0:  "Natural",
+	1:  "Bright",
+	2:  "Portrait",
+	3:  "Landscape",
+	4:  "Vibrant",
+	5:  "Monochrome",
+	6:  "Muted",
+	7:  "Reversal Film",
+	8:  "Bleach Bypass",
+	9:  "Radiant",
+	10: "Cross Processing",
+	11: "Flat",
+	12: "Auto",
+}
+
+var cMakerNotePentax = tMakerNoteVendor{
+	name: "Pentax",
+	detect: func(make string, note []byte) bool {
+		return strings.HasPrefix(string(note), "AOC\x00") || strings.HasPrefix(string(note), "PENTAX \x00")
+	},
+	open: func(mn *tMakerNoteAPP) (tExifIFD, error) {
+		note := string(mn.Block())
+		if strings.HasPrefix(note, "PENTAX \x00") {
+			if len(note) < 10 {
+				return tExifIFD{}, &exifError{"Pentax MakerNote is too small"}
+			}
+			return mn.mainIFD(10, mn.offset, makerNoteByteOrder(note[8:]))
+		}
+		endian := mn.exif.TIFFByteOrder()
+		if strings.HasPrefix(note[4:], "MM") || strings.HasPrefix(note[4:], "II") {
+			endian = makerNoteByteOrder(note[4:])
+		}
+		return mn.mainIFD(6, cEXIFTIFFOffset, endian)
+	},
+	decode: decodePentaxMakerNote,
+}
+
+func decodePentaxMakerNote(mn *tMakerNoteAPP, info *MakerNote) {
+	info.SerialNumber = mn.readString(PentaxTagSerialNumber)
+	if lens := mn.readInts(PentaxTagLensRec); len(lens) >= 2 {
+		info.LensID = fmt.Sprintf("%d %d", lens[0], lens[1])
+	}
+	if values := mn.readInts(PentaxTagImageTone); len(values) == 1 {
+		info.PictureMode = makerNoteName(aPentaxImageTone, values[0])
+	}
+	if sr := mn.readBytes(PentaxTagShakeReductionInfo); len(sr) >= 2 {
+		if sr[1]&0x01 != 0 {
+			info.ImageStabilization = "On"
+		} else {
+			info.ImageStabilization = "Off"
+		}
+	}
+
+	count, date, time := mn.readBytes(PentaxTagShutterCount), mn.readBytes(PentaxTagDate), mn.readBytes(PentaxTagTime)
+	if len(count) == 4 && len(date) == 4 && len(time) == 3 {
+		key := binary.BigEndian.Uint32(date) ^ ^binary.BigEndian.Uint32(append(append([]byte{}, time...), 0))
+		info.ShutterCount = binary.BigEndian.Uint32(count) ^ key
+	}
+}
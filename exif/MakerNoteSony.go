@@ -0,0 +1,116 @@
+package ImgMeta
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+Structure of a Sony MakerNote
+
+Sony writes an IFD in the byte order of the EXIF segment with the value offsets relative to the TIFF header of
+the EXIF segment. Cameras write a 12 byte header in front of the IFD, some phones and older models write none:
+
+    [Header]                  [description]
+    ---------------------------------------
+    "SONY DSC \000\000\000"   compact and interchangeable lens cameras
+    "SONY CAM \000\000\000"   camcorders
+    "SONY MOBILE\000"         phones
+    none                      IFD at offset 0
+
+    [Tag]   [Name]              [description]
+    ---------------------------------------
+    0xB000  FileFormat          int8u[4]
+    0xB001  SonyModelID         int16u
+    0xB020  CreativeStyle       ASCII, e.g. "Standard", "Vivid", "Portrait", "BW"
+    0xB026  ImageStabilization  int32u, 0 = Off, 1 = On
+    0xB027  LensType            int32u, A-mount lens id (65535 for E-mount lenses)
+    0xB02A  LensSpec            int8u[8], BCD encoded focal range and apertures
+    0xB02B  FullImageSize       int32u[2]
+    0x9050  Tag9050             enciphered, holds the shutter count on most models
+
+Sony does not store the lens model or serial numbers in the maker note, these come from the EXIF LensModel,
+BodySerialNumber and LensSerialNumber tags.
+
+*/
+
+// Sony MakerNote tags
+const (
+	SonyTagFileFormat         uint16 = 0xB000
+	SonyTagModelID            uint16 = 0xB001
+	SonyTagCreativeStyle      uint16 = 0xB020
+	SonyTagImageStabilization uint16 = 0xB026
+	SonyTagLensType           uint16 = 0xB027
+	SonyTagLensSpec           uint16 = 0xB02A
+	SonyTagFullImageSize      uint16 = 0xB02B
+	SonyTagLensType2          uint16 = 0xB02C
+)
+
+var aSonyHeaders = []string{"SONY DSC \x00\x00\x00", "SONY CAM \x00\x00\x00", "SONY MOBILE\x00"}
+
+var cMakerNoteSony = tMakerNoteVendor{
+	name: "Sony",
+	detect: func(make string, note []byte) bool {
+		for _, header := range aSonyHeaders {
+			if strings.HasPrefix(string(note), header) {
+				return true
+			}
+		}
+		return strings.HasPrefix(strings.ToUpper(make), "SONY")
+	},
+	open: func(mn *tMakerNoteAPP) (tExifIFD, error) {
+		for _, header := range aSonyHeaders {
+			if strings.HasPrefix(string(mn.Block()), header) {
+				return mn.mainIFD(uint32(len(header)), cEXIFTIFFOffset, mn.exif.TIFFByteOrder())
+			}
+		}
+		return mn.mainIFD(0, cEXIFTIFFOffset, mn.exif.TIFFByteOrder())
+	},
+	decode: decodeSonyMakerNote,
+}
+
+func decodeSonyMakerNote(mn *tMakerNoteAPP, info *MakerNote) {
+	info.PictureMode = mn.readString(SonyTagCreativeStyle)
+	if values := mn.readInts(SonyTagImageStabilization); len(values) == 1 {
+		switch values[0] {
+		case 0:
+			info.ImageStabilization = "Off"
+		case 1:
+			info.ImageStabilization = "On"
+		}
+	}
+	if values := mn.readInts(SonyTagLensType); len(values) == 1 && values[0] != 0xFFFF {
+		info.LensID = fmt.Sprintf("%d", values[0])
+	}
+	if values := mn.readInts(SonyTagModelID); len(values) == 1 {
+		info.Fields["SonyModelID"] = values[0]
+	}
+	if spec := mn.readBytes(SonyTagLensSpec); len(spec) == 8 {
+		info.Fields["LensSpec"] = sonyLensSpec(spec)
+	}
+}
+
+// sonyLensSpec formats the BCD encoded LensSpec as e.g. "16-35mm F2.8", the first and last byte hold
+// flags (e.g. G, ZA, OSS) that are not decoded.
+func sonyLensSpec(spec []byte) string {
+	bcd := func(b ...byte) int {
+		n := 0
+		for _, v := range b {
+			n = n*100 + int(v>>4)*10 + int(v&0x0F)
+		}
+		return n
+	}
+	short, long := bcd(spec[1], spec[2]), bcd(spec[3], spec[4])
+	wide, tele := float64(bcd(spec[5]))/10, float64(bcd(spec[6]))/10
+	text := fmt.Sprintf("%dmm", short)
+	if long > short {
+		text = fmt.Sprintf("%d-%dmm", short, long)
+	}
+	if wide > 0 {
+		text += fmt.Sprintf(" F%g", wide)
+		if tele > wide {
+			text += fmt.Sprintf("-%g", tele)
+		}
+	}
+	return text
+}
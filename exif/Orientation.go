@@ -0,0 +1,256 @@
+package ImgMeta
+
+import (
+	"fmt"
+	"image"
+	"strconv"
+)
+
+/*
+Orientation
+
+The EXIF Orientation tag (0x0112) tells how the stored pixels have to be transformed to display the image upright.
+The name gives where row 0 and column 0 of the stored image end up:
+
+    Value  Name         Transform to display                 Swaps width/height
+    1      TopLeft      none                                 no
+    2      TopRight     flip horizontal                      no
+    3      BottomRight  rotate 180                           no
+    4      BottomLeft   flip vertical                        no
+    5      LeftTop      transpose (flip, rotate 270 cw)      yes
+    6      RightTop     rotate 90 cw                         yes
+    7      RightBottom  transverse (flip, rotate 90 cw)      yes
+    8      LeftBottom   rotate 270 cw                        yes
+
+Phones store portrait photos as landscape pixels with orientation 6 (or 8), the display size therefore is the frame
+size with width and height swapped.
+
+*/
+
+// Orientation is the value of the EXIF Orientation tag
+type Orientation uint16
+
+// Orientations
+const (
+	OrientationTopLeft     Orientation = 1
+	OrientationTopRight    Orientation = 2
+	OrientationBottomRight Orientation = 3
+	OrientationBottomLeft  Orientation = 4
+	OrientationLeftTop     Orientation = 5
+	OrientationRightTop    Orientation = 6
+	OrientationRightBottom Orientation = 7
+	OrientationLeftBottom  Orientation = 8
+)
+
+type tOrientationDescr struct {
+	name     string
+	rotation int  // clockwise rotation in degrees, after the flip
+	flip     bool // horizontal flip, before the rotation
+}
+
+var aOrientationDescr = map[Orientation]tOrientationDescr{
+	OrientationTopLeft:     {name: "TopLeft", rotation: 0, flip: false},
+	OrientationTopRight:    {name: "TopRight", rotation: 0, flip: true},
+	OrientationBottomRight: {name: "BottomRight", rotation: 180, flip: false},
+	OrientationBottomLeft:  {name: "BottomLeft", rotation: 180, flip: true},
+	OrientationLeftTop:     {name: "LeftTop", rotation: 270, flip: true},
+	OrientationRightTop:    {name: "RightTop", rotation: 90, flip: false},
+	OrientationRightBottom: {name: "RightBottom", rotation: 90, flip: true},
+	OrientationLeftBottom:  {name: "LeftBottom", rotation: 270, flip: false},
+}
+
+// Valid returns true for the orientations 1 to 8
+func (o Orientation) Valid() bool {
+	_, valid := aOrientationDescr[o]
+	return valid
+}
+
+func (o Orientation) String() string {
+	if descr, valid := aOrientationDescr[o]; valid {
+		return descr.name
+	}
+	return "Orientation(" + strconv.Itoa(int(o)) + ")"
+}
+
+// Rotation returns the transform to display the image: a horizontal flip (when flip is true) followed by a
+// clockwise rotation over 0, 90, 180 or 270 degrees. Invalid orientations need no transform.
+func (o Orientation) Rotation() (degrees int, flip bool) {
+	descr := aOrientationDescr[o]
+	return descr.rotation, descr.flip
+}
+
+// SwapsDimensions returns true when the displayed image has the width and height of the stored image swapped
+func (o Orientation) SwapsDimensions() bool {
+	return o >= OrientationLeftTop && o <= OrientationLeftBottom
+}
+
+// Inverse returns the orientation that undoes this one
+func (o Orientation) Inverse() Orientation {
+	switch o {
+	case OrientationRightTop:
+		return OrientationLeftBottom
+	case OrientationLeftBottom:
+		return OrientationRightTop
+	}
+	return o
+}
+
+// Transform returns the affine transform from stored to displayed image coordinates, for an image of the given
+// (stored) size: x' = t[0]*x + t[1]*y + t[2], y' = t[3]*x + t[4]*y + t[5].
+func (o Orientation) Transform(width float64, height float64) [6]float64 {
+	switch o {
+	case OrientationTopRight:
+		return [6]float64{-1, 0, width, 0, 1, 0}
+	case OrientationBottomRight:
+		return [6]float64{-1, 0, width, 0, -1, height}
+	case OrientationBottomLeft:
+		return [6]float64{1, 0, 0, 0, -1, height}
+	case OrientationLeftTop:
+		return [6]float64{0, 1, 0, 1, 0, 0}
+	case OrientationRightTop:
+		return [6]float64{0, -1, height, 1, 0, 0}
+	case OrientationRightBottom:
+		return [6]float64{0, -1, height, -1, 0, width}
+	case OrientationLeftBottom:
+		return [6]float64{0, 1, 0, -1, 0, width}
+	}
+	return [6]float64{1, 0, 0, 0, 1, 0}
+}
+
+// displayed maps a stored pixel to its displayed position
+func (o Orientation) displayed(x int, y int, width int, height int) (int, int) {
+	switch o {
+	case OrientationTopRight:
+		return width - 1 - x, y
+	case OrientationBottomRight:
+		return width - 1 - x, height - 1 - y
+	case OrientationBottomLeft:
+		return x, height - 1 - y
+	case OrientationLeftTop:
+		return y, x
+	case OrientationRightTop:
+		return height - 1 - y, x
+	case OrientationRightBottom:
+		return height - 1 - y, width - 1 - x
+	case OrientationLeftBottom:
+		return y, width - 1 - x
+	}
+	return x, y
+}
+
+// ApplyOrientation returns the decoded image transformed for display, e.g. to make an upright thumbnail. After
+// this the orientation of the pixels is TopLeft.
+func ApplyOrientation(img image.Image, o Orientation) image.Image {
+	if o == OrientationTopLeft || !o.Valid() {
+		return img
+	}
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if o.SwapsDimensions() {
+		width, height = height, width
+	}
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			dx, dy := o.displayed(x, y, bounds.Dx(), bounds.Dy())
+			out.Set(dx, dy, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return out
+}
+
+// Orientation returns the orientation of the image from the EXIF, or else the XMP tiff:Orientation; an image
+// without orientation is TopLeft.
+func (i Image) Orientation() (Orientation, error) {
+	if app, exists := i.apps["EXIF"]; exists {
+		if value, err := app.ReadValue(ExifTagOrientation); err == nil {
+			if ints := exifInts(value); len(ints) > 0 {
+				o := Orientation(ints[0])
+				if !o.Valid() {
+					return OrientationTopLeft, &exifError{fmt.Sprintf("EXIF Orientation %d is not valid", ints[0])}
+				}
+				return o, nil
+			}
+		}
+	}
+	if value, err := i.ReadXMPValue(XmpNsTIFF, "Orientation"); err == nil {
+		n, err := strconv.Atoi(fmt.Sprint(value))
+		if o := Orientation(n); err != nil || !o.Valid() {
+			return OrientationTopLeft, &exifError{fmt.Sprintf("XMP tiff:Orientation '%v' is not valid", value)}
+		}
+		return Orientation(n), nil
+	}
+	return OrientationTopLeft, nil
+}
+
+// DisplaySize returns the size of the image as displayed, the frame size with the orientation applied
+func (i Image) DisplaySize() (width uint32, height uint32, err error) {
+	width, height, err = i.frameSize()
+	if err != nil {
+		return 0, 0, err
+	}
+	if o, _ := i.Orientation(); o.SwapsDimensions() {
+		width, height = height, width
+	}
+	return width, height, nil
+}
+
+// SetOrientation sets the EXIF Orientation, and the XMP tiff:Orientation when the XMP has it, without touching
+// the pixels. With swapDimensions the dimension tags (EXIF PixelXDimension/PixelYDimension, ImageWidth/ImageHeight
+// and their XMP counterparts) are swapped as well, for when the stored pixels were rotated by 90 or 270 degrees,
+// e.g. by a lossless rotation:
+//
+//	m.SetOrientation(OrientationTopLeft, o.SwapsDimensions())
+func (m *Metadata) SetOrientation(o Orientation, swapDimensions bool) error {
+	if !o.Valid() {
+		return &exifError{fmt.Sprintf("Orientation %d is not valid", o)}
+	}
+	exif, err := m.Exif()
+	if err != nil {
+		return err
+	}
+	if err := exif.SetTag(IFD0, ExifTagOrientation, int(o)); err != nil {
+		return err
+	}
+	if swapDimensions {
+		pairs := [][3]uint16{{IFDExif, ExifTagPixelXDimension, ExifTagPixelYDimension}, {IFD0, ExifTagImageWidth, ExifTagImageHeight}}
+		for _, pair := range pairs {
+			tags := exif.ifds[pair[0]]
+			x, hasX := tags[pair[1]]
+			y, hasY := tags[pair[2]]
+			if hasX && hasY {
+				tags[pair[1]], tags[pair[2]] = y, x
+			}
+		}
+	}
+
+	if _, exists := m.image.apps["XMP"]; !exists && m.xmp == nil {
+		return nil
+	}
+	untouched := m.xmp == nil
+	xmp, err := m.XMP()
+	if err != nil {
+		return err
+	}
+	changed := false
+	if _, exists := xmp.Property(XmpNsTIFF, "Orientation"); exists {
+		xmp.Set(XmpNsTIFF, "Orientation", int(o))
+		changed = true
+	}
+	if swapDimensions {
+		pairs := [][3]string{{XmpNsEXIF, "PixelXDimension", "PixelYDimension"}, {XmpNsTIFF, "ImageWidth", "ImageLength"}}
+		for _, pair := range pairs {
+			x, hasX := xmp.Property(pair[0], pair[1])
+			y, hasY := xmp.Property(pair[0], pair[2])
+			if hasX && hasY {
+				xmp.Set(pair[0], pair[1], fmt.Sprint(y))
+				xmp.Set(pair[0], pair[2], fmt.Sprint(x))
+				changed = true
+			}
+		}
+	}
+	if !changed && untouched {
+		m.xmp = nil
+	}
+	return nil
+}
@@ -12,7 +12,7 @@ type tSOFnAPP struct {
 }
 
 func (t tSOFnAPP) Name() string {
-	return fmt.Sprintf("SOF%01X", t.Marker()&0x0F)
+	return fmt.Sprintf("SOF%d", t.Marker()&0x0F)
 }
 func (t tSOFnAPP) Marker() uint16 {
 	return t.marker
@@ -27,21 +27,58 @@ func (t tSOFnAPP) HasID(cid []byte) bool {
 	return true
 }
 
+// All SOFn segments share the same layout, only the coding process differs
 func (t tSOFnAPP) ReadValue(tagID2Find uint16) (interface{}, error) {
-	if t.Marker()&0x0F == 0 {
-		if tagID2Find == SOF0ImageBPP {
-			return uint32(t.block[SOF0ImageBPP]), nil
-		} else if tagID2Find == SOF0ImageHeight {
-			return uint32(t.endian.Uint16(t.block[SOF0ImageHeight : SOF0ImageHeight+2])), nil
-		} else if tagID2Find == SOF0ImageWidth {
-			return uint32(t.endian.Uint16(t.block[SOF0ImageWidth : SOF0ImageWidth+2])), nil
-		}
+	if len(t.block) <= SOF0ImageComponents {
+		return int(0), &exifError{"SOF segment is too small"}
+	}
+	if tagID2Find == SOF0ImageBPP {
+		return uint32(t.block[SOF0ImageBPP]), nil
+	} else if tagID2Find == SOF0ImageHeight {
+		return uint32(t.endian.Uint16(t.block[SOF0ImageHeight : SOF0ImageHeight+2])), nil
+	} else if tagID2Find == SOF0ImageWidth {
+		return uint32(t.endian.Uint16(t.block[SOF0ImageWidth : SOF0ImageWidth+2])), nil
+	} else if tagID2Find == SOF0ImageComponents {
+		return uint32(t.block[SOF0ImageComponents]), nil
 	}
 	return int(0), nil
 }
 
+// ComponentIDs returns the identifier of each colour component in the frame
+func (t tSOFnAPP) ComponentIDs() []byte {
+	if len(t.block) <= SOF0ImageComponents {
+		return []byte{}
+	}
+	n := int(t.block[SOF0ImageComponents])
+	ids := make([]byte, 0, n)
+	for i := 0; i < n; i++ {
+		o := SOF0ImageComponents + 1 + i*3
+		if o >= len(t.block) {
+			break
+		}
+		ids = append(ids, t.block[o])
+	}
+	return ids
+}
+
+// frameSize returns the width and height of the frame, from whichever SOFn segment the image has
+func (i Image) frameSize() (width uint32, height uint32, err error) {
+	for _, app := range i.apps {
+		if sof, ok := app.(*tSOFnAPP); ok {
+			if len(sof.block) <= SOF0ImageComponents {
+				return 0, 0, &exifError{"SOF segment is too small"}
+			}
+			width = uint32(sof.endian.Uint16(sof.block[SOF0ImageWidth:]))
+			height = uint32(sof.endian.Uint16(sof.block[SOF0ImageHeight:]))
+			return width, height, nil
+		}
+	}
+	return 0, 0, &exifError{"Image does not have a SOF segment"}
+}
+
 const (
-	SOF0ImageBPP    = 0x0004
-	SOF0ImageHeight = 0x0005
-	SOF0ImageWidth  = 0x0007
+	SOF0ImageBPP        = 0x0004
+	SOF0ImageHeight     = 0x0005
+	SOF0ImageWidth      = 0x0007
+	SOF0ImageComponents = 0x0009
 )
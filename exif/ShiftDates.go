@@ -0,0 +1,317 @@
+package ImgMeta
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+Shifting dates
+
+ShiftDates corrects the dates of a camera with a wrong clock or time zone. Every date of the selected fields is
+shifted by the same delta:
+
+    Field              EXIF                                   IPTC                       XMP
+    DateFieldOriginal  DateTimeOriginal, OffsetTimeOriginal   2:55 DateCreated,          exif:DateTimeOriginal,
+                                                              2:60 TimeCreated           photoshop:DateCreated
+    DateFieldDigitized DateTimeDigitized, OffsetTimeDigitized 2:62 DigitizationDate,     exif:DateTimeDigitized,
+                                                              2:63 DigitizationTime      xmp:CreateDate
+    DateFieldModified  DateTime, OffsetTime                   -                          xmp:ModifyDate,
+                                                                                         xmp:MetadataDate
+
+By default the clock was wrong: the wall clock times move, the offsets stay. With DateFieldZone the camera was set
+to the wrong time zone: the offsets (EXIF OffsetTime*, the offsets of IPTC times and XMP dates) move along with the
+wall clock, so the moment of capture stays the same. The GPS time stamps are UTC from the satellites and are never
+shifted.
+
+Dates are written back in the form they were read in (a date without time stays a date). A date without time
+is only shifted when the delta is a whole number of days, moving it by part of a day would change the day it
+names. Blank or malformed dates are left as they are. The sub-second digits are not touched, the delta must be whole seconds.
+
+*/
+
+// DateFields selects the dates ShiftDates shifts
+type DateFields int
+
+// Date fields
+const (
+	DateFieldOriginal DateFields = 1 << iota
+	DateFieldDigitized
+	DateFieldModified
+	DateFieldZone // shift the UTC offsets along, the camera was set to the wrong time zone
+
+	DateFieldsAll = DateFieldOriginal | DateFieldDigitized | DateFieldModified
+)
+
+// cShiftDay is the unit dates without a time are shifted by
+const cShiftDay = 24 * time.Hour
+
+type tShiftDateField struct {
+	field    DateFields
+	exifIFD  uint16
+	exifTag  uint16
+	exifZone uint16
+	iptcDate uint16 // 0 when not in IPTC
+	iptcTime uint16
+	xmp      [][2]string
+}
+
+var aShiftDateFields = []tShiftDateField{
+	{DateFieldOriginal, IFDExif, ExifTagDateTimeOriginal, ExifTagOffsetTimeOriginal, IptcTagApplication2DateCreated, IptcTagApplication2TimeCreated,
+		[][2]string{{XmpNsEXIF, "DateTimeOriginal"}, {XmpNsPhotoshop, "DateCreated"}}},
+	{DateFieldDigitized, IFDExif, ExifTagDateTimeDigitized, ExifTagOffsetTimeDigitized, IptcTagApplication2DigitizationDate, IptcTagApplication2DigitizationTime,
+		[][2]string{{XmpNsEXIF, "DateTimeDigitized"}, {XmpNsXMP, "CreateDate"}}},
+	{DateFieldModified, IFD0, ExifTagDateTime, ExifTagOffsetTime, 0, 0,
+		[][2]string{{XmpNsXMP, "ModifyDate"}, {XmpNsXMP, "MetadataDate"}}},
+}
+
+// ShiftResult is the outcome of shifting the dates of one file of a batch
+type ShiftResult struct {
+	Path string
+	Err  error
+}
+
+// ShiftDates returns a writer that writes the image with its dates shifted.
+// Examples:
+//
+//	// the camera was 1 hour and 30 seconds behind
+//	writer, err := ShiftDates(image, time.Hour+30*time.Second, DateFieldsAll)
+func ShiftDates(image Image, delta time.Duration, fields DateFields) (*JpegWriter, error) {
+	m := NewMetadata(image)
+	if err := m.ShiftDates(delta, fields); err != nil {
+		return nil, err
+	}
+	return m.encode()
+}
+
+// ShiftDates shifts the dates, e.g. as part of UpdateFile
+func (m *Metadata) ShiftDates(delta time.Duration, fields DateFields) error {
+	if delta%time.Second != 0 {
+		return &exifError{"Dates can only be shifted by whole seconds"}
+	}
+	zone := fields&DateFieldZone != 0
+	if err := m.shiftExifDates(delta, fields, zone); err != nil {
+		return err
+	}
+	iptcChanged, err := m.shiftIPTCDates(delta, fields, zone)
+	if err != nil {
+		return err
+	}
+	return m.shiftXMPDates(delta, fields, zone, iptcChanged)
+}
+
+// ShiftDatesInDirectory shifts the dates of the JPEG files in a directory (not its subdirectories), a file that
+// fails does not stop the others
+func ShiftDatesInDirectory(dir string, delta time.Duration, fields DateFields, options UpdateOptions) ([]ShiftResult, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	results := []ShiftResult{}
+	for _, entry := range entries {
+		extension := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (extension != ".jpg" && extension != ".jpeg") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		err := UpdateFileWithOptions(path, func(m *Metadata) error {
+			return m.ShiftDates(delta, fields)
+		}, options)
+		results = append(results, ShiftResult{Path: path, Err: err})
+	}
+	sort.Slice(results, func(a, b int) bool { return results[a].Path < results[b].Path })
+	return results, nil
+}
+
+// shiftTime moves the wall clock by delta, and the offset as well when zone is set
+func shiftTime(t time.Time, delta time.Duration, zone bool) (time.Time, error) {
+	shifted := t.Add(delta)
+	if !zone {
+		return shifted, nil
+	}
+	_, offset := t.Zone()
+	newOffset := time.Duration(offset)*time.Second + delta
+	if newOffset < -cMaxUTCOffset || newOffset > cMaxUTCOffset {
+		return t, &exifError{fmt.Sprintf("UTC offset %v is out of range", newOffset)}
+	}
+	return withOffset(shifted, newOffset), nil
+}
+
+func (m *Metadata) shiftExifDates(delta time.Duration, fields DateFields, zone bool) error {
+	if _, exists := m.image.apps["EXIF"]; !exists && m.exif == nil {
+		return nil
+	}
+	untouched := m.exif == nil
+	w, err := m.Exif()
+	if err != nil {
+		return err
+	}
+	changed := false
+	for _, f := range aShiftDateFields {
+		if fields&f.field == 0 {
+			continue
+		}
+		value, exists := w.Tag(f.exifIFD, f.exifTag)
+		text, _ := value.(string)
+		t, err := parseExifDateTime(text, "")
+		if !exists || err != nil {
+			continue
+		}
+		if err := w.SetTag(f.exifIFD, f.exifTag, t.Add(delta).Format("2006:01:02 15:04:05")); err != nil {
+			return err
+		}
+		changed = true
+		offsetValue, hasOffset := w.Tag(IFDExif, f.exifZone)
+		offsetText, _ := offsetValue.(string)
+		offset, err := parseUTCOffset(offsetText)
+		if !zone || !hasOffset || err != nil {
+			continue
+		}
+		if offset += delta; offset < -cMaxUTCOffset || offset > cMaxUTCOffset {
+			return &exifError{fmt.Sprintf("UTC offset %v is out of range", offset)}
+		}
+		if err := w.SetTag(IFDExif, f.exifZone, formatUTCOffset(offset)); err != nil {
+			return err
+		}
+	}
+	if !changed && untouched {
+		m.exif = nil
+	}
+	return nil
+}
+
+func (m *Metadata) shiftIPTCDates(delta time.Duration, fields DateFields, zone bool) (bool, error) {
+	if _, exists := m.image.apps["IPTC"]; !exists && m.iptc == nil {
+		return false, nil
+	}
+	untouched := m.iptc == nil
+	w, err := m.IPTC()
+	if err != nil {
+		return false, err
+	}
+	changed := false
+	for _, f := range aShiftDateFields {
+		if fields&f.field == 0 || f.iptcDate == 0 {
+			continue
+		}
+		date, clock := firstValue(w.Values(f.iptcDate)), firstValue(w.Values(f.iptcTime))
+		t, err := parseIPTCDateTime(date, clock)
+		if err != nil {
+			continue
+		}
+		layout := ""
+		for _, l := range []string{"150405-0700", "150405"} {
+			if _, err := time.Parse(l, clock); err == nil {
+				layout = l
+				break
+			}
+		}
+		if layout == "" && (clock != "" || delta%cShiftDay != 0) {
+			continue
+		}
+		if layout == "150405-0700" {
+			if t, err = shiftTime(t, delta, zone); err != nil {
+				return false, err
+			}
+		} else {
+			t = t.Add(delta)
+		}
+		if err := w.Set(f.iptcDate, t.Format("20060102")); err != nil {
+			return false, err
+		}
+		if layout != "" {
+			if err := w.Set(f.iptcTime, t.Format(layout)); err != nil {
+				return false, err
+			}
+		}
+		changed = true
+	}
+	if !changed && untouched {
+		m.iptc = nil
+	}
+	return changed, nil
+}
+
+func (m *Metadata) shiftXMPDates(delta time.Duration, fields DateFields, zone bool, iptcChanged bool) error {
+	if _, exists := m.image.apps["XMP"]; !exists && m.xmp == nil {
+		return nil
+	}
+	untouched := m.xmp == nil
+	w, err := m.XMP()
+	if err != nil {
+		return err
+	}
+	changed := false
+	for _, f := range aShiftDateFields {
+		if fields&f.field == 0 {
+			continue
+		}
+		for _, name := range f.xmp {
+			value, exists := w.Property(name[0], name[1])
+			text, isText := value.(string)
+			if !exists || !isText {
+				continue
+			}
+			shifted, err := shiftXMPDate(strings.TrimSpace(text), delta, zone)
+			if err != nil {
+				return err
+			}
+			if shifted != "" {
+				if err := w.Set(name[0], name[1], shifted); err != nil {
+					return err
+				}
+				changed = true
+			}
+		}
+	}
+	// keep the XMP when the IPTC changed, so its digest is updated
+	if !changed && !iptcChanged && untouched {
+		m.xmp = nil
+	}
+	return nil
+}
+
+// shiftXMPDate shifts an XMP date and formats it in the layout it was read in, "" when it does not parse or is a
+// date without time and the delta is not a whole number of days
+func shiftXMPDate(value string, delta time.Duration, zone bool) (string, error) {
+	for _, layout := range aXMPDateLayouts {
+		t, err := time.Parse(layout, value)
+		if err != nil {
+			continue
+		}
+		if !strings.Contains(layout, "T") && delta%cShiftDay != 0 {
+			return "", nil
+		}
+		if strings.Contains(layout, "Z07:00") {
+			if t, err = shiftTime(t, delta, zone); err != nil {
+				return "", err
+			}
+		} else {
+			t = t.Add(delta)
+		}
+		return t.Format(layout), nil
+	}
+	return "", nil
+}
+
+// formatUTCOffset formats an offset as EXIF "+HH:MM"
+func formatUTCOffset(offset time.Duration) string {
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	return fmt.Sprintf("%s%02d:%02d", sign, int(offset/time.Hour), int(offset%time.Hour/time.Minute))
+}
+
+// firstValue returns the first value as a string, "" when there is none
+func firstValue(values []interface{}) string {
+	if len(values) == 0 {
+		return ""
+	}
+	text, _ := values[0].(string)
+	return strings.TrimSpace(text)
+}
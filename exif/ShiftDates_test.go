@@ -0,0 +1,60 @@
+package ImgMeta
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShiftXMPDate(t *testing.T) {
+	for _, test := range []struct {
+		value string
+		delta time.Duration
+		zone  bool
+		want  string
+	}{
+		{"2024-05-01T10:00:00", time.Hour, false, "2024-05-01T11:00:00"},
+		{"2024-05-01T23:30", time.Hour, false, "2024-05-02T00:30"},
+		{"2024-05-01T10:00:00+02:00", time.Hour, false, "2024-05-01T11:00:00+02:00"},
+		{"2024-05-01T10:00:00+02:00", time.Hour, true, "2024-05-01T11:00:00+03:00"},
+		{"2024-05-01", time.Hour, false, ""},
+		{"2024-05-01", -time.Second, false, ""},
+		{"2024-05", 36 * time.Hour, false, ""},
+		{"2024", -time.Hour, false, ""},
+		{"2024-05-01", 48 * time.Hour, false, "2024-05-03"},
+		{"2024-05-01", -24 * time.Hour, false, "2024-04-30"},
+		{"not a date", time.Hour, false, ""},
+	} {
+		got, err := shiftXMPDate(test.value, test.delta, test.zone)
+		if err != nil || got != test.want {
+			t.Errorf("%s by %v: '%s' %v, want '%s'", test.value, test.delta, got, err, test.want)
+		}
+	}
+}
+
+func TestShiftIPTCDateWithoutTime(t *testing.T) {
+	m := NewMetadata(readTestImage(t))
+	iptc, err := m.IPTC()
+	if err != nil {
+		t.Fatal(err)
+	}
+	iptc.Remove(IptcTagApplication2TimeCreated)
+	if err := iptc.Set(IptcTagApplication2DateCreated, "20240501"); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		delta time.Duration
+		want  string
+	}{
+		{time.Hour, "20240501"},
+		{-30 * time.Hour, "20240501"},
+		{-24 * time.Hour, "20240430"},
+		{72 * time.Hour, "20240503"},
+	} {
+		if err := m.ShiftDates(test.delta, DateFieldOriginal); err != nil {
+			t.Fatal(err)
+		}
+		if got := firstValue(iptc.Values(IptcTagApplication2DateCreated)); got != test.want {
+			t.Errorf("shifted by %v: %s, want %s", test.delta, got, test.want)
+		}
+	}
+}
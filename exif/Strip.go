@@ -0,0 +1,326 @@
+package ImgMeta
+
+import (
+	"strings"
+)
+
+/*
+Stripping metadata
+
+Strip removes privacy sensitive metadata on segment level: segments that are not touched are copied byte for byte,
+the EXIF, XMP and IPTC segments are only rewritten when something was removed from them, and the compressed image
+data is never re-encoded.
+
+    Option          Removes
+    GPS             EXIF GPS IFD, XMP exif:GPS*
+    SerialNumbers   EXIF BodySerialNumber, LensSerialNumber, ImageUniqueID and the MakerNote (which holds the
+                    camera serial number for most makes), XMP aux/exifEX serial numbers
+    Owner           EXIF CameraOwnerName, XMP aux:OwnerName and exifEX:CameraOwnerName
+    Location        XMP photoshop:City/State/Country, Iptc4xmpCore:Location/CountryCode, Iptc4xmpExt locations,
+                    IPTC 2:26, 2:27, 2:90, 2:92, 2:95, 2:100, 2:101
+    Persons         XMP Iptc4xmpExt:PersonInImage(WDetails), mwg-rs:Regions, MP:RegionInfo
+    Contact         XMP Iptc4xmpCore:CreatorContactInfo, IPTC 2:118 Contact
+    Thumbnail       EXIF thumbnail, JFXX thumbnail
+    Comments        COM segments
+    EXIF, XMP, IPTC the whole segment(s)
+    ICC             ICC profile
+    Other           all other APPn segments, except JFIF, Adobe and MPF which describe the image data
+
+With KeepCopyright the copyright notices (EXIF Copyright, XMP dc:rights and xmpRights, IPTC 2:116) are kept when
+a whole EXIF, XMP or IPTC section is removed.
+
+Presets:
+
+    "web-publish"                            GPS, SerialNumbers, Owner, Location, Persons, Contact, Thumbnail
+    "all-metadata-except-copyright-and-ICC"  EXIF, XMP, IPTC, Comments, Other with KeepCopyright
+    "everything"                             EXIF, XMP, IPTC, Comments, Other, ICC
+
+Images appended after the primary image (MPF images, motion photo video) are kept as they are.
+
+*/
+
+// Strip presets
+const (
+	StripWebPublish               = "web-publish"
+	StripAllExceptCopyrightAndICC = "all-metadata-except-copyright-and-ICC"
+	StripEverything               = "everything"
+)
+
+// StripOptions select the metadata that Strip removes
+type StripOptions struct {
+	GPS           bool
+	SerialNumbers bool
+	Owner         bool
+	Location      bool
+	Persons       bool
+	Contact       bool
+	Thumbnail     bool
+	Comments      bool
+	EXIF          bool
+	XMP           bool
+	IPTC          bool
+	ICC           bool
+	Other         bool
+	KeepCopyright bool
+}
+
+var aStripPresets = map[string]StripOptions{
+	StripWebPublish:               {GPS: true, SerialNumbers: true, Owner: true, Location: true, Persons: true, Contact: true, Thumbnail: true},
+	StripAllExceptCopyrightAndICC: {EXIF: true, XMP: true, IPTC: true, Comments: true, Other: true, KeepCopyright: true},
+	StripEverything:               {EXIF: true, XMP: true, IPTC: true, Comments: true, Other: true, ICC: true},
+}
+
+// StripPreset returns the options of a preset
+func StripPreset(name string) (StripOptions, error) {
+	options, exists := aStripPresets[name]
+	if !exists {
+		return StripOptions{}, &exifError{"Unknown strip preset '" + name + "'"}
+	}
+	return options, nil
+}
+
+const (
+	cStripGPS = 1 << iota
+	cStripSerialNumbers
+	cStripOwner
+	cStripLocation
+	cStripPersons
+	cStripContact
+)
+
+func (o StripOptions) categories() (categories int) {
+	for category, set := range map[int]bool{cStripGPS: o.GPS, cStripSerialNumbers: o.SerialNumbers, cStripOwner: o.Owner,
+		cStripLocation: o.Location, cStripPersons: o.Persons, cStripContact: o.Contact} {
+		if set {
+			categories |= category
+		}
+	}
+	return categories
+}
+
+type tStripExifTag struct {
+	ifd      uint16
+	tag      uint16
+	category int
+}
+
+var aStripExifTags = []tStripExifTag{
+	{IFDExif, ExifTagBodySerialNumber, cStripSerialNumbers},
+	{IFDExif, ExifTagLensSerialNumber, cStripSerialNumbers},
+	{IFDExif, ExifTagImageUniqueID, cStripSerialNumbers},
+	{IFDExif, ExifTagMakerNote, cStripSerialNumbers},
+	{IFDExif, ExifTagCameraOwnerName, cStripOwner},
+}
+
+// tStripXMPProperty names an XMP property, a name that ends with '*' is a prefix
+type tStripXMPProperty struct {
+	namespace string
+	name      string
+	category  int
+}
+
+var aStripXMPProperties = []tStripXMPProperty{
+	{XmpNsEXIF, "GPS*", cStripGPS},
+	{XmpNsAux, "SerialNumber", cStripSerialNumbers},
+	{XmpNsAux, "LensSerialNumber", cStripSerialNumbers},
+	{XmpNsEXIF, "ImageUniqueID", cStripSerialNumbers},
+	{XmpNsExifEX, "BodySerialNumber", cStripSerialNumbers},
+	{XmpNsExifEX, "LensSerialNumber", cStripSerialNumbers},
+	{XmpNsExifEX, "ImageUniqueID", cStripSerialNumbers},
+	{XmpNsAux, "OwnerName", cStripOwner},
+	{XmpNsExifEX, "CameraOwnerName", cStripOwner},
+	{XmpNsPhotoshop, "City", cStripLocation},
+	{XmpNsPhotoshop, "State", cStripLocation},
+	{XmpNsPhotoshop, "Country", cStripLocation},
+	{XmpNsIptc4xmpCore, "Location", cStripLocation},
+	{XmpNsIptc4xmpCore, "CountryCode", cStripLocation},
+	{XmpNsIptc4xmpExt, "LocationCreated", cStripLocation},
+	{XmpNsIptc4xmpExt, "LocationShown", cStripLocation},
+	{XmpNsIptc4xmpExt, "PersonInImage", cStripPersons},
+	{XmpNsIptc4xmpExt, "PersonInImageWDetails", cStripPersons},
+	{XmpNsMWGRegions, "Regions", cStripPersons},
+	{XmpNsMicrosoftPhoto, "RegionInfo", cStripPersons},
+	{XmpNsIptc4xmpCore, "CreatorContactInfo", cStripContact},
+}
+
+func (p tStripXMPProperty) matches(node *tXMPNode) bool {
+	if node.name.Space != p.namespace {
+		return false
+	}
+	if strings.HasSuffix(p.name, "*") {
+		return strings.HasPrefix(node.name.Local, strings.TrimSuffix(p.name, "*"))
+	}
+	return node.name.Local == p.name
+}
+
+type tStripIPTCDataset struct {
+	tag      uint16
+	category int
+}
+
+var aStripIPTCDatasets = []tStripIPTCDataset{
+	{IptcTagApplication2LocationCode, cStripLocation},
+	{IptcTagApplication2LocationName, cStripLocation},
+	{IptcTagApplication2City, cStripLocation},
+	{IptcTagApplication2SubLocation, cStripLocation},
+	{IptcTagApplication2ProvinceState, cStripLocation},
+	{IptcTagApplication2CountryCode, cStripLocation},
+	{IptcTagApplication2CountryName, cStripLocation},
+	{IptcTagApplication2Contact, cStripContact},
+}
+
+// Strip removes metadata from an image, the returned writer writes the stripped image.
+// Examples:
+//
+//	options, _ := StripPreset(StripWebPublish)
+//	writer, err := Strip(image, options)
+//	...
+//	err = writer.Write(out)
+func Strip(image Image, options StripOptions) (*JpegWriter, error) {
+	m := NewMetadata(image)
+	if err := m.Strip(options); err != nil {
+		return nil, err
+	}
+	return m.encode()
+}
+
+// Strip removes metadata, e.g. as part of UpdateFile
+func (m *Metadata) Strip(options StripOptions) error {
+	categories := options.categories()
+	if err := m.stripExif(options, categories); err != nil {
+		return err
+	}
+	if err := m.stripXMP(options, categories); err != nil {
+		return err
+	}
+	if err := m.stripIPTC(options, categories); err != nil {
+		return err
+	}
+	if options.Thumbnail || options.Comments || options.ICC || options.Other {
+		m.Segments().removeIf(func(app APP, rank int) bool {
+			switch rank {
+			case 1:
+				return options.Thumbnail || options.Other
+			case 5:
+				return options.ICC
+			case 8:
+				return options.Other && app.Name() != "Adobe"
+			case 9:
+				return options.Comments
+			}
+			return false
+		})
+	}
+	return nil
+}
+
+func (m *Metadata) stripExif(options StripOptions, categories int) error {
+	if _, exists := m.image.apps["EXIF"]; !exists && m.exif == nil {
+		return nil
+	}
+	untouched := m.exif == nil
+	w, err := m.Exif()
+	if err != nil {
+		return err
+	}
+	if options.EXIF {
+		copyright, keep := w.ifds[IFD0][ExifTagCopyright]
+		if options.KeepCopyright && keep {
+			m.exif = NewExifWriter(w.endian)
+			m.exif.set(IFD0, ExifTagCopyright, copyright)
+			return nil
+		}
+		m.exif = nil
+		m.Segments().Remove("EXIF")
+		return nil
+	}
+
+	changed := false
+	for _, s := range aStripExifTags {
+		if categories&s.category != 0 && w.RemoveTag(s.ifd, s.tag) {
+			changed = true
+		}
+	}
+	if categories&cStripGPS != 0 && len(w.ifds[IFDGPS]) > 0 {
+		delete(w.ifds, IFDGPS)
+		changed = true
+	}
+	if options.Thumbnail && w.thumbnail != nil {
+		w.thumbnail = nil
+		changed = true
+	}
+	if !changed && untouched {
+		m.exif = nil
+	}
+	return nil
+}
+
+func (m *Metadata) stripXMP(options StripOptions, categories int) error {
+	if _, exists := m.image.apps["XMP"]; !exists && m.xmp == nil {
+		return nil
+	}
+	untouched := m.xmp == nil
+	w, err := m.XMP()
+	if err != nil {
+		return err
+	}
+	keep := func(node *tXMPNode) bool {
+		if options.XMP {
+			return options.KeepCopyright && (node.is(XmpNsDC, "rights") || node.name.Space == XmpNsXMPRights)
+		}
+		for _, p := range aStripXMPProperties {
+			if categories&p.category != 0 && p.matches(node) {
+				return false
+			}
+		}
+		return true
+	}
+	properties := w.properties[:0:0]
+	for _, p := range w.properties {
+		if keep(p) {
+			properties = append(properties, p)
+		}
+	}
+	if len(properties) == len(w.properties) {
+		if untouched {
+			m.xmp = nil
+		}
+		return nil
+	}
+	w.properties = properties
+	if options.XMP {
+		w.packetSize = 0
+	}
+	return nil
+}
+
+func (m *Metadata) stripIPTC(options StripOptions, categories int) error {
+	if _, exists := m.image.apps["IPTC"]; !exists && m.iptc == nil {
+		return nil
+	}
+	untouched := m.iptc == nil
+	w, err := m.IPTC()
+	if err != nil {
+		return err
+	}
+	if options.IPTC {
+		copyright := w.Values(IptcTagApplication2Copyright)
+		if options.KeepCopyright && len(copyright) > 0 {
+			m.iptc = NewIPTCWriter()
+			return m.iptc.Set(IptcTagApplication2Copyright, copyright...)
+		}
+		m.iptc = nil
+		m.Segments().Remove("IPTC")
+		return nil
+	}
+	removed := 0
+	for _, s := range aStripIPTCDatasets {
+		if categories&s.category != 0 {
+			removed += w.Remove(s.tag)
+		}
+	}
+	if removed == 0 && untouched {
+		m.iptc = nil
+	}
+	return nil
+}
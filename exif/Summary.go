@@ -0,0 +1,296 @@
+package ImgMeta
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+Summary
+
+The Summary holds the information most callers need, each value taken from the first source that has it:
+
+    Field           Sources
+    Width, Height   SOFn frame size, EXIF PixelXDimension/PixelYDimension; as displayed (orientation applied)
+    CaptureTime     see CaptureTime
+    Make, Model     EXIF Make/Model, XMP tiff:Make/tiff:Model
+    Lens            EXIF LensModel, XMP exifEX:LensModel, aux:Lens
+    Exposure        EXIF ExposureTime, FNumber, PhotographicSensitivity, FocalLength
+    GPS             EXIF GPS IFD
+    Title           XMP dc:title, IPTC 2:05 ObjectName
+    Description     reconciled (see Reconcile): EXIF ImageDescription, IPTC 2:120 Caption, XMP dc:description
+    Keywords        reconciled: IPTC 2:25 Keywords, XMP dc:subject
+    Rating          XMP xmp:Rating
+    Copyright       reconciled: EXIF Copyright, IPTC 2:116 Copyright, XMP dc:rights
+    Creator         reconciled: EXIF Artist, IPTC 2:80 By-line, XMP dc:creator
+
+Missing or malformed values are left empty (zero), reading a summary never fails.
+
+*/
+
+// Summary holds the commonly used information of an image
+type Summary struct {
+	Width        uint32 // as displayed
+	Height       uint32 // as displayed
+	Orientation  Orientation
+	CaptureTime  time.Time // zero when unknown
+	Make         string
+	Model        string
+	Lens         string
+	ExposureTime float64 // seconds
+	FNumber      float64
+	ISO          uint32
+	FocalLength  float64 // mm
+	HasGPS       bool
+	Latitude     float64 // decimal degrees, north positive
+	Longitude    float64 // decimal degrees, east positive
+	Altitude     float64 // meters above sea level
+	Title        string
+	Description  string
+	Keywords     []string
+	Rating       int
+	Copyright    string
+	Creator      []string
+}
+
+// tMetadataSources gives access to the EXIF, IPTC and XMP of an image, missing sections read as empty
+type tMetadataSources struct {
+	exif *tEXIFAPP
+	iptc *IPTCWriter
+	xmp  *tXMPNode
+}
+
+func (i Image) metadataSources() (s tMetadataSources) {
+	if exif, ok := i.apps["EXIF"].(*tEXIFAPP); ok {
+		s.exif = exif
+	}
+	if _, exists := i.apps["IPTC"]; exists {
+		if iptc, err := NewIPTCWriterFrom(i); err == nil {
+			s.iptc = iptc
+		}
+	}
+	if _, exists := i.apps["XMP"]; exists {
+		if root, err := i.xmpRoot(); err == nil {
+			s.xmp = root
+		}
+	}
+	return s
+}
+
+func (s tMetadataSources) exifValue(ifdType uint16, tag uint16) (interface{}, bool) {
+	if s.exif == nil {
+		return nil, false
+	}
+	value, err := s.exif.ReadIFDValue(ifdType, tag)
+	return value, err == nil
+}
+
+func (s tMetadataSources) exifString(ifdType uint16, tag uint16) string {
+	value, ok := s.exifValue(ifdType, tag)
+	if text, isString := value.(string); ok && isString {
+		return strings.TrimSpace(text)
+	}
+	return ""
+}
+
+func (s tMetadataSources) exifFloat(ifdType uint16, tag uint16) (float64, bool) {
+	value, ok := s.exifValue(ifdType, tag)
+	if !ok {
+		return 0, false
+	}
+	floats := exifFloats(value)
+	if len(floats) == 0 {
+		return 0, false
+	}
+	return floats[0], true
+}
+
+func (s tMetadataSources) iptcStrings(tag uint16) (values []string) {
+	if s.iptc == nil {
+		return nil
+	}
+	for _, value := range s.iptc.Values(tag) {
+		if text, ok := value.(string); ok && strings.TrimSpace(text) != "" {
+			values = append(values, strings.TrimSpace(text))
+		}
+	}
+	return values
+}
+
+func (s tMetadataSources) xmpStrings(namespace string, name string) []string {
+	if s.xmp == nil {
+		return nil
+	}
+	value, ok := s.xmp.property(namespace, name)
+	if !ok {
+		return nil
+	}
+	switch v := value.(type) {
+	case string:
+		if v != "" {
+			return []string{v}
+		}
+	case []string:
+		return v
+	}
+	return nil
+}
+
+// exifFloats converts the numbers of a decoded EXIF value to float64
+func exifFloats(value interface{}) []float64 {
+	switch v := value.(type) {
+	case float64:
+		return []float64{v}
+	case float32:
+		return []float64{float64(v)}
+	case []float64:
+		return v
+	}
+	ints := exifInts(value)
+	floats := make([]float64, len(ints))
+	for n, i := range ints {
+		floats[n] = float64(i)
+	}
+	return floats
+}
+
+func firstString(candidates ...[]string) string {
+	for _, values := range candidates {
+		if len(values) > 0 && values[0] != "" {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+func firstStrings(candidates ...[]string) []string {
+	for _, values := range candidates {
+		if len(values) > 0 {
+			return values
+		}
+	}
+	return nil
+}
+
+// Summary returns the commonly used information of the image
+// Examples:
+//
+//	summary := image.Summary()
+//	fmt.Printf("%dx%d %s %v\n", summary.Width, summary.Height, summary.Model, summary.CaptureTime)
+func (i Image) Summary() (summary Summary) {
+	s := i.metadataSources()
+
+	summary.Orientation, _ = i.Orientation()
+	if width, height, err := i.frameSize(); err == nil && width > 0 && height > 0 {
+		summary.Width, summary.Height = width, height
+	} else {
+		x, okX := s.exifFloat(IFDExif, ExifTagPixelXDimension)
+		y, okY := s.exifFloat(IFDExif, ExifTagPixelYDimension)
+		if okX && okY {
+			summary.Width, summary.Height = uint32(x), uint32(y)
+		}
+	}
+	if summary.Orientation.SwapsDimensions() {
+		summary.Width, summary.Height = summary.Height, summary.Width
+	}
+
+	summary.CaptureTime = i.CaptureTime().Time
+
+	summary.Make = firstString([]string{s.exifString(IFD0, ExifTagMake)}, s.xmpStrings(XmpNsTIFF, "Make"))
+	summary.Model = firstString([]string{s.exifString(IFD0, ExifTagModel)}, s.xmpStrings(XmpNsTIFF, "Model"))
+	summary.Lens = firstString([]string{s.exifString(IFDExif, ExifTagLensModel)}, s.xmpStrings(XmpNsExifEX, "LensModel"), s.xmpStrings(XmpNsAux, "Lens"))
+	summary.ExposureTime, _ = s.exifFloat(IFDExif, ExifTagExposureTime)
+	summary.FNumber, _ = s.exifFloat(IFDExif, ExifTagFNumber)
+	summary.FocalLength, _ = s.exifFloat(IFDExif, ExifTagFocalLength)
+	if iso, ok := s.exifFloat(IFDExif, ExifTagPhotographicSensitivity); ok {
+		summary.ISO = uint32(iso)
+	}
+
+	if gps := s.gps(); gps.HasPosition {
+		summary.HasGPS, summary.Latitude, summary.Longitude = true, gps.Latitude, gps.Longitude
+		if !math.IsNaN(gps.Altitude) {
+			summary.Altitude = gps.Altitude
+		}
+	}
+
+	summary.Title = firstString(s.xmpStrings(XmpNsDC, "title"), s.iptcStrings(IptcTagApplication2ObjectName))
+	reconciled := i.Reconcile()
+	summary.Description = reconciled.Description.String()
+	summary.Keywords = reconciled.Keywords.Values
+	if rating, err := strconv.ParseFloat(firstString(s.xmpStrings(XmpNsXMP, "Rating")), 64); err == nil {
+		summary.Rating = int(rating)
+	}
+	summary.Copyright = reconciled.Copyright.String()
+	summary.Creator = reconciled.Creator.Values
+	return summary
+}
+
+// aExifDateLayouts are the forms of an EXIF date/time: the standard one first, then the ones some writers use
+var aExifDateLayouts = []string{
+	"2006:01:02 15:04:05",
+	"2006-01-02 15:04:05",
+	"2006/01/02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006:01:02 15:04",
+}
+
+// parseExifDateTime parses "YYYY:MM:DD HH:MM:SS" with the optional sub-second digits, the time is local (the
+// offset is unknown) and returned in UTC as if it were. Blank and zero ("0000:00:00 00:00:00") values are malformed.
+func parseExifDateTime(value string, subsec string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range aExifDateLayouts {
+		t, err := time.Parse(layout, value)
+		if err != nil || t.Year() == 0 {
+			continue
+		}
+		if subsec = strings.TrimSpace(subsec); subsec != "" && strings.Trim(subsec, "0123456789") == "" {
+			if digits, err := strconv.ParseFloat("0."+subsec, 64); err == nil {
+				t = t.Add(time.Duration(digits * float64(time.Second)))
+			}
+		}
+		return t, nil
+	}
+	return time.Time{}, &exifError{fmt.Sprintf("EXIF date/time '%s' is malformed", value)}
+}
+
+// aXMPDateLayouts are the forms of an XMP date, from complete to year only
+var aXMPDateLayouts = []string{
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+// parseXMPDate parses an ISO 8601 XMP date, a date without offset is returned in UTC
+func parseXMPDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range aXMPDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, &exifError{fmt.Sprintf("XMP date '%s' is malformed", value)}
+}
+
+// parseIPTCDateTime parses the IPTC date "CCYYMMDD" and the optional time "HHMMSS±HHMM"
+func parseIPTCDateTime(date string, clock string) (time.Time, error) {
+	date, clock = strings.TrimSpace(date), strings.TrimSpace(clock)
+	if clock != "" {
+		for _, layout := range []string{"20060102150405-0700", "20060102150405"} {
+			if t, err := time.Parse(layout, date+clock); err == nil {
+				return t, nil
+			}
+		}
+	}
+	t, err := time.Parse("20060102", date)
+	if err != nil {
+		return time.Time{}, &exifError{fmt.Sprintf("IPTC date '%s' is malformed", date)}
+	}
+	return t, nil
+}
@@ -0,0 +1,84 @@
+package ImgMeta
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+// replaceSegments returns the JPEG with the payload of every segment with the marker replaced, the length is
+// corrected, the identifier of the segment is kept
+func replaceSegments(data []byte, marker uint16, replace func(payload []byte) []byte) []byte {
+	out := append([]byte{}, data[:2]...)
+	cursor := 2
+	for cursor+4 <= len(data) {
+		m := binary.BigEndian.Uint16(data[cursor:])
+		size := int(binary.BigEndian.Uint16(data[cursor+2:]))
+		if m == 0xFFDA || cursor+2+size > len(data) {
+			break
+		}
+		payload := data[cursor+4 : cursor+2+size]
+		if m == marker {
+			payload = replace(append([]byte{}, payload...))
+		}
+		out = append(out, byte(m>>8), byte(m), byte((len(payload)+2)>>8), byte(len(payload)+2))
+		out = append(out, payload...)
+		cursor += 2 + size
+	}
+	return append(out, data[cursor:]...)
+}
+
+func TestSummaryMalformedSegments(t *testing.T) {
+	data, err := os.ReadFile("../examples/test.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	random := rand.New(rand.NewSource(1))
+	garbage := func(keep int) func([]byte) []byte {
+		return func(payload []byte) []byte {
+			for n := keep; n < len(payload); n++ {
+				payload[n] = byte(random.Intn(256))
+			}
+			return payload
+		}
+	}
+	truncate := func(size int) func([]byte) []byte {
+		return func(payload []byte) []byte {
+			if size < len(payload) {
+				return payload[:size]
+			}
+			return payload
+		}
+	}
+	for _, test := range []struct {
+		name    string
+		marker  uint16
+		replace func([]byte) []byte
+	}{
+		{"APP1 truncated after the TIFF header", cEXIF, truncate(14)},
+		{"APP1 truncated in IFD0", cEXIF, truncate(40)},
+		{"APP1 truncated halfway", cEXIF, truncate(300)},
+		{"APP1 garbage", cEXIF, garbage(len(idEXIF))},
+		{"APP1 garbage IFDs", cEXIF, garbage(len(idEXIF) + 8)},
+		{"APP13 truncated", cIPTC, truncate(20)},
+		{"APP13 truncated halfway", cIPTC, truncate(60)},
+		{"APP13 garbage", cIPTC, garbage(14)},
+		{"SOF0 truncated", 0xFFC0, truncate(3)},
+		{"SOF0 garbage", 0xFFC0, garbage(0)},
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("%s: %v", test.name, r)
+				}
+			}()
+			image, err := readJpegData(replaceSegments(data, test.marker, test.replace))
+			if err != nil {
+				return
+			}
+			image.Summary()
+			GetBasicInfo(image)
+		}()
+	}
+}
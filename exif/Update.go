@@ -0,0 +1,299 @@
+package ImgMeta
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+/*
+Updating files
+
+UpdateFile reads a JPEG file, lets the caller edit its metadata and replaces the file atomically:
+
+    1. the new file is written to a temporary file in the same directory and synced to disk
+    2. the temporary file is read back, it has to parse and hold the same compressed image data
+    3. permissions, ownership (as far as the caller may set it) and (optionally) the modification time of the
+       original are applied
+    4. the temporary file is renamed over the original and the directory is synced
+
+A crash before the rename leaves the original untouched (and at most a stray ".name.*.tmp" file), the rename itself
+is atomic. Symbolic links are followed, the file they point to is replaced.
+
+With UpdateOptions.InPlace an update that only changes the XMP is written into the existing XMP segment when the new
+packet fits in its padding (see UpdateXMPInPlace); this is much faster for large files but is not atomic.
+
+*/
+
+// UpdateOptions control how UpdateFile replaces the file
+type UpdateOptions struct {
+	PreserveModTime bool // Keep the modification time of the original file
+	InPlace         bool // Overwrite the XMP segment in place when only the XMP changed and it fits
+}
+
+// Metadata gives access to the metadata of an image that is being updated; the writers are created on first use
+// and only the sections whose writer was requested are written.
+type Metadata struct {
+	image    Image
+	segments *JpegWriter
+	exif     *ExifWriter
+	iptc     *IPTCWriter
+	xmp      *XMPWriter
+	Dropped  []ExifDroppedTag // EXIF tags that were left out to make the EXIF segment fit
+}
+
+// NewMetadata returns the metadata of an image for editing
+func NewMetadata(image Image) *Metadata {
+	return &Metadata{image: image}
+}
+
+// Image returns the image as it was read
+func (m *Metadata) Image() Image {
+	return m.image
+}
+
+// Exif returns the EXIF writer, holding the EXIF of the image
+func (m *Metadata) Exif() (*ExifWriter, error) {
+	if m.exif == nil {
+		exif, err := NewExifWriterFrom(m.image)
+		if err != nil {
+			return nil, err
+		}
+		m.exif = exif
+	}
+	return m.exif, nil
+}
+
+// IPTC returns the IPTC writer, holding the IPTC datasets and Photoshop resources of the image
+func (m *Metadata) IPTC() (*IPTCWriter, error) {
+	if m.iptc == nil {
+		iptc, err := NewIPTCWriterFrom(m.image)
+		if err != nil {
+			return nil, err
+		}
+		m.iptc = iptc
+	}
+	return m.iptc, nil
+}
+
+// XMP returns the XMP writer, holding the XMP of the image
+func (m *Metadata) XMP() (*XMPWriter, error) {
+	if m.xmp == nil {
+		xmp, err := NewXMPWriterFrom(m.image)
+		if err != nil {
+			return nil, err
+		}
+		m.xmp = xmp
+	}
+	return m.xmp, nil
+}
+
+// Segments returns the segment writer, for changes to other segments (e.g. ICC, comments)
+func (m *Metadata) Segments() *JpegWriter {
+	if m.segments == nil {
+		m.segments = NewJpegWriter(m.image)
+	}
+	return m.segments
+}
+
+// modified returns true when any section was requested for editing
+func (m *Metadata) modified() bool {
+	return m.segments != nil || m.exif != nil || m.iptc != nil || m.xmp != nil
+}
+
+// encode puts the edited sections in the segment writer
+func (m *Metadata) encode() (*JpegWriter, error) {
+	w := m.Segments()
+	if m.exif != nil {
+		block, dropped, err := m.exif.Encode()
+		if err != nil {
+			return nil, err
+		}
+		m.Dropped = dropped
+		if err := w.Replace(block); err != nil {
+			return nil, err
+		}
+	}
+	if m.iptc != nil {
+		_, hasXMP := m.image.apps["XMP"]
+		m.iptc.keepDigest = hasXMP && m.xmp == nil
+		blocks, err := m.iptc.Encode()
+		if err != nil {
+			return nil, err
+		}
+		if err := w.ReplaceAll("IPTC", blocks); err != nil {
+			return nil, err
+		}
+	}
+	if m.xmp != nil {
+		if len(m.xmp.properties) == 0 {
+			w.Remove("XMP")
+			w.Remove("XMPExtension")
+			return w, nil
+		}
+		segment, extension, err := m.xmp.Encode()
+		if err != nil {
+			return nil, err
+		}
+		if err := w.Replace(segment); err != nil {
+			return nil, err
+		}
+		if err := w.ReplaceAll("XMPExtension", extension); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// Write writes the image with the edited metadata to out
+func (m *Metadata) Write(out io.Writer) error {
+	w, err := m.encode()
+	if err != nil {
+		return err
+	}
+	return w.Write(out)
+}
+
+// UpdateFile edits the metadata of a JPEG file and replaces the file atomically, keeping its permissions and
+// ownership.
+// Examples:
+//
+//	err := UpdateFile("photo.jpg", func(m *Metadata) error {
+//		xmp, err := m.XMP()
+//		if err != nil {
+//			return err
+//		}
+//		return xmp.Set(XmpNsXMP, "Rating", 5)
+//	})
+func UpdateFile(path string, update func(*Metadata) error) error {
+	return UpdateFileWithOptions(path, update, UpdateOptions{})
+}
+
+// UpdateFileWithOptions is UpdateFile with control over the modification time and in place XMP updates
+func UpdateFileWithOptions(path string, update func(*Metadata) error, options UpdateOptions) error {
+	path, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	fhnd, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	info, err := fhnd.Stat()
+	if err != nil {
+		fhnd.Close()
+		return err
+	}
+	image, err := ReadJpeg(fhnd)
+	fhnd.Close()
+	if err != nil {
+		return err
+	}
+
+	m := NewMetadata(image)
+	if err := update(m); err != nil {
+		return err
+	}
+	if !m.modified() {
+		return nil
+	}
+
+	if options.InPlace && m.xmp != nil && m.segments == nil && m.exif == nil && m.iptc == nil && len(m.xmp.properties) > 0 {
+		segment, extension, err := m.xmp.Encode()
+		if err != nil {
+			return err
+		}
+		if offset, fits := image.xmpInPlaceOffset(segment, extension); fits {
+			if err := writeAt(path, segment, offset); err != nil {
+				return err
+			}
+			if options.PreserveModTime {
+				return os.Chtimes(path, time.Now(), info.ModTime())
+			}
+			return nil
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	renamed := false
+	defer func() {
+		if !renamed {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if err := m.Write(tmp); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := verifyUpdate(tmp.Name(), image); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), info.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+		return err
+	}
+	if err := chownLike(tmp.Name(), info); err != nil {
+		return err
+	}
+	if options.PreserveModTime {
+		if err := os.Chtimes(tmp.Name(), time.Now(), info.ModTime()); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	renamed = true
+	return syncDir(filepath.Dir(path))
+}
+
+// verifyUpdate reads the written file back, it has to parse and hold the compressed image data of the original
+func verifyUpdate(path string, original Image) error {
+	fhnd, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fhnd.Close()
+	image, err := ReadJpeg(fhnd)
+	if err != nil {
+		return err
+	}
+	if image.scan == 0 || !bytes.Equal(image.data[image.scan:], original.data[original.scan:]) {
+		return &exifError{"Updated file does not hold the image data of the original"}
+	}
+	return nil
+}
+
+func writeAt(path string, block []byte, offset int64) error {
+	fhnd, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer fhnd.Close()
+	if _, err := fhnd.WriteAt(block, offset); err != nil {
+		return err
+	}
+	return fhnd.Sync()
+}
+
+// syncDir makes the rename durable, not all platforms can sync a directory so errors are ignored
+func syncDir(dir string) error {
+	fhnd, err := os.Open(dir)
+	if err != nil {
+		return nil
+	}
+	fhnd.Sync()
+	fhnd.Close()
+	return nil
+}
@@ -0,0 +1,70 @@
+package ImgMeta
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpdateFileKeepsImageData(t *testing.T) {
+	original, err := os.ReadFile("../examples/test.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	image, err := readJpegData(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "test.jpg")
+	if err := os.WriteFile(path, original, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err = UpdateFileWithOptions(path, func(m *Metadata) error {
+		exif, err := m.Exif()
+		if err != nil {
+			return err
+		}
+		if err := exif.SetTag(IFD0, ExifTagArtist, "Jane Doe"); err != nil {
+			return err
+		}
+		xmp, err := m.XMP()
+		if err != nil {
+			return err
+		}
+		return xmp.Set(XmpNsXMP, "Rating", 5)
+	}, UpdateOptions{PreserveModTime: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := readJpegData(updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(updated[decoded.scan:], original[image.scan:]) {
+		t.Errorf("image data changed")
+	}
+	if value, err := decoded.ReadTagValue("EXIF", ExifTagArtist); err != nil || value != "Jane Doe" {
+		t.Errorf("Artist: %v %v", value, err)
+	}
+	if value, err := decoded.ReadXMPValue(XmpNsXMP, "Rating"); err != nil || value != "5" {
+		t.Errorf("Rating: %v %v", value, err)
+	}
+
+	// a failing update leaves the file as it is
+	err = UpdateFile(path, func(m *Metadata) error {
+		return &exifError{"update failed"}
+	})
+	if after, _ := os.ReadFile(path); err == nil || !bytes.Equal(after, updated) {
+		t.Errorf("failing update: %v", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("temporary file left behind")
+	}
+}
@@ -0,0 +1,34 @@
+//go:build !windows
+
+package ImgMeta
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// chownLike gives the file the owner and group of the original, when they differ from the current ones. This is
+// best-effort: when the caller may not give the file away only the group is kept, and when that is not allowed
+// either the file keeps the owner and group of the caller.
+func chownLike(path string, original os.FileInfo) error {
+	want, ok := original.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if have, ok := info.Sys().(*syscall.Stat_t); ok && have.Uid == want.Uid && have.Gid == want.Gid {
+		return nil
+	}
+	err = os.Chown(path, int(want.Uid), int(want.Gid))
+	if errors.Is(err, syscall.EPERM) {
+		err = os.Chown(path, -1, int(want.Gid))
+	}
+	if errors.Is(err, syscall.EPERM) {
+		return nil
+	}
+	return err
+}
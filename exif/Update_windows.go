@@ -0,0 +1,8 @@
+package ImgMeta
+
+import "os"
+
+// chownLike does nothing, files on Windows get the owner of the directory
+func chownLike(path string, original os.FileInfo) error {
+	return nil
+}
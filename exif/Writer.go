@@ -0,0 +1,253 @@
+package ImgMeta
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+/*
+Writing a JPEG
+
+Metadata is changed on segment level, the compressed image data (everything from the SOS marker on, including any
+MPF images appended after the EOI) is copied verbatim, so rewriting metadata never re-compresses the image.
+
+New segments are inserted in the conventional order, existing segments keep their place:
+
+    SOI
+    APP0   JFIF
+    APP0   JFXX
+    APP1   EXIF
+    APP1   XMP
+    APP1   Extended XMP
+    APP2   ICC_PROFILE
+    APP2   MPF
+    APP13  IPTC (Photoshop 3.0)
+    APPn   others
+    COM
+    DQT, DHT, SOFn, ... SOS
+
+The MPF segment holds the offsets of the appended images relative to its own position, when segments change size
+these offsets are corrected.
+
+*/
+
+// JpegWriter writes an image with replaced, inserted or removed APPn/COM segments
+type JpegWriter struct {
+	image    Image
+	segments []tSegment
+}
+
+// NewJpegWriter returns a writer for the image, the image itself is not modified
+func NewJpegWriter(image Image) *JpegWriter {
+	return &JpegWriter{image: image, segments: append([]tSegment{}, image.segments...)}
+}
+
+// Segments returns the segments that will be written, in file order
+func (w *JpegWriter) Segments() []APP {
+	apps := make([]APP, 0, len(w.segments))
+	for _, segment := range w.segments {
+		apps = append(apps, segment.app)
+	}
+	return apps
+}
+
+// newSegment decodes a full segment (marker, length and payload)
+func newSegment(block []byte) (tSegment, error) {
+	if len(block) < 4 || block[0] != 0xFF {
+		return tSegment{}, &exifError{"Segment does not start with a marker"}
+	}
+	if len(block) > 0xFFFF+2 {
+		return tSegment{}, &exifError{fmt.Sprintf("Segment of %d bytes does not fit in a JPEG segment", len(block))}
+	}
+	if int(binary.BigEndian.Uint16(block[2:])) != len(block)-2 {
+		return tSegment{}, &exifError{"Segment length does not match its size"}
+	}
+	marker := binary.BigEndian.Uint16(block)
+	isAPP := marker >= cAPP0 && marker <= cAPP15
+	if !isAPP && marker != cCOMMENT {
+		return tSegment{}, &exifError{fmt.Sprintf("Only APPn and COM segments can be written, not 0x%X", marker)}
+	}
+	reader := &JpegReader{cursor: 2, data: block}
+	app, err := fAPPReadSegment(marker, reader)
+	if err != nil {
+		return tSegment{}, err
+	}
+	return tSegment{app: app, block: block, inserted: true}, nil
+}
+
+// segmentRank gives the position of a segment in the conventional order
+func segmentRank(app APP) int {
+	marker := app.Marker()
+	switch {
+	case marker == cJFIF && app.HasID(idJFIF):
+		return 0
+	case marker == cJFIF && app.HasID(idJFXX):
+		return 1
+	case marker == cEXIF && app.HasID(idEXIF):
+		return 2
+	case marker == cEXIF && app.HasID(idXMP):
+		return 3
+	case marker == cEXIF && app.HasID(idXMPExtension):
+		return 4
+	case marker == cICC && app.HasID(idAPP2):
+		return 5
+	case marker == cICC && app.HasID(idMPF):
+		return 6
+	case marker == cIPTC:
+		return 7
+	case marker >= cAPP0 && marker <= cAPP15:
+		return 8
+	case marker == cCOMMENT:
+		return 9
+	}
+	return 10
+}
+
+// Insert inserts a segment (given as marker, length and payload) at its conventional position,
+// after the existing segments of the same kind.
+func (w *JpegWriter) Insert(block []byte) error {
+	segment, err := newSegment(block)
+	if err != nil {
+		return err
+	}
+	rank := segmentRank(segment.app)
+	at := 0
+	for n, s := range w.segments {
+		r := segmentRank(s.app)
+		if r == 10 {
+			break
+		}
+		if r <= rank {
+			at = n + 1
+		}
+	}
+	segments := append([]tSegment{}, w.segments[:at]...)
+	segments = append(segments, segment)
+	w.segments = append(segments, w.segments[at:]...)
+	return nil
+}
+
+// Replace replaces the segments with the same name as the given segment (e.g. "EXIF", "XMP", "IPTC") by
+// the new segment, at the position of the first one; when there is none the segment is inserted.
+func (w *JpegWriter) Replace(block []byte) error {
+	segment, err := newSegment(block)
+	if err != nil {
+		return err
+	}
+	name := segment.app.Name()
+	replaced := false
+	segments := make([]tSegment, 0, len(w.segments))
+	for _, s := range w.segments {
+		if s.app.Name() != name {
+			segments = append(segments, s)
+		} else if !replaced {
+			segments = append(segments, segment)
+			replaced = true
+		}
+	}
+	w.segments = segments
+	if !replaced {
+		return w.Insert(block)
+	}
+	return nil
+}
+
+// ReplaceAll replaces all segments with the given name by the new segments, e.g. an IPTC block that spans
+// more than one APP13 segment; the new segments take the place of the first old one.
+func (w *JpegWriter) ReplaceAll(name string, blocks [][]byte) error {
+	segments := make([]tSegment, 0, len(blocks))
+	for _, block := range blocks {
+		segment, err := newSegment(block)
+		if err != nil {
+			return err
+		}
+		segments = append(segments, segment)
+	}
+	at := -1
+	for n, s := range w.segments {
+		if s.app.Name() == name && segmentRank(s.app) < 10 {
+			at = n
+			break
+		}
+	}
+	w.Remove(name)
+	if at < 0 {
+		for _, block := range blocks {
+			if err := w.Insert(block); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	result := append([]tSegment{}, w.segments[:at]...)
+	result = append(result, segments...)
+	w.segments = append(result, w.segments[at:]...)
+	return nil
+}
+
+// Remove removes all APPn/COM segments with the given name, it returns the number of removed segments
+func (w *JpegWriter) Remove(name string) int {
+	return w.removeIf(func(app APP, rank int) bool { return app.Name() == name })
+}
+
+// removeIf removes the APPn/COM segments for which remove returns true, given the segment and its rank
+func (w *JpegWriter) removeIf(remove func(app APP, rank int) bool) int {
+	segments := make([]tSegment, 0, len(w.segments))
+	for _, s := range w.segments {
+		if rank := segmentRank(s.app); rank < 10 && remove(s.app, rank) {
+			continue
+		}
+		segments = append(segments, s)
+	}
+	removed := len(w.segments) - len(segments)
+	w.segments = segments
+	return removed
+}
+
+// Write writes the JPEG stream to out, it fails before writing anything when the offsets of the MPF images can
+// not be corrected for the new segment sizes
+func (w *JpegWriter) Write(out io.Writer) error {
+	if w.image.scan == 0 {
+		return &exifError{"Image does not have compressed image data"}
+	}
+
+	// Determine where the scan starts in the new file to correct the MPF offsets
+	position := uint64(2)
+	for _, segment := range w.segments {
+		position += uint64(len(segment.block))
+	}
+	shift := int64(position) - int64(w.image.scan)
+
+	// Correct the MPF offsets before anything is written, an MPF segment that can not be corrected would point
+	// to the wrong images
+	blocks := make([][]byte, 0, len(w.segments))
+	position = 2
+	for _, segment := range w.segments {
+		block := segment.block
+		if mpf, ok := segment.app.(*tMPFAPP); ok && !segment.inserted {
+			// Images after the scan move by shift, the MPF header moves from its old to its new position
+			moved := int64(position) - int64(mpf.offset)
+			fixed, err := mpf.shiftedOffsets(shift - moved)
+			if err != nil {
+				return err
+			}
+			block = fixed
+		}
+		blocks = append(blocks, block)
+		position += uint64(len(block))
+	}
+
+	soi := []byte{0, 0}
+	binary.BigEndian.PutUint16(soi, cSOI)
+	if _, err := out.Write(soi); err != nil {
+		return err
+	}
+	for _, block := range blocks {
+		if _, err := out.Write(block); err != nil {
+			return err
+		}
+	}
+	_, err := out.Write(w.image.data[w.image.scan:])
+	return err
+}
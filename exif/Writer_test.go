@@ -0,0 +1,82 @@
+package ImgMeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"testing"
+)
+
+// writeTestJpeg writes the image with the segments added and returns the new file
+func writeTestJpeg(t *testing.T, img Image, blocks ...[]byte) []byte {
+	w := NewJpegWriter(img)
+	for _, block := range blocks {
+		if err := w.Insert(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var out bytes.Buffer
+	if err := w.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+// mpfTestImage returns the test image followed by a secondary image at the given MP entry offset, 0 for its
+// real offset
+func mpfTestImage(t *testing.T, entryOffset uint32) (Image, []byte) {
+	var secondary bytes.Buffer
+	if err := jpeg.Encode(&secondary, image.NewGray(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	// MP index IFD with one MPEntry tag, its 2 entries follow the IFD
+	mpf := append([]byte{}, idMPF...)
+	mpf = append(mpf, 'M', 'M', 0, 42, 0, 0, 0, 8, 0, 1, 0xB0, 0x02, 0, 7, 0, 0, 0, 32, 0, 0, 0, 26, 0, 0, 0, 0)
+	mpf = append(mpf, make([]byte, 32)...)
+	block := append([]byte{0xFF, 0xE2, 0, 0}, mpf...)
+	binary.BigEndian.PutUint16(block[2:], uint16(len(block)-2))
+
+	primary := writeTestJpeg(t, readTestImage(t), block)
+	img, err := readJpegData(primary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tiff := img.apps["MPF"].(*tMPFAPP).FileOffset()
+	if entryOffset == 0 {
+		entryOffset = uint32(uint64(len(primary)) - tiff)
+	}
+	entries := primary[tiff+26:]
+	binary.BigEndian.PutUint32(entries[0:], 0x20030000)
+	binary.BigEndian.PutUint32(entries[4:], uint32(len(primary)))
+	binary.BigEndian.PutUint32(entries[16+4:], uint32(secondary.Len()))
+	binary.BigEndian.PutUint32(entries[16+8:], entryOffset)
+	img, err = readJpegData(append(primary, secondary.Bytes()...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img, secondary.Bytes()
+}
+
+func TestJpegWriterMPFOffsets(t *testing.T) {
+	comment := []byte{0xFF, 0xFE, 0, 7, 'h', 'e', 'l', 'l', 'o'}
+	img, secondary := mpfTestImage(t, 0)
+	written, err := readJpegData(writeTestJpeg(t, img, comment))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, images, err := written.MPImages(); err != nil || len(images) != 1 || !bytes.Equal(images[0], secondary) {
+		t.Errorf("secondary image not found after the move: %v", err)
+	}
+
+	// an offset that can not be moved fails the write instead of writing a stale MPF segment
+	img, _ = mpfTestImage(t, 0xFFFFFFFF)
+	w := NewJpegWriter(img)
+	if err := w.Insert(comment); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := w.Write(&out); err == nil || out.Len() != 0 {
+		t.Errorf("write of %d bytes: %v", out.Len(), err)
+	}
+}
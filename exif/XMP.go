@@ -0,0 +1,391 @@
+package ImgMeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+/*
+Structure of an XMP APP1 segment
+
+XMP (Extensible Metadata Platform) is Adobe's RDF/XML based metadata format. In a JPEG file it is stored in an APP1 segment,
+just like EXIF, but with a different identifier:
+
+    [Record name]    [size]   [description]
+    ---------------------------------------
+    Identifier      29 bytes  ("http://ns.adobe.com/xap/1.0/\000")
+    XMP packet         ...    UTF-8 encoded XML, usually wrapped in <?xpacket begin ... ?> and <?xpacket end="w"?>
+
+The packet holds an x:xmpmeta element with a single rdf:RDF element, which in turn holds one or more rdf:Description
+elements. A property is either written as an attribute of rdf:Description (simple values only) or as a child element;
+array values are written as rdf:Seq (ordered), rdf:Bag (unordered) or rdf:Alt (alternatives, e.g. languages) holding
+rdf:li items. Structures are written as nested elements with rdf:parseType="Resource" or a nested rdf:Description.
+
+    <x:xmpmeta xmlns:x="adobe:ns:meta/">
+      <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+        <rdf:Description rdf:about="" xmlns:hdrgm="http://ns.adobe.com/hdr-gain-map/1.0/" hdrgm:Version="1.0">
+          <dc:subject><rdf:Bag><rdf:li>keyword</rdf:li></rdf:Bag></dc:subject>
+        </rdf:Description>
+      </rdf:RDF>
+    </x:xmpmeta>
+
+Properties are addressed by their namespace URI and local name, the prefix used in the packet is irrelevant.
+
+A packet is limited to 65502 bytes, larger XMP (e.g. the base64 encoded depth map and image of Google cameras) is
+split in a standard and an extended packet. The standard packet names the extended packet in the
+xmpNote:HasExtendedXMP property (the MD5 digest of the extended packet as 32 hex characters) and the extended
+packet is stored in chunks, each in an APP1 segment of its own:
+
+    [Record name]    [size]   [description]
+    ---------------------------------------
+    Identifier      35 bytes  ("http://ns.adobe.com/xmp/extension/\000")
+    GUID            32 bytes  MD5 digest of the full extended packet, as hex
+    Full length      4 bytes  size of the full extended packet
+    Offset           4 bytes  offset of this chunk in the extended packet
+    Chunk              ...    part of the extended packet
+
+*/
+
+// XMP namespaces
+const (
+	XmpNsRDF                = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	XmpNsDC                 = "http://purl.org/dc/elements/1.1/"
+	XmpNsXMP                = "http://ns.adobe.com/xap/1.0/"
+	XmpNsXMPMM              = "http://ns.adobe.com/xap/1.0/mm/"
+	XmpNsXMPRights          = "http://ns.adobe.com/xap/1.0/rights/"
+	XmpNsPhotoshop          = "http://ns.adobe.com/photoshop/1.0/"
+	XmpNsEXIF               = "http://ns.adobe.com/exif/1.0/"
+	XmpNsTIFF               = "http://ns.adobe.com/tiff/1.0/"
+	XmpNsIptc4xmpCore       = "http://iptc.org/std/Iptc4xmpCore/1.0/xmlns/"
+	XmpNsIptc4xmpExt        = "http://iptc.org/std/Iptc4xmpExt/2008-02-29/"
+	XmpNsAux                = "http://ns.adobe.com/exif/1.0/aux/"
+	XmpNsExifEX             = "http://cipa.jp/exif/1.0/"
+	XmpNsMWGRegions         = "http://www.metadataworkinggroup.com/schemas/regions/"
+	XmpNsMicrosoftPhoto     = "http://ns.microsoft.com/photo/1.2/"
+	XmpNsXMPNote            = "http://ns.adobe.com/xmp/note/"
+	XmpNsHDRGainMap         = "http://ns.adobe.com/hdr-gain-map/1.0/"
+	XmpNsContainer          = "http://ns.google.com/photos/1.0/container/"
+	XmpNsContainerItem      = "http://ns.google.com/photos/1.0/container/item/"
+	XmpNsApplePixelDataInfo = "http://ns.apple.com/pixeldatainfo/1.0/"
+	XmpNsAppleHDRGainMap    = "http://ns.apple.com/HDRGainMap/1.0/"
+)
+
+type tXMPAPP struct {
+	offset uint64           // Offset of this APP in the file
+	endian binary.ByteOrder // Byte-Order
+	block  []byte           // full APP block
+}
+
+func (t tXMPAPP) Name() string {
+	return "XMP"
+}
+func (t tXMPAPP) Marker() uint16 {
+	return t.endian.Uint16(t.block)
+}
+func (t tXMPAPP) Length() uint16 {
+	return t.endian.Uint16(t.block[2:])
+}
+func (t tXMPAPP) ID(cid []byte) (id []byte) {
+	id = t.block[4 : 4+len(cid)]
+	return
+}
+func (t tXMPAPP) HasID(cid []byte) bool {
+	id := t.block[4 : 4+len(cid)]
+	for i, b := range id {
+		if b != cid[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Packet returns the raw XMP packet (the XML) of this segment
+func (t tXMPAPP) Packet() []byte {
+	return t.block[4+len(idXMP):]
+}
+
+// Root parses the XMP packet and returns the document root
+func (t tXMPAPP) Root() (*tXMPNode, error) {
+	return parseXMP(t.Packet())
+}
+
+func (t tXMPAPP) ReadValue(tagID2Find uint16) (interface{}, error) {
+	return nil, &exifError{fmt.Sprintf("XMP has no numeric tags (0x%X), use ReadXMPValue", tagID2Find)}
+}
+
+// ReadProperty reads the value of a top-level XMP property, it returns either a string or,
+// for rdf:Seq/rdf:Bag/rdf:Alt arrays, a []string.
+func (t tXMPAPP) ReadProperty(namespace string, name string) (interface{}, error) {
+	root, err := t.Root()
+	if err != nil {
+		return nil, err
+	}
+	if value, ok := root.property(namespace, name); ok {
+		return value, nil
+	}
+	return nil, &exifError{fmt.Sprintf("XMP property '%s%s' not found", namespace, name)}
+}
+
+// ReadXMPValue reads the value of an XMP property given by namespace URI and name, the properties
+// of the extended XMP packet are included.
+// Examples:
+//
+//	version := image.ReadXMPValue(XmpNsHDRGainMap, "Version")
+func (i Image) ReadXMPValue(namespace string, name string) (value interface{}, err error) {
+	root, err := i.xmpRoot()
+	if err != nil {
+		return nil, err
+	}
+	if value, ok := root.property(namespace, name); ok {
+		return value, nil
+	}
+	return nil, &exifError{fmt.Sprintf("XMP property '%s%s' not found", namespace, name)}
+}
+
+// xmpRoot parses the standard XMP packet and merges the rdf:Description elements of the extended
+// packet, when there is one, into it.
+func (i Image) xmpRoot() (*tXMPNode, error) {
+	app, exists := i.apps["XMP"]
+	if !exists {
+		return nil, &exifError{"Image does not have 'XMP' meta section"}
+	}
+	xmp, ok := app.(*tXMPAPP)
+	if !ok {
+		return nil, &exifError{"'XMP' meta section was not read as XMP"}
+	}
+	root, err := xmp.Root()
+	if err != nil {
+		return nil, err
+	}
+	guid, hasExtension := root.property(XmpNsXMPNote, "HasExtendedXMP")
+	if !hasExtension {
+		return root, nil
+	}
+	packet, err := i.extendedXMP(fmt.Sprint(guid))
+	if err != nil {
+		return root, nil
+	}
+	extended, err := parseXMP(packet)
+	if err != nil {
+		return root, nil
+	}
+	if rdf := root.findAll(XmpNsRDF, "RDF"); len(rdf) > 0 {
+		rdf[0].children = append(rdf[0].children, extended.descriptions()...)
+	}
+	return root, nil
+}
+
+// extendedXMP assembles the extended XMP packet with the given GUID from its chunks. The full length is taken from
+// the chunks but can not be more than the chunks together hold, and every byte of the packet has to be covered.
+func (i Image) extendedXMP(guid string) ([]byte, error) {
+	chunks := []*tXMPExtensionAPP{}
+	held := uint64(0)
+	for _, segment := range i.segments {
+		if chunk, ok := segment.app.(*tXMPExtensionAPP); ok && chunk.GUID() == guid {
+			chunks = append(chunks, chunk)
+			held += uint64(len(chunk.Chunk()))
+		}
+	}
+	if len(chunks) == 0 {
+		return nil, &exifError{fmt.Sprintf("Extended XMP packet '%s' is missing", guid)}
+	}
+	length := uint64(chunks[0].FullLength())
+	if length > held {
+		return nil, &exifError{fmt.Sprintf("Extended XMP packet '%s' is incomplete", guid)}
+	}
+
+	sort.SliceStable(chunks, func(a, b int) bool { return chunks[a].ChunkOffset() < chunks[b].ChunkOffset() })
+	packet := make([]byte, length)
+	covered := uint64(0) // the packet is complete up to here
+	for _, chunk := range chunks {
+		offset, data := uint64(chunk.ChunkOffset()), chunk.Chunk()
+		if uint64(chunk.FullLength()) != length || offset+uint64(len(data)) > length {
+			return nil, &exifError{"Extended XMP chunk is out of bounds"}
+		}
+		if offset > covered {
+			break
+		}
+		copy(packet[offset:], data)
+		if end := offset + uint64(len(data)); end > covered {
+			covered = end
+		}
+	}
+	if covered < length {
+		return nil, &exifError{fmt.Sprintf("Extended XMP packet '%s' is incomplete", guid)}
+	}
+	return packet, nil
+}
+
+type tXMPExtensionAPP struct {
+	offset uint64           // Offset of this APP in the file
+	endian binary.ByteOrder // Byte-Order
+	block  []byte           // full APP block
+}
+
+func (t tXMPExtensionAPP) Name() string {
+	return "XMPExtension"
+}
+func (t tXMPExtensionAPP) Marker() uint16 {
+	return t.endian.Uint16(t.block)
+}
+func (t tXMPExtensionAPP) Length() uint16 {
+	return t.endian.Uint16(t.block[2:])
+}
+func (t tXMPExtensionAPP) ID(cid []byte) (id []byte) {
+	id = t.block[4 : 4+len(cid)]
+	return
+}
+func (t tXMPExtensionAPP) HasID(cid []byte) bool {
+	return len(t.block) >= 4+len(cid) && bytes.Equal(t.block[4:4+len(cid)], cid)
+}
+
+func (t tXMPExtensionAPP) ReadValue(tagID2Find uint16) (interface{}, error) {
+	return nil, &exifError{fmt.Sprintf("Extended XMP has no numeric tags (0x%X), use ReadXMPValue", tagID2Find)}
+}
+
+// GUID returns the MD5 digest (as hex) of the extended packet this chunk belongs to
+func (t tXMPExtensionAPP) GUID() string {
+	o := 4 + len(idXMPExtension)
+	return string(t.block[o : o+32])
+}
+
+// FullLength returns the size of the full extended packet
+func (t tXMPExtensionAPP) FullLength() uint32 {
+	return binary.BigEndian.Uint32(t.block[4+len(idXMPExtension)+32:])
+}
+
+// ChunkOffset returns the offset of this chunk in the extended packet
+func (t tXMPExtensionAPP) ChunkOffset() uint32 {
+	return binary.BigEndian.Uint32(t.block[4+len(idXMPExtension)+36:])
+}
+
+// Chunk returns the part of the extended packet stored in this segment
+func (t tXMPExtensionAPP) Chunk() []byte {
+	return t.block[4+len(idXMPExtension)+40:]
+}
+
+// ============================================== XMP DOM ==============================================
+
+// tXMPNode is a minimal DOM of an XMP packet, enough to look up properties and to
+// write the packet back without losing unknown namespaces.
+type tXMPNode struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	children []*tXMPNode
+	text     string
+}
+
+func parseXMP(packet []byte) (*tXMPNode, error) {
+	root := &tXMPNode{}
+	stack := []*tXMPNode{root}
+	// Some writers terminate the packet with a NUL, as a C string
+	decoder := xml.NewDecoder(bytes.NewReader(bytes.TrimRight(packet, "\x00")))
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return root, &exifError{fmt.Sprintf("Parsing XMP packet failed: %s", err.Error())}
+		}
+		top := stack[len(stack)-1]
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &tXMPNode{name: t.Name, attrs: append([]xml.Attr{}, t.Attr...)}
+			top.children = append(top.children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			top.text += string(t)
+		}
+	}
+	return root, nil
+}
+
+func (n *tXMPNode) is(space string, local string) bool {
+	return n.name.Space == space && n.name.Local == local
+}
+
+// attr returns the value of the attribute given by namespace and name
+func (n *tXMPNode) attr(space string, local string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.Name.Space == space && a.Name.Local == local {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// child returns the first direct child element given by namespace and name
+func (n *tXMPNode) child(space string, local string) *tXMPNode {
+	for _, c := range n.children {
+		if c.is(space, local) {
+			return c
+		}
+	}
+	return nil
+}
+
+// findAll returns all elements given by namespace and name in this sub-tree
+func (n *tXMPNode) findAll(space string, local string) (nodes []*tXMPNode) {
+	for _, c := range n.children {
+		if c.is(space, local) {
+			nodes = append(nodes, c)
+		}
+		nodes = append(nodes, c.findAll(space, local)...)
+	}
+	return nodes
+}
+
+// descriptions returns the top-level rdf:Description elements
+func (n *tXMPNode) descriptions() (nodes []*tXMPNode) {
+	for _, rdf := range n.findAll(XmpNsRDF, "RDF") {
+		for _, c := range rdf.children {
+			if c.is(XmpNsRDF, "Description") {
+				nodes = append(nodes, c)
+			}
+		}
+	}
+	return nodes
+}
+
+// value returns the value of a property element, a string for simple values and a []string for arrays
+func (n *tXMPNode) value() interface{} {
+	for _, array := range []string{"Seq", "Bag", "Alt"} {
+		if list := n.child(XmpNsRDF, array); list != nil {
+			items := []string{}
+			for _, li := range list.children {
+				if li.is(XmpNsRDF, "li") {
+					items = append(items, strings.TrimSpace(li.text))
+				}
+			}
+			return items
+		}
+	}
+	if resource, ok := n.attr(XmpNsRDF, "resource"); ok {
+		return resource
+	}
+	return strings.TrimSpace(n.text)
+}
+
+// property looks up a top-level property, either as an attribute of or an element in rdf:Description
+func (n *tXMPNode) property(space string, local string) (interface{}, bool) {
+	for _, descr := range n.descriptions() {
+		if value, ok := descr.attr(space, local); ok {
+			return value, true
+		}
+		if c := descr.child(space, local); c != nil {
+			return c.value(), true
+		}
+	}
+	return nil, false
+}
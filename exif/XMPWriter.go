@@ -0,0 +1,566 @@
+package ImgMeta
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+Writing XMP
+
+The XMPWriter holds the top-level properties of the XMP (of both the standard and the extended packet) as DOM nodes,
+properties in namespaces that the writer does not know about are kept as they are. The packet is written in a
+canonical form: a single rdf:Description that declares all namespaces, holding every property as an element, sorted
+by prefix and name:
+
+    <?xpacket begin="\xEF\xBB\xBF" id="W5M0MpCehiHzreSzNTczkc9d"?>
+    <x:xmpmeta xmlns:x="adobe:ns:meta/">
+     <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+      <rdf:Description rdf:about=""
+        xmlns:dc="http://purl.org/dc/elements/1.1/">
+       <dc:subject>
+        <rdf:Bag>
+         <rdf:li>keyword</rdf:li>
+        </rdf:Bag>
+       </dc:subject>
+      </rdf:Description>
+     </rdf:RDF>
+    </x:xmpmeta>
+    ...padding...
+    <?xpacket end="w"?>
+
+The padding (whitespace) before the closing xpacket allows the packet to be updated in place: when the new packet
+is not larger than the old one it is padded to exactly the old size, so the XMP segment can be overwritten without
+rewriting the file (see UpdateXMPInPlace). A new packet gets 2048 bytes of padding.
+
+When the packet does not fit in 65502 bytes the largest properties are moved to an extended packet, the standard
+packet then names the extended packet in xmpNote:HasExtendedXMP. The extended packet is written without xpacket
+wrapper and split in chunks of at most 65458 bytes.
+
+*/
+
+// XMPBag is an unordered XMP array (rdf:Bag), e.g. dc:subject
+type XMPBag []string
+
+// XMPSeq is an ordered XMP array (rdf:Seq), e.g. dc:creator
+type XMPSeq []string
+
+// XMPLangAlt is a language alternative (rdf:Alt with xml:lang), e.g. dc:title; the default is "x-default"
+type XMPLangAlt map[string]string
+
+const (
+	cXMPMaxPacketSize = 65502
+	cXMPMaxChunkSize  = 0xFFFF - 2 - 35 - 40
+	cXMPPadding       = 2048
+	cXMPPacketBegin   = "<?xpacket begin=\"\xEF\xBB\xBF\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n"
+	cXMPPacketEnd     = "<?xpacket end=\"w\"?>"
+	cXMLNamespace     = "http://www.w3.org/XML/1998/namespace"
+)
+
+// aXMPPrefixes holds the conventional prefixes of the common namespaces
+var aXMPPrefixes = map[string]string{
+	"adobe:ns:meta/":                    "x",
+	XmpNsRDF:                            "rdf",
+	cXMLNamespace:                       "xml",
+	XmpNsDC:                             "dc",
+	XmpNsXMP:                            "xmp",
+	XmpNsXMPMM:                          "xmpMM",
+	XmpNsXMPRights:                      "xmpRights",
+	XmpNsPhotoshop:                      "photoshop",
+	XmpNsEXIF:                           "exif",
+	XmpNsTIFF:                           "tiff",
+	XmpNsIptc4xmpCore:                   "Iptc4xmpCore",
+	XmpNsXMPNote:                        "xmpNote",
+	XmpNsHDRGainMap:                     "hdrgm",
+	XmpNsContainer:                      "Container",
+	XmpNsContainerItem:                  "Item",
+	XmpNsApplePixelDataInfo:             "apdi",
+	XmpNsAppleHDRGainMap:                "HDRGainMap",
+	XmpNsGCamera:                        "GCamera",
+	XmpNsGImage:                         "GImage",
+	XmpNsGDepth:                         "GDepth",
+	"http://ns.adobe.com/exif/1.0/aux/": "aux",
+	"http://ns.adobe.com/camera-raw-settings/1.0/":     "crs",
+	"http://ns.adobe.com/lightroom/1.0/":               "lr",
+	"http://ns.adobe.com/xap/1.0/sType/ResourceEvent#": "stEvt",
+	"http://ns.adobe.com/xap/1.0/sType/ResourceRef#":   "stRef",
+	XmpNsIptc4xmpExt:    "Iptc4xmpExt",
+	XmpNsExifEX:         "exifEX",
+	XmpNsMWGRegions:     "mwg-rs",
+	XmpNsMicrosoftPhoto: "MP",
+}
+
+// XMPWriter builds the XMP segment(s) of an image or an XMP sidecar
+type XMPWriter struct {
+	properties []*tXMPNode
+	prefixes   map[string]string // namespace URI to prefix, as used in the original packet
+	packetSize int               // size of the original packet when it has padding, for in place updates
+}
+
+// NewXMPWriter returns an empty XMP writer
+func NewXMPWriter() *XMPWriter {
+	return &XMPWriter{prefixes: map[string]string{}}
+}
+
+// NewXMPWriterFrom returns an XMP writer holding the properties of the image, including those of the
+// extended packet.
+func NewXMPWriterFrom(image Image) (*XMPWriter, error) {
+	w := NewXMPWriter()
+	app, exists := image.apps["XMP"]
+	if !exists {
+		return w, nil
+	}
+	root, err := image.xmpRoot()
+	if err != nil {
+		return nil, err
+	}
+	w.load(root)
+	if xmp, ok := app.(*tXMPAPP); ok && bytes.Contains(xmp.Packet(), []byte("<?xpacket end")) {
+		w.packetSize = len(xmp.Packet())
+	}
+	return w, nil
+}
+
+// NewXMPWriterFromSidecar returns an XMP writer holding the properties of an XMP sidecar file
+func NewXMPWriterFromSidecar(path string) (*XMPWriter, error) {
+	packet, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	root, err := parseXMP(packet)
+	if err != nil {
+		return nil, err
+	}
+	w := NewXMPWriter()
+	w.load(root)
+	return w, nil
+}
+
+// load takes the properties of all rdf:Description elements, attributes are turned into elements
+func (w *XMPWriter) load(root *tXMPNode) {
+	w.collectPrefixes(root)
+	for _, descr := range root.descriptions() {
+		for _, a := range descr.attrs {
+			if isXMLNamespaceDecl(a.Name) || a.Name.Space == XmpNsRDF {
+				continue
+			}
+			w.put(&tXMPNode{name: a.Name, text: a.Value})
+		}
+		for _, c := range descr.children {
+			w.put(c)
+		}
+	}
+	w.remove(XmpNsXMPNote, "HasExtendedXMP")
+}
+
+func (w *XMPWriter) collectPrefixes(n *tXMPNode) {
+	for _, a := range n.attrs {
+		if a.Name.Space == "xmlns" {
+			if _, exists := w.prefixes[a.Value]; !exists {
+				w.prefixes[a.Value] = a.Name.Local
+			}
+		}
+	}
+	for _, c := range n.children {
+		w.collectPrefixes(c)
+	}
+}
+
+func isXMLNamespaceDecl(name xml.Name) bool {
+	return name.Space == "xmlns" || (name.Space == "" && name.Local == "xmlns")
+}
+
+// put adds a property, replacing the property with the same name
+func (w *XMPWriter) put(node *tXMPNode) {
+	for i, p := range w.properties {
+		if p.name == node.name {
+			w.properties[i] = node
+			return
+		}
+	}
+	w.properties = append(w.properties, node)
+}
+
+func (w *XMPWriter) remove(space string, local string) bool {
+	for i, p := range w.properties {
+		if p.is(space, local) {
+			w.properties = append(w.properties[:i], w.properties[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterNamespace sets the prefix that is written for a namespace
+func (w *XMPWriter) RegisterNamespace(prefix string, namespace string) {
+	w.prefixes[namespace] = prefix
+}
+
+// Set sets a top-level property; the value is a string, int, int64, float64, bool, time.Time, XMPBag, XMPSeq
+// or XMPLangAlt.
+func (w *XMPWriter) Set(namespace string, name string, value interface{}) error {
+	if namespace == "" || namespace == XmpNsRDF || (namespace == XmpNsXMPNote && name == "HasExtendedXMP") {
+		return &exifError{fmt.Sprintf("XMP property '%s%s' can not be set", namespace, name)}
+	}
+	node := &tXMPNode{name: xml.Name{Space: namespace, Local: name}}
+	array := func(kind string, items []string) {
+		list := &tXMPNode{name: xml.Name{Space: XmpNsRDF, Local: kind}}
+		for _, item := range items {
+			list.children = append(list.children, &tXMPNode{name: xml.Name{Space: XmpNsRDF, Local: "li"}, text: item})
+		}
+		node.children = []*tXMPNode{list}
+	}
+	switch v := value.(type) {
+	case string:
+		node.text = v
+	case int:
+		node.text = strconv.Itoa(v)
+	case int64:
+		node.text = strconv.FormatInt(v, 10)
+	case float64:
+		node.text = strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		node.text = "False"
+		if v {
+			node.text = "True"
+		}
+	case time.Time:
+		node.text = v.Format("2006-01-02T15:04:05.999999999Z07:00")
+	case XMPBag:
+		array("Bag", v)
+	case XMPSeq:
+		array("Seq", v)
+	case XMPLangAlt:
+		languages := make([]string, 0, len(v))
+		for language := range v {
+			if language != "x-default" {
+				languages = append(languages, language)
+			}
+		}
+		sort.Strings(languages)
+		if _, exists := v["x-default"]; exists {
+			languages = append([]string{"x-default"}, languages...)
+		}
+		alt := &tXMPNode{name: xml.Name{Space: XmpNsRDF, Local: "Alt"}}
+		for _, language := range languages {
+			alt.children = append(alt.children, &tXMPNode{
+				name:  xml.Name{Space: XmpNsRDF, Local: "li"},
+				attrs: []xml.Attr{{Name: xml.Name{Space: cXMLNamespace, Local: "lang"}, Value: language}},
+				text:  v[language],
+			})
+		}
+		node.children = []*tXMPNode{alt}
+	default:
+		return &exifError{fmt.Sprintf("XMP property can not hold a value of type %T", value)}
+	}
+	w.put(node)
+	return nil
+}
+
+// Property returns the value of a top-level property, a string or for arrays a []string
+func (w *XMPWriter) Property(namespace string, name string) (interface{}, bool) {
+	for _, p := range w.properties {
+		if p.is(namespace, name) {
+			return p.value(), true
+		}
+	}
+	return nil, false
+}
+
+// Remove removes a top-level property, it returns false when the property was not set
+func (w *XMPWriter) Remove(namespace string, name string) bool {
+	return w.remove(namespace, name)
+}
+
+// Packet returns the full packet, as written to a sidecar
+func (w *XMPWriter) Packet() []byte {
+	return w.wrap(w.serialize(w.properties), 0)
+}
+
+// WriteSidecar writes the XMP to a sidecar file
+func (w *XMPWriter) WriteSidecar(path string) error {
+	return os.WriteFile(path, w.Packet(), 0644)
+}
+
+// Encode builds the XMP APP1 segment and, when the XMP does not fit in it, the Extended XMP APP1 segments
+func (w *XMPWriter) Encode() (segment []byte, extension [][]byte, err error) {
+	body := w.serialize(w.properties)
+	if len(cXMPPacketBegin)+len(body)+len(cXMPPacketEnd) <= cXMPMaxPacketSize {
+		return xmpSegment(w.wrap(body, w.padding(len(body)))), nil, nil
+	}
+
+	// Move the largest properties to the extended packet until the standard packet fits
+	properties := append([]*tXMPNode{}, w.properties...)
+	sizes := map[*tXMPNode]int{}
+	for _, p := range properties {
+		sizes[p] = len(w.serialize([]*tXMPNode{p}))
+	}
+	sort.SliceStable(properties, func(a, b int) bool { return sizes[properties[a]] > sizes[properties[b]] })
+	placeholder := &tXMPNode{name: xml.Name{Space: XmpNsXMPNote, Local: "HasExtendedXMP"}, text: strings.Repeat("0", 32)}
+	moved := 0
+	for ; moved <= len(properties); moved++ {
+		standard := append([]*tXMPNode{placeholder}, properties[moved:]...)
+		if len(cXMPPacketBegin)+len(w.serialize(standard))+len(cXMPPacketEnd) <= cXMPMaxPacketSize {
+			break
+		}
+	}
+	if moved > len(properties) {
+		return nil, nil, &exifError{"XMP does not fit in a segment"}
+	}
+
+	extended := w.serialize(properties[:moved])
+	digest := md5.Sum(extended)
+	guid := strings.ToUpper(hex.EncodeToString(digest[:]))
+	hasExtended := &tXMPNode{name: placeholder.name, text: guid}
+	body = w.serialize(append([]*tXMPNode{hasExtended}, properties[moved:]...))
+	segment = xmpSegment(w.wrap(body, w.padding(len(body))))
+
+	for offset := 0; offset < len(extended); offset += cXMPMaxChunkSize {
+		end := offset + cXMPMaxChunkSize
+		if end > len(extended) {
+			end = len(extended)
+		}
+		chunk := make([]byte, 4, 4+len(idXMPExtension)+40+end-offset)
+		binary.BigEndian.PutUint16(chunk, cEXIF)
+		binary.BigEndian.PutUint16(chunk[2:], uint16(2+len(idXMPExtension)+40+end-offset))
+		chunk = append(chunk, idXMPExtension...)
+		chunk = append(chunk, guid...)
+		chunk = append(chunk, make([]byte, 8)...)
+		binary.BigEndian.PutUint32(chunk[len(chunk)-8:], uint32(len(extended)))
+		binary.BigEndian.PutUint32(chunk[len(chunk)-4:], uint32(offset))
+		extension = append(extension, append(chunk, extended[offset:end]...))
+	}
+	return segment, extension, nil
+}
+
+// padding returns the amount of padding for a packet body, the packet keeps its original size when possible
+func (w *XMPWriter) padding(body int) int {
+	size := len(cXMPPacketBegin) + body + len(cXMPPacketEnd)
+	if w.packetSize >= size {
+		return w.packetSize - size
+	}
+	if size+cXMPPadding > cXMPMaxPacketSize {
+		return cXMPMaxPacketSize - size
+	}
+	return cXMPPadding
+}
+
+// wrap puts the xpacket processing instructions and the padding around the packet body
+func (w *XMPWriter) wrap(body []byte, padding int) []byte {
+	packet := make([]byte, 0, len(cXMPPacketBegin)+len(body)+padding+len(cXMPPacketEnd))
+	packet = append(packet, cXMPPacketBegin...)
+	packet = append(packet, body...)
+	for i := 1; i <= padding; i++ {
+		if i%100 == 0 || i == padding {
+			packet = append(packet, '\n')
+		} else {
+			packet = append(packet, ' ')
+		}
+	}
+	return append(packet, cXMPPacketEnd...)
+}
+
+func xmpSegment(packet []byte) []byte {
+	segment := make([]byte, 4, 4+len(idXMP)+len(packet))
+	binary.BigEndian.PutUint16(segment, cEXIF)
+	binary.BigEndian.PutUint16(segment[2:], uint16(2+len(idXMP)+len(packet)))
+	segment = append(segment, idXMP...)
+	return append(segment, packet...)
+}
+
+// namespaces returns the prefix of every namespace used by the properties
+func (w *XMPWriter) namespaces(properties []*tXMPNode) map[string]string {
+	used := map[string]bool{}
+	var walk func(n *tXMPNode)
+	walk = func(n *tXMPNode) {
+		used[n.name.Space] = true
+		for _, a := range n.attrs {
+			if !isXMLNamespaceDecl(a.Name) {
+				used[a.Name.Space] = true
+			}
+		}
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	for _, p := range properties {
+		walk(p)
+	}
+
+	prefixes := map[string]string{XmpNsRDF: "rdf", cXMLNamespace: "xml"}
+	taken := map[string]bool{"rdf": true, "xml": true, "x": true}
+	spaces := []string{}
+	for space := range used {
+		if _, exists := prefixes[space]; !exists && space != "" {
+			spaces = append(spaces, space)
+		}
+	}
+	sort.Strings(spaces)
+	for _, space := range spaces {
+		prefix, exists := w.prefixes[space]
+		if !exists {
+			prefix, exists = aXMPPrefixes[space]
+		}
+		if !exists || taken[prefix] {
+			base := prefix
+			if base == "" {
+				base = "ns"
+			}
+			for n := 1; !exists || taken[prefix]; n++ {
+				prefix, exists = fmt.Sprintf("%s%d", base, n), true
+			}
+		}
+		prefixes[space] = prefix
+		taken[prefix] = true
+	}
+	return prefixes
+}
+
+// serialize writes the x:xmpmeta element holding the properties
+func (w *XMPWriter) serialize(properties []*tXMPNode) []byte {
+	prefixes := w.namespaces(properties)
+	qualified := func(name xml.Name) string {
+		if name.Space == "" {
+			return name.Local
+		}
+		return prefixes[name.Space] + ":" + name.Local
+	}
+	sorted := append([]*tXMPNode{}, properties...)
+	sort.SliceStable(sorted, func(a, b int) bool { return qualified(sorted[a].name) < qualified(sorted[b].name) })
+
+	var buf bytes.Buffer
+	buf.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	buf.WriteString(" <rdf:RDF xmlns:rdf=\"" + XmpNsRDF + "\">\n")
+	buf.WriteString("  <rdf:Description rdf:about=\"\"")
+	declared := []string{}
+	for space, prefix := range prefixes {
+		if prefix != "rdf" && prefix != "xml" {
+			declared = append(declared, space)
+		}
+	}
+	sort.Slice(declared, func(a, b int) bool { return prefixes[declared[a]] < prefixes[declared[b]] })
+	for _, space := range declared {
+		buf.WriteString("\n    xmlns:" + prefixes[space] + "=\"")
+		xml.EscapeText(&buf, []byte(space))
+		buf.WriteString("\"")
+	}
+	if len(sorted) == 0 {
+		buf.WriteString("/>\n")
+	} else {
+		buf.WriteString(">\n")
+		for _, p := range sorted {
+			writeXMPNode(&buf, p, qualified, 3)
+		}
+		buf.WriteString("  </rdf:Description>\n")
+	}
+	buf.WriteString(" </rdf:RDF>\n")
+	buf.WriteString("</x:xmpmeta>\n")
+	return buf.Bytes()
+}
+
+// writeXMPNode writes an element with its attributes and children
+func writeXMPNode(buf *bytes.Buffer, n *tXMPNode, qualified func(xml.Name) string, depth int) {
+	indent := strings.Repeat(" ", depth)
+	buf.WriteString(indent + "<" + qualified(n.name))
+	for _, a := range n.attrs {
+		if isXMLNamespaceDecl(a.Name) {
+			continue
+		}
+		buf.WriteString(" " + qualified(a.Name) + "=\"")
+		xml.EscapeText(buf, []byte(a.Value))
+		buf.WriteString("\"")
+	}
+	switch {
+	case len(n.children) > 0:
+		buf.WriteString(">\n")
+		for _, c := range n.children {
+			writeXMPNode(buf, c, qualified, depth+1)
+		}
+		buf.WriteString(indent + "</" + qualified(n.name) + ">\n")
+	case n.text != "":
+		buf.WriteString(">")
+		xml.EscapeText(buf, []byte(n.text))
+		buf.WriteString("</" + qualified(n.name) + ">\n")
+	default:
+		buf.WriteString("/>\n")
+	}
+}
+
+// UpdateXMPInPlace edits the XMP of a JPEG file and overwrites the XMP segment in place when the new packet fits
+// in the padding of the old one, the file keeps its size. It returns false, without writing anything, when the
+// packet does not fit; the file then has to be rewritten.
+func UpdateXMPInPlace(path string, edit func(*XMPWriter) error) (bool, error) {
+	fhnd, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return false, err
+	}
+	defer fhnd.Close()
+	image, err := ReadJpeg(fhnd)
+	if err != nil {
+		return false, err
+	}
+	if _, exists := image.apps["XMP"]; !exists {
+		return false, nil
+	}
+	w, err := NewXMPWriterFrom(image)
+	if err != nil {
+		return false, err
+	}
+	if err := edit(w); err != nil {
+		return false, err
+	}
+	segment, extension, err := w.Encode()
+	if err != nil {
+		return false, err
+	}
+
+	offset, fits := image.xmpInPlaceOffset(segment, extension)
+	if !fits {
+		return false, nil
+	}
+	if _, err := fhnd.WriteAt(segment, offset); err != nil {
+		return false, err
+	}
+	return true, fhnd.Sync()
+}
+
+// xmpInPlaceOffset returns the file offset of the XMP segment when the new segment can overwrite it: the segment
+// has to keep its size and the extended packet has to stay the same.
+func (i Image) xmpInPlaceOffset(segment []byte, extension [][]byte) (int64, bool) {
+	app, exists := i.apps["XMP"]
+	if !exists {
+		return 0, false
+	}
+	xmp, ok := app.(*tXMPAPP)
+	if !ok || len(segment) != len(xmp.block) {
+		return 0, false
+	}
+	current := [][]byte{}
+	for _, s := range i.segments {
+		if _, ok := s.app.(*tXMPExtensionAPP); ok {
+			current = append(current, s.block)
+		}
+	}
+	if len(current) != len(extension) {
+		return 0, false
+	}
+	for n := range current {
+		if !bytes.Equal(current[n], extension[n]) {
+			return 0, false
+		}
+	}
+	offset := int64(xmp.offset) - 2
+	if offset < 0 || offset+int64(len(xmp.block)) > int64(len(i.data)) || !bytes.Equal(i.data[offset:offset+int64(len(xmp.block))], xmp.block) {
+		return 0, false
+	}
+	return offset, true
+}
@@ -0,0 +1,69 @@
+package ImgMeta
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestXMPWriterEncodeExtended(t *testing.T) {
+	image := readTestImage(t)
+	w, err := NewXMPWriterFrom(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	instructions := strings.Repeat("x", 2*cXMPMaxPacketSize)
+	if err := w.Set(XmpNsPhotoshop, "Instructions", instructions); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Set(XmpNsXMP, "Rating", 4); err != nil {
+		t.Fatal(err)
+	}
+	segment, extension, err := w.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segment) > 2+0xFFFF || len(extension) < 2 {
+		t.Fatalf("segment of %d bytes with %d extension segments", len(segment), len(extension))
+	}
+
+	jw := NewJpegWriter(image)
+	if err := jw.Replace(segment); err != nil {
+		t.Fatal(err)
+	}
+	if err := jw.ReplaceAll("XMPExtension", extension); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := jw.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := readJpegData(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := decoded.xmpRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := root.property(XmpNsPhotoshop, "Instructions"); value != instructions {
+		t.Errorf("Instructions was not read back from the extended XMP")
+	}
+	if value, _ := root.property(XmpNsXMP, "Rating"); value != "4" {
+		t.Errorf("Rating: %v", value)
+	}
+	if _, exists := root.property(XmpNsXMPNote, "HasExtendedXMP"); !exists {
+		t.Errorf("HasExtendedXMP is missing")
+	}
+}
+
+func TestXMPWriterEncodeSingleLargeProperty(t *testing.T) {
+	w := NewXMPWriter()
+	if err := w.Set(XmpNsPhotoshop, "Instructions", strings.Repeat("x", 2*cXMPMaxPacketSize)); err != nil {
+		t.Fatal(err)
+	}
+	segment, extension, err := w.Encode()
+	if err != nil || len(segment) > 2+0xFFFF || len(extension) == 0 {
+		t.Fatalf("%d extension segments, %v", len(extension), err)
+	}
+}
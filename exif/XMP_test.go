@@ -0,0 +1,83 @@
+package ImgMeta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// extendedXMPImage writes the test image with an XMP packet that needs 3 extension segments, tamper can change
+// the extension segments before they are written
+func extendedXMPImage(t *testing.T, tamper func(extension [][]byte) [][]byte) (Image, string) {
+	image := readTestImage(t)
+	w := NewXMPWriter()
+	if err := w.Set(XmpNsPhotoshop, "Instructions", strings.Repeat("x", 150000)); err != nil {
+		t.Fatal(err)
+	}
+	segment, extension, err := w.Encode()
+	if err != nil || len(extension) != 3 {
+		t.Fatal(err, len(extension))
+	}
+	guid := extension[0][4+len(idXMPExtension) : 4+len(idXMPExtension)+32]
+	jw := NewJpegWriter(image)
+	if err := jw.Replace(segment); err != nil {
+		t.Fatal(err)
+	}
+	if err := jw.ReplaceAll("XMPExtension", tamper(extension)); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := jw.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := readJpegData(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded, string(guid)
+}
+
+func TestExtendedXMPMalformed(t *testing.T) {
+	fullLength := 4 + len(idXMPExtension) + 32
+	for _, c := range []struct {
+		name     string
+		complete bool
+		tamper   func(extension [][]byte) [][]byte
+	}{
+		{"intact", true, func(e [][]byte) [][]byte { return e }},
+		{"reversed", true, func(e [][]byte) [][]byte { return [][]byte{e[2], e[1], e[0]} }},
+		{"missing chunk", false, func(e [][]byte) [][]byte { return [][]byte{e[0], e[2]} }},
+		{"duplicate chunk", false, func(e [][]byte) [][]byte { return [][]byte{e[0], e[1], e[1]} }},
+		{"huge length", false, func(e [][]byte) [][]byte {
+			for _, chunk := range e {
+				binary.BigEndian.PutUint32(chunk[fullLength:], 0xFFFFFFFF)
+			}
+			return e
+		}},
+		{"lengths differ", false, func(e [][]byte) [][]byte {
+			binary.BigEndian.PutUint32(e[1][fullLength:], binary.BigEndian.Uint32(e[1][fullLength:])-1)
+			return e
+		}},
+	} {
+		image, guid := extendedXMPImage(t, c.tamper)
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		packet, err := image.extendedXMP(guid)
+		runtime.ReadMemStats(&after)
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+			t.Errorf("%s: allocated %d bytes", c.name, allocated)
+		}
+		if c.complete && (err != nil || len(packet) < 150000) {
+			t.Errorf("%s: %d bytes, %v", c.name, len(packet), err)
+		}
+		if !c.complete && err == nil {
+			t.Errorf("%s: no error", c.name)
+		}
+		// the standard packet still reads
+		if _, err := image.xmpRoot(); err != nil {
+			t.Errorf("%s: %v", c.name, err)
+		}
+	}
+}
@@ -1,13 +1,9 @@
 package ImgMeta
 
-import (
-	"fmt"
-)
-
-// BasicInfo contains the most basic information that could be asked for
+// BasicInfo contains the most basic information that could be asked for, see Summary for more
 type BasicInfo struct {
-	Width    interface{}
-	Height   uint32
+	Width    uint32 // as displayed
+	Height   uint32 // as displayed
 	Title    string
 	Descr    string
 	Keywords []string
@@ -15,31 +11,11 @@ type BasicInfo struct {
 
 // GetBasicInfo gets the basic information from the meta-information of the image
 func GetBasicInfo(img Image) (info BasicInfo) {
-	width, err := img.ReadTagValue("SOF0", SOF0ImageWidth)
-	if err == nil {
-		info.Width = width
-	} else {
-		fmt.Println(err.Error())
-	}
-	height, err := img.ReadTagValue("SOF0", SOF0ImageHeight)
-	if err == nil {
-		info.Height = height.(uint32)
-	} else {
-		fmt.Println(err.Error())
-	}
-	keyword, err := img.ReadTagValue("IPTC", IptcTagApplication2Keywords)
-	if err == nil {
-		info.Keywords = []string{keyword.(string)}
-	}
-	datetime, err := img.ReadTagValue("EXIF", ExifTagDateTimeOriginal)
-	if err == nil {
-		fmt.Printf("datetime:%v\n", datetime)
-	}
-	//height, err := img.ReadTagValue("IPTC", IptcTagApplication2Keywords)
-	//if err == nil {
-	//	info.Height = height.(float64)
-	//} else {
-	//	fmt.Println(err.Error())
-	//}
+	summary := img.Summary()
+	info.Width = summary.Width
+	info.Height = summary.Height
+	info.Title = summary.Title
+	info.Descr = summary.Description
+	info.Keywords = summary.Keywords
 	return
 }
@@ -0,0 +1,40 @@
+#!/usr/bin/env python3
+# gen_jis.py writes JISTable.go, the JIS X 0208 table of the JIS character code, from the Python cp932 codec.
+# Run it with "go generate" in this directory.
+
+
+def decode(lead, trail):
+    try:
+        return ord(bytes([lead, trail]).decode("cp932"))
+    except UnicodeDecodeError:
+        return 0
+
+
+def shift_jis(row, cell):
+    """the Shift-JIS bytes of a row and cell, rows 95 to 120 are the lead bytes 0xF0 to 0xFC"""
+    lead = (row - 1) // 2 + (0x81 if row <= 62 else 0xC1)
+    if row % 2 == 1:
+        trail = cell + 0x3F + (1 if cell >= 64 else 0)
+    else:
+        trail = cell + 0x9E
+    return lead, trail
+
+
+def table(out, name, first_row, last_row, comment):
+    values = [decode(*shift_jis(row, cell)) for row in range(first_row, last_row + 1) for cell in range(1, 95)]
+    out.write(comment)
+    out.write("var %s = [%d * 94]uint16{\n" % (name, last_row - first_row + 1))
+    for n in range(0, len(values), 16):
+        out.write("\t" + " ".join("0x%04X," % v for v in values[n:n + 16]) + "\n")
+    out.write("}\n")
+
+
+with open("JISTable.go", "w") as out:
+    out.write("// Code generated by gen_jis.py from the Python cp932 codec; DO NOT EDIT.\n\npackage ImgMeta\n\n")
+    table(out, "aJISX0208", 1, 94,
+          "// aJISX0208 maps JIS X 0208 row/cell codes to Unicode, index (row-1)*94 + (cell-1), 0 for unassigned codes. The\n"
+          "// mapping is the one of Windows code page 932 (Shift-JIS), including the NEC and IBM extensions in rows 13 and 89-92.\n")
+    out.write("\n")
+    table(out, "aIBMExtension", 115, 120,
+          "// aIBMExtension maps the IBM extension of code page 932 (Shift-JIS 0xFA40 to 0xFC4B) to Unicode, index\n"
+          "// (row-115)*94 + (cell-1) where rows 115 to 120 are the Shift-JIS lead bytes 0xFA to 0xFC.\n")